@@ -11,12 +11,17 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/config"
 	"github.com/nexus/backend/internal/database"
 	"github.com/nexus/backend/internal/handlers"
 	"github.com/nexus/backend/internal/middleware"
+	"github.com/nexus/backend/internal/ratelimit"
+	"github.com/nexus/backend/internal/services"
+	"github.com/nexus/backend/internal/unfurl"
 )
 
 func main() {
@@ -52,6 +57,43 @@ func main() {
 
 	logger.Info("Connected to Cassandra", zap.Strings("hosts", envConfig.CassandraHosts))
 
+	// Conectar ao NATS (eventos para o gateway WebSocket)
+	nc, err := nats.Connect(envConfig.NatsURL)
+	if err != nil {
+		logger.Fatal("failed to connect to NATS", zap.Error(err))
+	}
+	defer nc.Close()
+
+	logger.Info("Connected to NATS", zap.String("url", envConfig.NatsURL))
+
+	eventService := services.NewEventService(nc, logger)
+	scheduler := services.NewScheduler(logger)
+	defer scheduler.Stop()
+
+	// Jobs em segundo plano (exportações, purges) ficam disponíveis por 1 hora após concluírem
+	jobManager := services.NewJobManager(logger, time.Hour)
+	defer jobManager.Stop()
+
+	// Webhooks de saída: entrega eventos do NATS aos endpoints registrados pelos servidores
+	webhookDispatcher := services.NewWebhookDispatcher(nc, db, logger, envConfig.WebhookAllowHTTP)
+	if err := webhookDispatcher.Start(); err != nil {
+		logger.Fatal("failed to start webhook dispatcher", zap.Error(err))
+	}
+	defer webhookDispatcher.Stop()
+
+	// Desdobramento de links (Open Graph/oEmbed) em segundo plano
+	unfurler := unfurl.New(logger, 4)
+	defer unfurler.Stop()
+
+	// Slowmode por canal (compartilhado com o gateway pelo Cassandra) e limite de rajada de
+	// mensagens por usuário (por réplica)
+	messageLimiter := ratelimit.NewMessageLimiter(ratelimit.DefaultBurst, ratelimit.DefaultRefill, db)
+	// Limite separado por webhook de entrada
+	webhookLimiter := ratelimit.NewMessageLimiter(ratelimit.WebhookBurst, ratelimit.WebhookRefill, nil)
+
+	// Política de acesso a canais e servidores (membros do servidor ou participantes da DM)
+	accessPolicy := access.NewPolicy(db, 0)
+
 	// Setup CORS middleware
 	corsConfig := middleware.NewCORSConfig(logger)
 
@@ -61,12 +103,25 @@ func main() {
 	// Setup handlers
 	authHandler := handlers.NewAuthHandler(logger, envConfig.JWTSecret, db)
 	healthHandler := handlers.NewHealthHandler(logger)
-	channelHandler := handlers.NewChannelHandler(logger, db)
-	messageHandler := handlers.NewMessageHandler(logger, db)
-	taskHandler := handlers.NewTaskHandler(logger, db)
-	serverHandler := handlers.NewServerHandler(logger, db)
+	channelHandler := handlers.NewChannelHandler(logger, db, accessPolicy)
+	messageHandler := handlers.NewMessageHandler(logger, db, accessPolicy, eventService, scheduler, messageLimiter, jobManager, unfurler)
+	taskHandler := handlers.NewTaskHandler(logger, db, accessPolicy, eventService)
+	serverHandler := handlers.NewServerHandler(logger, db, accessPolicy, eventService)
 	friendHandler := handlers.NewFriendHandler(logger, db)
+	presenceHandler := handlers.NewPresenceHandler(logger, db, accessPolicy, eventService, scheduler)
 	imageHandler := handlers.NewImageHandler(logger, db, "./uploads")
+	exportHandler := handlers.NewExportHandler(logger, db, accessPolicy, jobManager, "./exports")
+	webhookHandler := handlers.NewWebhookHandler(logger, db, messageHandler, webhookLimiter)
+	outgoingWebhookHandler := handlers.NewOutgoingWebhookHandler(logger, db, webhookDispatcher)
+	commandHandler := handlers.NewCommandHandler(logger, db, messageHandler, taskHandler, webhookDispatcher)
+	emojiHandler := handlers.NewEmojiHandler(logger, db, eventService, messageHandler, "./uploads")
+
+	// Comandos de barra enviados pelo gateway WebSocket
+	commandSub, err := commandHandler.Subscribe(nc)
+	if err != nil {
+		logger.Fatal("failed to subscribe to command requests", zap.Error(err))
+	}
+	defer commandSub.Unsubscribe()
 
 	// Setup rotas HTTP
 	mux := http.NewServeMux()
@@ -96,6 +151,59 @@ func main() {
 		}
 	})))
 
+	// Exportação do histórico de canais (protegidas)
+	mux.Handle("/api/channels/export", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			exportHandler.GetExport(w, r)
+		case http.MethodPost:
+			exportHandler.StartExport(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/channels/export/download", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			exportHandler.DownloadExport(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Gestão de webhooks de entrada (protegidas, owners e admins do servidor)
+	mux.Handle("/api/channels/webhooks", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			webhookHandler.ListWebhooks(w, r)
+		case http.MethodPost:
+			webhookHandler.CreateWebhook(w, r)
+		case http.MethodPatch:
+			webhookHandler.UpdateWebhook(w, r)
+		case http.MethodDelete:
+			webhookHandler.DeleteWebhook(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/channels/webhooks/token", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			webhookHandler.RegenerateWebhookToken(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Execução de webhooks (pública, autenticada pelo token na URL)
+	mux.HandleFunc("/api/webhooks/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			webhookHandler.ExecuteWebhook(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+
 	// Rotas de mensagens (protegidas)
 	mux.Handle("/api/messages", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		channelID := r.URL.Query().Get("channelId")
@@ -127,6 +235,48 @@ func main() {
 		}
 	})))
 
+	// Remoção em massa de mensagens (moderadores): iniciar e acompanhar o job
+	mux.Handle("/api/messages/purge", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			messageHandler.GetPurge(w, r)
+		case http.MethodPost:
+			messageHandler.PurgeMessages(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Encaminhar uma mensagem para outro canal (protegida)
+	mux.Handle("/api/messages/forward", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			messageHandler.ForwardMessage(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Rotas de enquetes (protegidas): votar/remover voto
+	mux.Handle("/api/messages/poll", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost, http.MethodPut:
+			messageHandler.VotePoll(w, r)
+		case http.MethodDelete:
+			messageHandler.UnvotePoll(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Encerrar enquete antes da expiração
+	mux.Handle("/api/messages/poll/close", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			messageHandler.ClosePoll(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	// Rotas de tarefas (protegidas)
 	mux.Handle("/api/tasks", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		channelID := r.URL.Query().Get("channelId")
@@ -178,6 +328,85 @@ func main() {
 		}
 	})))
 
+	// Webhooks de saída dos servidores (protegidas, owners e admins do servidor)
+	mux.Handle("/api/servers/webhooks", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			outgoingWebhookHandler.ListOutgoingWebhooks(w, r)
+		case http.MethodPost:
+			outgoingWebhookHandler.CreateOutgoingWebhook(w, r)
+		case http.MethodPatch:
+			outgoingWebhookHandler.UpdateOutgoingWebhook(w, r)
+		case http.MethodDelete:
+			outgoingWebhookHandler.DeleteOutgoingWebhook(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/servers/webhooks/secret", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			outgoingWebhookHandler.RegenerateOutgoingWebhookSecret(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/servers/webhooks/ping", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			outgoingWebhookHandler.PingOutgoingWebhook(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	mux.Handle("/api/servers/webhooks/deliveries", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			outgoingWebhookHandler.GetWebhookDeliveries(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Comandos de bots dos servidores (protegidas, owners e admins do servidor)
+	mux.Handle("/api/servers/commands", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			commandHandler.ListBotCommands(w, r)
+		case http.MethodPost:
+			commandHandler.CreateBotCommand(w, r)
+		case http.MethodDelete:
+			commandHandler.DeleteBotCommand(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Emojis customizados dos servidores (listagem para membros; gestão para owners e admins)
+	mux.Handle("/api/servers/emojis", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			emojiHandler.ListEmojis(w, r)
+		case http.MethodPost:
+			emojiHandler.UploadEmoji(w, r)
+		case http.MethodPatch:
+			emojiHandler.RenameEmoji(w, r)
+		case http.MethodDelete:
+			emojiHandler.DeleteEmoji(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Comandos de barra disponíveis em um canal (autocomplete)
+	mux.Handle("/api/commands", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			commandHandler.ListCommands(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	// Rotas de servidores (protegidas)
 	mux.Handle("/api/servers", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -200,6 +429,9 @@ func main() {
 		} else if strings.HasSuffix(path, "/channels") && r.Method == http.MethodGet {
 			// /api/servers/{id}/channels - GET
 			serverHandler.GetServerChannels(w, r)
+		} else if strings.HasSuffix(path, "/members") && r.Method == http.MethodGet {
+			// /api/servers/{id}/members - GET
+			serverHandler.GetServerMembers(w, r)
 		} else if strings.HasSuffix(path, "/channels") && r.Method == http.MethodPost {
 			// /api/servers/{id}/channels - POST
 			serverHandler.CreateServerChannel(w, r)
@@ -281,6 +513,27 @@ func main() {
 		}
 	})))
 
+	// Rota para consultar presença em lote (protegida)
+	mux.Handle("/api/presence", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			presenceHandler.QueryPresence(w, r)
+		} else {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
+	// Status personalizado do usuário (protegida)
+	mux.Handle("/api/users/status", authHandler.AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			presenceHandler.SetCustomStatus(w, r)
+		case http.MethodDelete:
+			presenceHandler.ClearCustomStatus(w, r)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+
 	// Rotas de imagens
 	// Upload de avatar de usuário (protegida)
 	mux.Handle("/api/users/avatar", authHandler.AuthMiddleware(http.HandlerFunc(imageHandler.UploadUserAvatar)))
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"expvar"
+	"os"
+	"strconv"
+	"time"
+)
+
+// backpressurePolicy define como a fila de envio de uma sessão lenta é tratada. Em ordem:
+// eventos de baixa prioridade são descartados (digitação) ou agrupados (presença) quando a fila
+// passa de lowPriorityWatermark; com a fila cheia os demais frames ficam só no buffer de replay e
+// o cliente recebe um "resync" quando a fila esvaziar; a conexão só é encerrada se a sobrecarga
+// durar mais que overloadGrace (a sessão continua disponível para resume).
+type backpressurePolicy struct {
+	lowPriorityWatermark int
+	overloadGrace        time.Duration
+	presenceFlushDelay   time.Duration // intervalo para reenviar a presença agrupada
+}
+
+var backpressure = backpressurePolicy{
+	lowPriorityWatermark: sendBufferSize / 2,
+	overloadGrace:        10 * time.Second,
+	presenceFlushDelay:   time.Second,
+}
+
+// Métricas publicadas em /debug/vars
+var (
+	// gatewayDroppedFrames conta os frames não enfileirados, por tipo de evento
+	gatewayDroppedFrames = expvar.NewMap("gateway_dropped_frames")
+
+	// gatewayBackpressure conta presenças agrupadas, resyncs e desconexões por sobrecarga
+	gatewayBackpressure = expvar.NewMap("gateway_backpressure")
+)
+
+// ResyncData avisa o cliente que frames a partir de FromSeq não foram entregues: ele deve
+// retomar a sessão (resume com o último seq recebido) ou recarregar o estado pela API
+type ResyncData struct {
+	FromSeq uint64 `json:"fromSeq"`
+	Reason  string `json:"reason"`
+}
+
+// loadBackpressurePolicy aplica GATEWAY_LOW_PRIORITY_WATERMARK e GATEWAY_OVERLOAD_GRACE, se definidos
+func loadBackpressurePolicy() {
+	if value := os.Getenv("GATEWAY_LOW_PRIORITY_WATERMARK"); value != "" {
+		if watermark, err := strconv.Atoi(value); err == nil && watermark > 0 && watermark <= sendBufferSize {
+			backpressure.lowPriorityWatermark = watermark
+		}
+	}
+	if value := os.Getenv("GATEWAY_OVERLOAD_GRACE"); value != "" {
+		if grace, err := time.ParseDuration(value); err == nil && grace > 0 {
+			backpressure.overloadGrace = grace
+		}
+	}
+}
+
+// lowPriority indica os eventos que podem ser descartados ou agrupados sob pressão
+func lowPriority(eventType string) bool {
+	return eventType == "typing" || eventType == "presence"
+}
+
+// frameType extrai o tipo do evento do início do frame ("type" é o primeiro campo, após "op")
+func frameType(frame []byte) string {
+	head := frame
+	if len(head) > 32 {
+		head = head[:32]
+	}
+	start := bytes.Index(head, []byte(`"type":"`))
+	if start < 0 {
+		return "unknown"
+	}
+	start += len(`"type":"`)
+	end := bytes.IndexByte(frame[start:], '"')
+	if end < 0 {
+		return "unknown"
+	}
+	return string(frame[start : start+end])
+}
+
+// deferLowPriority descarta (digitação) ou agrupa por usuário (presença) um evento de baixa
+// prioridade que não cabe na fila. Chamado com c.mu adquirido.
+func (c *WebSocketConn) deferLowPriority(eventType string, frame []byte) {
+	if eventType != "presence" {
+		gatewayDroppedFrames.Add(eventType, 1)
+		return
+	}
+
+	var presence struct {
+		UserID string `json:"userId"`
+	}
+	json.Unmarshal(frame, &presence)
+
+	if c.pendingPresence == nil {
+		c.pendingPresence = make(map[string][]byte)
+	}
+	if _, replaced := c.pendingPresence[presence.UserID]; replaced {
+		gatewayDroppedFrames.Add(eventType, 1)
+	}
+	c.pendingPresence[presence.UserID] = frame
+	gatewayBackpressure.Add("presence_coalesced", 1)
+
+	if c.presenceTimer == nil {
+		c.presenceTimer = time.AfterFunc(backpressure.presenceFlushDelay, c.flushPendingPresence)
+	}
+}
+
+// flushPendingPresence reenvia a presença agrupada; se a fila ainda estiver ocupada, tenta de novo depois
+func (c *WebSocketConn) flushPendingPresence() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.presenceTimer = nil
+	if c.closed || len(c.pendingPresence) == 0 {
+		return
+	}
+	if !c.relieve() {
+		c.presenceTimer = time.AfterFunc(backpressure.presenceFlushDelay, c.flushPendingPresence)
+	}
+}
+
+// relieve, se a fila tiver espaço, envia o resync pendente e a presença agrupada.
+// Retorna false se a fila continua ocupada. Chamado com c.mu adquirido.
+func (c *WebSocketConn) relieve() bool {
+	if c.send != nil && len(c.send) >= backpressure.lowPriorityWatermark {
+		return false
+	}
+
+	if !c.overloadedSince.IsZero() {
+		data, _ := json.Marshal(ResyncData{FromSeq: c.resyncFrom, Reason: "backpressure"})
+		frame, _ := json.Marshal(WebSocketMessage{
+			Type:      "resync",
+			UserID:    c.userID.String(),
+			Data:      data,
+			Timestamp: time.Now(),
+		})
+		c.overloadedSince = time.Time{}
+		if c.enqueue(frame, "resync") {
+			gatewayBackpressure.Add("resyncs", 1)
+		}
+	}
+
+	for userID, frame := range c.pendingPresence {
+		delete(c.pendingPresence, userID)
+		c.enqueue(frame, "presence")
+	}
+	return true
+}
+
+// overloaded registra um frame que não coube na fila cheia e encerra a conexão se a
+// sobrecarga já dura mais que overloadGrace. Chamado com c.mu adquirido.
+func (c *WebSocketConn) overloaded(eventType string) {
+	gatewayDroppedFrames.Add(eventType, 1)
+
+	now := time.Now()
+	if c.overloadedSince.IsZero() {
+		c.overloadedSince = now
+		c.resyncFrom = c.seq
+		return
+	}
+	if now.Sub(c.overloadedSince) > backpressure.overloadGrace && c.conn != nil {
+		gatewayBackpressure.Add("overload_disconnects", 1)
+		c.overloadedSince = time.Time{}
+		c.conn.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+func testFrame(eventType, userID string) []byte {
+	frame, _ := json.Marshal(WebSocketMessage{Type: eventType, UserID: userID, Timestamp: time.Now()})
+	return frame
+}
+
+// TestBackpressurePolicy verifica a ordem da política: baixa prioridade primeiro, resync depois
+func TestBackpressurePolicy(t *testing.T) {
+	client := newTestConn(uuid.Must(uuid.NewV4()), 0)
+
+	// Fila acima do limite: digitação é descartada e presença agrupada por usuário
+	for i := 0; i < backpressure.lowPriorityWatermark; i++ {
+		if !client.dispatch(testFrame("message", "author")) {
+			t.Fatalf("message %d should be queued", i)
+		}
+	}
+	if client.dispatch(testFrame("typing", "u1")) {
+		t.Fatal("typing should be dropped above the watermark")
+	}
+	client.dispatch(testFrame("presence", "u1"))
+	client.dispatch(testFrame("presence", "u1"))
+	client.dispatch(testFrame("presence", "u2"))
+	if len(client.pendingPresence) != 2 {
+		t.Fatalf("expected presence coalesced per user, got %d pending", len(client.pendingPresence))
+	}
+
+	// Fila cheia: mensagens não cabem, mas ficam no replay e a sessão entra em sobrecarga
+	for len(client.send) < cap(client.send) {
+		client.dispatch(testFrame("message", "author"))
+	}
+	lastQueued := client.seq
+	if client.dispatch(testFrame("message", "author")) {
+		t.Fatal("message should not fit in a full queue")
+	}
+	if client.overloadedSince.IsZero() || client.resyncFrom != lastQueued+1 {
+		t.Fatalf("expected overload from seq %d, got %d", lastQueued+1, client.resyncFrom)
+	}
+
+	// Fila esvaziada: o próximo frame é precedido do resync e da presença agrupada
+	for len(client.send) > 0 {
+		<-client.send
+	}
+	client.dispatch(testFrame("message", "author"))
+
+	var types []string
+	for len(client.send) > 0 {
+		types = append(types, frameType(<-client.send))
+	}
+	if len(types) != 4 || types[0] != "resync" || types[1] != "presence" || types[2] != "presence" || types[3] != "message" {
+		t.Fatalf("unexpected frames after relief: %v", types)
+	}
+	if !client.overloadedSince.IsZero() || len(client.pendingPresence) != 0 {
+		t.Fatal("expected backpressure state to be cleared")
+	}
+	client.presenceTimer.Stop()
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// Codificações do gateway, negociadas pelo parâmetro "encoding" da URL de conexão.
+// Internamente os frames são sempre JSON; a codificação é aplicada na borda de cada conexão,
+// então uma sessão pode ser retomada por uma conexão com outra codificação.
+const (
+	encodingJSON    = "json"
+	encodingMsgpack = "msgpack"
+)
+
+const (
+	// compressionThreshold é o tamanho mínimo de uma mensagem para comprimi-la (permessage-deflate);
+	// abaixo disso o custo de CPU não compensa
+	compressionThreshold = 256
+
+	// maxCoalescedFrames e maxCoalescedBytes limitam quantos frames da fila são agrupados
+	// numa mensagem WebSocket (v2)
+	maxCoalescedFrames = 64
+	maxCoalescedBytes  = 64 * 1024
+)
+
+// frameCodec converte frames JSON para o formato da conexão e vice-versa
+type frameCodec struct {
+	name        string
+	messageType int    // websocket.TextMessage ou websocket.BinaryMessage
+	separator   []byte // entre frames agrupados numa mensagem (MessagePack é autodelimitado)
+	encode      func(frame []byte) ([]byte, error)
+	decode      func(data []byte) ([]byte, error)
+}
+
+var codecs = map[string]*frameCodec{
+	encodingJSON: {
+		name:        encodingJSON,
+		messageType: websocket.TextMessage,
+		separator:   []byte("\n"),
+		encode:      func(frame []byte) ([]byte, error) { return frame, nil },
+		decode:      func(data []byte) ([]byte, error) { return data, nil },
+	},
+	encodingMsgpack: {
+		name:        encodingMsgpack,
+		messageType: websocket.BinaryMessage,
+		encode:      jsonToMsgpack,
+		decode:      msgpackToJSON,
+	},
+}
+
+// negotiateEncoding lê a codificação pedida pelo cliente (parâmetro "encoding"; padrão json)
+func negotiateEncoding(r *http.Request) (*frameCodec, error) {
+	requested := r.URL.Query().Get("encoding")
+	if requested == "" {
+		return codecs[encodingJSON], nil
+	}
+
+	codec, ok := codecs[requested]
+	if !ok {
+		return nil, fmt.Errorf("unsupported encoding %q (supported: %s, %s)", requested, encodingJSON, encodingMsgpack)
+	}
+	return codec, nil
+}
+
+// frameWriter escreve frames numa conexão com a codificação negociada. Com coalesce, os frames
+// enfileirados juntos vão numa única mensagem WebSocket (separados por codec.separator).
+type frameWriter struct {
+	conn     *websocket.Conn
+	codec    *frameCodec
+	coalesce bool
+	logger   *zap.Logger
+}
+
+// encode converte o frame para a codificação da conexão. Frames que não podem ser
+// convertidos são descartados (o erro é registrado) para não derrubar a conexão.
+func (fw *frameWriter) encode(frame []byte) ([]byte, bool) {
+	data, err := fw.codec.encode(frame)
+	if err != nil {
+		fw.logger.Error("failed to encode gateway frame", zap.String("encoding", fw.codec.name), zap.Error(err))
+		return nil, false
+	}
+	return data, true
+}
+
+// write escreve um lote de frames retirado da fila de envio
+func (fw *frameWriter) write(frames [][]byte) error {
+	if !fw.coalesce {
+		for _, frame := range frames {
+			data, ok := fw.encode(frame)
+			if !ok {
+				continue
+			}
+			fw.conn.EnableWriteCompression(len(data) >= compressionThreshold)
+			if err := fw.conn.WriteMessage(fw.codec.messageType, data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	encoded := make([][]byte, 0, len(frames))
+	size := 0
+	for _, frame := range frames {
+		data, ok := fw.encode(frame)
+		if !ok {
+			continue
+		}
+		encoded = append(encoded, data)
+		size += len(data) + len(fw.codec.separator)
+	}
+
+	if len(encoded) == 0 {
+		return nil
+	}
+
+	fw.conn.EnableWriteCompression(size >= compressionThreshold)
+	w, err := fw.conn.NextWriter(fw.codec.messageType)
+	if err != nil {
+		return err
+	}
+	for i, data := range encoded {
+		if i > 0 {
+			w.Write(fw.codec.separator)
+		}
+		w.Write(data)
+	}
+	return w.Close()
+}
+
+// drain completa o lote com os frames já enfileirados, sem bloquear.
+// Retorna false se a fila foi fechada.
+func drain(batch [][]byte, send chan []byte) ([][]byte, bool) {
+	size := 0
+	for _, frame := range batch {
+		size += len(frame)
+	}
+
+	for len(batch) < maxCoalescedFrames && size < maxCoalescedBytes {
+		select {
+		case frame, ok := <-send:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, frame)
+			size += len(frame)
+		default:
+			return batch, true
+		}
+	}
+	return batch, true
+}
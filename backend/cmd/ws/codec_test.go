@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+func TestMsgpackRoundTrip(t *testing.T) {
+	frames := []string{
+		`{"type":"message","channelId":"c1","data":{"content":"olá","count":3,"ratio":0.5,"neg":-200,"big":4294967296,"ok":true,"none":null},"timestamp":"2025-01-01T00:00:00Z"}`,
+		`{"users":[{"userId":"u1"},{"userId":"u2"}],"empty":{},"list":[]}`,
+		`{"long":"` + strings.Repeat("x", 70000) + `"}`,
+	}
+	for _, frame := range frames {
+		packed, err := jsonToMsgpack([]byte(frame))
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		unpacked, err := msgpackToJSON(packed)
+		if err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+
+		var expected, got interface{}
+		json.Unmarshal([]byte(frame), &expected)
+		json.Unmarshal(unpacked, &got)
+		if !jsonEqual(expected, got) {
+			t.Errorf("round trip mismatch:\n%s\n%s", frame, unpacked)
+		}
+	}
+
+	if _, err := msgpackToJSON([]byte{0x82, 0xa1}); err == nil {
+		t.Error("expected error for truncated frame")
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return bytes.Equal(x, y)
+}
+
+// busyChannelFrames simula o tráfego de um canal movimentado: mensagens, digitação e presença
+func busyChannelFrames(n int) [][]byte {
+	channelID := uuid.Must(uuid.NewV4()).String()
+	frames := make([][]byte, 0, n)
+	for i := 0; i < n; i++ {
+		userID := uuid.Must(uuid.NewV4()).String()
+		var msg WebSocketMessage
+		switch i % 10 {
+		case 0, 1, 2:
+			data, _ := json.Marshal(TypingData{IsTyping: true, Username: fmt.Sprintf("user%d", i)})
+			msg = WebSocketMessage{Type: "typing", ChannelID: channelID, UserID: userID, Data: data}
+		case 3:
+			data, _ := json.Marshal(PresenceData{Status: presenceOnline, LastSeen: time.Now()})
+			msg = WebSocketMessage{Type: "presence", UserID: userID, Data: data}
+		default:
+			data, _ := json.Marshal(MessageData{
+				ID:        uuid.Must(uuid.NewV4()).String(),
+				Content:   fmt.Sprintf("mensagem %d: alguém viu o deploy de hoje? o gateway está respondendo normalmente aqui", i),
+				AuthorID:  userID,
+				Username:  fmt.Sprintf("user%d", i),
+				AvatarURL: "https://cdn.nexus.app/avatars/" + userID + ".png",
+				CreatedAt: time.Now(),
+			})
+			msg = WebSocketMessage{Type: "message", ChannelID: channelID, UserID: userID, Data: data}
+		}
+		msg.Timestamp = time.Now()
+		frame, _ := json.Marshal(msg)
+		frames = append(frames, withSeq(frame, uint64(i+1)))
+	}
+	return frames
+}
+
+// countingConn conta os bytes recebidos pelo cliente (servidor -> cliente)
+type countingConn struct {
+	net.Conn
+	read *int64
+}
+
+func (c countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	atomic.AddInt64(c.read, int64(n))
+	return n, err
+}
+
+// BenchmarkGatewayWire mede bytes no fio e CPU por frame para cada combinação de codificação,
+// compressão e agrupamento, entregando o tráfego de um canal movimentado em rajadas de 10 frames.
+func BenchmarkGatewayWire(b *testing.B) {
+	const burst = 10
+	frames := busyChannelFrames(200)
+
+	for _, encoding := range []string{encodingJSON, encodingMsgpack} {
+		for _, compress := range []bool{false, true} {
+			for _, coalesce := range []bool{false, true} {
+				name := fmt.Sprintf("%s/deflate=%t/coalesce=%t", encoding, compress, coalesce)
+				b.Run(name, func(b *testing.B) {
+					benchmarkWire(b, frames, burst, codecs[encoding], compress, coalesce)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkWire(b *testing.B, frames [][]byte, burst int, codec *frameCodec, compress, coalesce bool) {
+	// O leitor de deflate do gorilla registra erros ao fechar a conexão de teste
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	upgrader := websocket.Upgrader{EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		writer := &frameWriter{conn: conn, codec: codec, coalesce: coalesce, logger: zap.NewNop()}
+
+		// Cada mensagem do cliente pede uma rodada de frames
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+			for start := 0; start < len(frames); start += burst {
+				if err := writer.write(frames[start : start+burst]); err != nil {
+					return
+				}
+			}
+		}
+	}))
+	defer server.Close()
+
+	var wire int64
+	dialer := websocket.Dialer{
+		EnableCompression: compress,
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return countingConn{Conn: conn, read: &wire}, nil
+		},
+	}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer conn.Close()
+
+	expected := len(frames)
+	if coalesce {
+		expected = len(frames) / burst
+	}
+
+	atomic.StoreInt64(&wire, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte("go")); err != nil {
+			b.Fatal(err)
+		}
+		for received := 0; received < expected; received++ {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	b.StopTimer()
+
+	b.ReportMetric(float64(atomic.LoadInt64(&wire))/float64(b.N*len(frames)), "wire-B/frame")
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*len(frames)), "ns/frame")
+}
+
+// BenchmarkFrameEncode mede o custo de CPU da conversão de cada frame para a codificação da conexão
+func BenchmarkFrameEncode(b *testing.B) {
+	frames := busyChannelFrames(200)
+	for _, encoding := range []string{encodingJSON, encodingMsgpack} {
+		codec := codecs[encoding]
+		b.Run(encoding, func(b *testing.B) {
+			size := 0
+			for i := 0; i < b.N; i++ {
+				data, err := codec.encode(frames[i%len(frames)])
+				if err != nil {
+					b.Fatal(err)
+				}
+				size += len(data)
+			}
+			b.ReportMetric(float64(size)/float64(b.N), "B/frame")
+		})
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+const (
+	// gatewayChannelPrefix é o prefixo dos frames de clientes (chat, digitação, voz) trocados entre os nós
+	gatewayChannelPrefix = "gateway.channel."
+
+	// gatewayUserPrefix é o prefixo dos frames direcionados a um usuário (sinalização WebRTC)
+	gatewayUserPrefix = "gateway.user."
+
+	// gatewayBroadcastSubject transmite frames para todos os clientes de todos os nós
+	gatewayBroadcastSubject = "gateway.broadcast"
+
+	// nodeHeader identifica o nó que publicou o frame; o próprio nó o ignora (já entregou localmente)
+	nodeHeader = "Nexus-Node"
+
+	// excludeHeader indica um usuário que não deve receber o frame (ex: o autor)
+	excludeHeader = "Nexus-Exclude-User"
+
+	// sessionHeader restringe um frame de usuário a uma das suas sessões (sinalização WebRTC)
+	sessionHeader = "Nexus-Session"
+)
+
+// fanout distribui frames entre as réplicas do gateway via NATS. Cada nó assina apenas
+// os subjects dos canais e usuários dos seus clientes locais (com contagem de referências),
+// entrega localmente o que publica e descarta o eco das próprias publicações.
+type fanout struct {
+	nc     *nats.Conn
+	nodeID string
+	logger *zap.Logger
+
+	// Entrega local (clientes conectados a este nó)
+	deliverChannel func(channelID string, frame []byte, excludeUserID string)
+	deliverUser    func(userID string, frame []byte, sessionID string)
+	deliverServer  func(serverID string, frame []byte)
+	deliverAll     func(frame []byte)
+
+	mu        sync.Mutex
+	channels  map[string]*fanoutSub
+	users     map[string]*fanoutSub
+	servers   map[string]*fanoutSub
+	broadcast *nats.Subscription
+}
+
+// fanoutSub são as assinaturas NATS de um canal, usuário ou servidor e quantos clientes locais dependem delas
+type fanoutSub struct {
+	refs int
+	subs []*nats.Subscription
+}
+
+func newFanout(nc *nats.Conn, nodeID string, logger *zap.Logger) *fanout {
+	return &fanout{
+		nc:       nc,
+		nodeID:   nodeID,
+		logger:   logger,
+		channels: make(map[string]*fanoutSub),
+		users:    make(map[string]*fanoutSub),
+		servers:  make(map[string]*fanoutSub),
+	}
+}
+
+// Start assina o subject de broadcast global
+func (f *fanout) Start() error {
+	sub, err := f.nc.Subscribe(gatewayBroadcastSubject, func(msg *nats.Msg) {
+		if f.isEcho(msg) {
+			return
+		}
+		f.deliverAll(msg.Data)
+	})
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.broadcast = sub
+	f.mu.Unlock()
+	return nil
+}
+
+// Stop cancela todas as assinaturas do nó
+func (f *fanout) Stop() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.broadcast != nil {
+		f.broadcast.Unsubscribe()
+	}
+	for _, index := range []map[string]*fanoutSub{f.channels, f.users, f.servers} {
+		for id, entry := range index {
+			for _, sub := range entry.subs {
+				sub.Unsubscribe()
+			}
+			delete(index, id)
+		}
+	}
+}
+
+// JoinChannel registra interesse de um cliente local no canal: frames dos outros nós e eventos da API
+func (f *fanout) JoinChannel(channelID string) {
+	f.join(f.channels, channelID, f.handleChannel, gatewayChannelPrefix, channelEventsPrefix)
+}
+
+// LeaveChannel remove o interesse de um cliente local no canal
+func (f *fanout) LeaveChannel(channelID string) {
+	f.leave(f.channels, channelID)
+}
+
+// JoinUser registra uma conexão local do usuário
+func (f *fanout) JoinUser(userID string) {
+	f.join(f.users, userID, f.handleUser, gatewayUserPrefix, userEventsPrefix)
+}
+
+// LeaveUser remove uma conexão local do usuário
+func (f *fanout) LeaveUser(userID string) {
+	f.leave(f.users, userID)
+}
+
+// JoinServer registra interesse de uma sessão local nos eventos do servidor publicados pela API
+func (f *fanout) JoinServer(serverID string) {
+	f.join(f.servers, serverID, f.handleServer, serverEventsPrefix)
+}
+
+// LeaveServer remove o interesse de uma sessão local nos eventos do servidor
+func (f *fanout) LeaveServer(serverID string) {
+	f.leave(f.servers, serverID)
+}
+
+// PublishChannel entrega o frame aos clientes locais do canal e aos demais nós
+func (f *fanout) PublishChannel(channelID string, frame []byte, excludeUserID string) {
+	f.deliverChannel(channelID, frame, excludeUserID)
+	f.publish(gatewayChannelPrefix+channelID, frame, excludeUserID)
+}
+
+// PublishUser entrega o frame às sessões do usuário em qualquer nó; se sessionID
+// não for vazio, apenas a essa sessão
+func (f *fanout) PublishUser(userID string, frame []byte, sessionID string) {
+	f.deliverUser(userID, frame, sessionID)
+
+	msg := f.newMsg(gatewayUserPrefix+userID, frame)
+	if sessionID != "" {
+		msg.Header.Set(sessionHeader, sessionID)
+	}
+	f.publishMsg(msg)
+}
+
+// PublishAll entrega o frame a todos os clientes de todos os nós
+func (f *fanout) PublishAll(frame []byte) {
+	f.deliverAll(frame)
+	f.publish(gatewayBroadcastSubject, frame, "")
+}
+
+func (f *fanout) publish(subject string, frame []byte, excludeUserID string) {
+	msg := f.newMsg(subject, frame)
+	if excludeUserID != "" {
+		msg.Header.Set(excludeHeader, excludeUserID)
+	}
+	f.publishMsg(msg)
+}
+
+// newMsg cria a mensagem NATS de um frame, identificando este nó
+func (f *fanout) newMsg(subject string, frame []byte) *nats.Msg {
+	msg := nats.NewMsg(subject)
+	msg.Data = frame
+	msg.Header.Set(nodeHeader, f.nodeID)
+	return msg
+}
+
+func (f *fanout) publishMsg(msg *nats.Msg) {
+	if err := f.nc.PublishMsg(msg); err != nil {
+		f.logger.Error("failed to publish gateway frame", zap.String("subject", msg.Subject), zap.Error(err))
+	}
+}
+
+func (f *fanout) handleChannel(msg *nats.Msg) {
+	if f.isEcho(msg) {
+		return
+	}
+	f.deliverChannel(subjectID(msg.Subject), msg.Data, msg.Header.Get(excludeHeader))
+}
+
+func (f *fanout) handleUser(msg *nats.Msg) {
+	if f.isEcho(msg) {
+		return
+	}
+	f.deliverUser(subjectID(msg.Subject), msg.Data, msg.Header.Get(sessionHeader))
+}
+
+func (f *fanout) handleServer(msg *nats.Msg) {
+	f.deliverServer(subjectID(msg.Subject), msg.Data)
+}
+
+// isEcho indica se o frame foi publicado por este nó (eventos da API não têm o header)
+func (f *fanout) isEcho(msg *nats.Msg) bool {
+	return msg.Header.Get(nodeHeader) == f.nodeID
+}
+
+func (f *fanout) join(index map[string]*fanoutSub, id string, handler nats.MsgHandler, prefixes ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if entry, ok := index[id]; ok {
+		entry.refs++
+		return
+	}
+
+	entry := &fanoutSub{refs: 1}
+	for _, prefix := range prefixes {
+		sub, err := f.nc.Subscribe(prefix+id, handler)
+		if err != nil {
+			f.logger.Error("failed to subscribe gateway subject", zap.String("subject", prefix+id), zap.Error(err))
+			continue
+		}
+		entry.subs = append(entry.subs, sub)
+	}
+	index[id] = entry
+}
+
+func (f *fanout) leave(index map[string]*fanoutSub, id string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	entry, ok := index[id]
+	if !ok {
+		return
+	}
+	entry.refs--
+	if entry.refs > 0 {
+		return
+	}
+
+	for _, sub := range entry.subs {
+		if err := sub.Unsubscribe(); err != nil {
+			f.logger.Warn("failed to unsubscribe gateway subject", zap.String("subject", sub.Subject), zap.Error(err))
+		}
+	}
+	delete(index, id)
+}
+
+// subjectID retorna o último token do subject (ID do canal, do usuário ou do servidor)
+func subjectID(subject string) string {
+	return subject[strings.LastIndex(subject, ".")+1:]
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// heartbeatInterval é o intervalo de heartbeat anunciado no hello
+	heartbeatInterval = 45 * time.Second
+
+	// zombieTimeout é quanto a conexão pode ficar sem receber nada do cliente (frames,
+	// heartbeats ou pongs) antes de ser considerada morta e encerrada
+	zombieTimeout = 2 * heartbeatInterval
+
+	// pingPeriod é o intervalo dos pings WebSocket do servidor; os pongs medem a latência
+	pingPeriod = heartbeatInterval / 2
+)
+
+// Métricas publicadas em /debug/vars
+var (
+	// gatewayHeartbeat conta acks de heartbeat e conexões encerradas por falta de heartbeat
+	gatewayHeartbeat = expvar.NewMap("gateway_heartbeat")
+
+	// gatewayLatency é o histograma das medições de RTT das sessões, em ms
+	gatewayLatency = expvar.NewMap("gateway_latency_ms")
+)
+
+// latencyBuckets são os limites superiores (ms) do histograma de latência
+var latencyBuckets = []int64{50, 100, 250, 500, 1000}
+
+// HeartbeatAckData é o payload do heartbeat ack: a latência da conexão medida pelo servidor
+type HeartbeatAckData struct {
+	Latency int64 `json:"latency"` // RTT em ms (média móvel); 0 enquanto não houver medição
+}
+
+// heartbeatAck responde ao heartbeat do cliente (op 1 em v2, "ping" em v1)
+func (ws *WebSocketServer) heartbeatAck(client *WebSocketConn, msg *WebSocketMessage) {
+	var data HeartbeatData
+	if !ws.decodePayload(client, msg, &data) {
+		return
+	}
+
+	ack, _ := json.Marshal(HeartbeatAckData{Latency: client.latency().Milliseconds()})
+	frame, _ := json.Marshal(WebSocketMessage{
+		Op:        OpHeartbeatAck,
+		Type:      "heartbeat_ack",
+		Data:      ack,
+		Timestamp: time.Now(),
+	})
+	if !client.sendControl(frame) {
+		ws.logger.Warn("failed to send heartbeat ack, buffer full", zap.String("userID", client.userID.String()))
+		return
+	}
+	gatewayHeartbeat.Add("acks", 1)
+}
+
+// pingPayload é o conteúdo do ping WebSocket: o instante do envio, devolvido no pong
+func pingPayload(now time.Time) []byte {
+	return strconv.AppendInt(nil, now.UnixNano(), 10)
+}
+
+// recordLatency registra o RTT medido pelo pong de um ping do servidor
+func (ws *WebSocketServer) recordLatency(client *WebSocketConn, appData string) {
+	sent, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		return
+	}
+	rtt := time.Since(time.Unix(0, sent))
+	if rtt < 0 {
+		return
+	}
+
+	client.observeLatency(rtt)
+	gatewayLatency.Add(latencyBucket(rtt), 1)
+}
+
+func latencyBucket(rtt time.Duration) string {
+	ms := rtt.Milliseconds()
+	for _, limit := range latencyBuckets {
+		if ms < limit {
+			return "lt_" + strconv.FormatInt(limit, 10)
+		}
+	}
+	return "ge_" + strconv.FormatInt(latencyBuckets[len(latencyBuckets)-1], 10)
+}
+
+// observeLatency atualiza a média móvel do RTT da sessão
+func (c *WebSocketConn) observeLatency(rtt time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.rtt == 0 {
+		c.rtt = rtt
+		return
+	}
+	c.rtt = (3*c.rtt + rtt) / 4
+}
+
+// latency retorna o RTT da sessão
+func (c *WebSocketConn) latency() time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rtt
+}
+
+// sessionStats resume as sessões do nó e suas latências (publicado como gateway_sessions)
+func (ws *WebSocketServer) sessionStats() interface{} {
+	sessions := ws.hub.All()
+
+	var total, max time.Duration
+	measured := 0
+	for _, session := range sessions {
+		rtt := session.latency()
+		if rtt == 0 {
+			continue
+		}
+		measured++
+		total += rtt
+		if rtt > max {
+			max = rtt
+		}
+	}
+
+	stats := map[string]int64{
+		"sessions":       int64(len(sessions)),
+		"latency_max_ms": max.Milliseconds(),
+	}
+	if measured > 0 {
+		stats["latency_avg_ms"] = (total / time.Duration(measured)).Milliseconds()
+	}
+	return stats
+}
+
+// isTimeout indica se o erro de leitura veio do prazo de leitura (conexão zumbi)
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"hash/fnv"
+	"sync"
+)
+
+// hubShardCount é o número de partições do registro de sessões; cada partição tem o próprio lock
+const hubShardCount = 32
+
+// hubListener é avisado das inscrições locais (o fanout assina e cancela os subjects NATS).
+// As chamadas acontecem com o lock da sessão adquirido, na ordem em que as inscrições mudam.
+type hubListener interface {
+	JoinChannel(channelID string)
+	LeaveChannel(channelID string)
+	JoinUser(userID string)
+	LeaveUser(userID string)
+	JoinServer(serverID string)
+	LeaveServer(serverID string)
+}
+
+// hub é o registro de sessões do nó, com índices canal -> sessões, servidor -> sessões,
+// usuário -> sessões e sessionID -> sessão, particionados por chave. Uma sessão pertence a
+// um servidor enquanto estiver inscrita em algum canal dele.
+//
+// Ordem dos locks: WebSocketConn.mu antes do lock da partição. Os métodos de leitura
+// devolvem cópias, para que a entrega (que adquire WebSocketConn.mu) ocorra sem o lock da partição.
+type hub struct {
+	shards   [hubShardCount]*hubShard
+	listener hubListener
+}
+
+type hubShard struct {
+	mu       sync.RWMutex
+	channels map[string]map[*WebSocketConn]struct{}
+	servers  map[string]map[*WebSocketConn]struct{}
+	users    map[string]map[*WebSocketConn]struct{}
+	sessions map[string]*WebSocketConn
+}
+
+func newHub(listener hubListener) *hub {
+	h := &hub{listener: listener}
+	for i := range h.shards {
+		h.shards[i] = &hubShard{
+			channels: make(map[string]map[*WebSocketConn]struct{}),
+			servers:  make(map[string]map[*WebSocketConn]struct{}),
+			users:    make(map[string]map[*WebSocketConn]struct{}),
+			sessions: make(map[string]*WebSocketConn),
+		}
+	}
+	return h
+}
+
+func (h *hub) shard(key string) *hubShard {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return h.shards[hash.Sum32()%hubShardCount]
+}
+
+// Add registra a sessão nos índices de usuário e de sessionID
+func (h *hub) Add(client *WebSocketConn) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.closed {
+		return
+	}
+
+	userID := client.userID.String()
+	shard := h.shard(userID)
+	shard.mu.Lock()
+	addIndex(shard.users, userID, client)
+	shard.mu.Unlock()
+
+	shard = h.shard(client.sessionID)
+	shard.mu.Lock()
+	shard.sessions[client.sessionID] = client
+	shard.mu.Unlock()
+
+	h.listener.JoinUser(userID)
+}
+
+// Remove tira a sessão de todos os índices e cancela suas inscrições. Sessões removidas
+// não voltam a ser inscritas (closed). Retorna false se a sessão já tinha sido removida.
+func (h *hub) Remove(client *WebSocketConn) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.removed {
+		return false
+	}
+	client.removed = true
+	client.closed = true
+
+	for channelID, serverID := range client.channels {
+		shard := h.shard(channelID)
+		shard.mu.Lock()
+		removeIndex(shard.channels, channelID, client)
+		shard.mu.Unlock()
+
+		delete(client.channels, channelID)
+		h.listener.LeaveChannel(channelID)
+		h.leaveServer(client, serverID)
+	}
+
+	userID := client.userID.String()
+	shard := h.shard(userID)
+	shard.mu.Lock()
+	registered := removeIndex(shard.users, userID, client)
+	shard.mu.Unlock()
+
+	shard = h.shard(client.sessionID)
+	shard.mu.Lock()
+	if shard.sessions[client.sessionID] == client {
+		delete(shard.sessions, client.sessionID)
+	}
+	shard.mu.Unlock()
+
+	if registered {
+		h.listener.LeaveUser(userID)
+	}
+	return true
+}
+
+// Subscribe inscreve a sessão no canal do servidor serverID ("" para DMs e grupos).
+// Retorna false se já estava inscrita ou foi removida.
+func (h *hub) Subscribe(client *WebSocketConn, channelID, serverID string) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if _, ok := client.channels[channelID]; ok || client.removed {
+		return false
+	}
+	client.channels[channelID] = serverID
+
+	shard := h.shard(channelID)
+	shard.mu.Lock()
+	addIndex(shard.channels, channelID, client)
+	shard.mu.Unlock()
+
+	h.listener.JoinChannel(channelID)
+	h.joinServer(client, serverID)
+	return true
+}
+
+// Unsubscribe cancela a inscrição da sessão no canal. Retorna false se não estava inscrita.
+func (h *hub) Unsubscribe(client *WebSocketConn, channelID string) bool {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	serverID, ok := client.channels[channelID]
+	if !ok {
+		return false
+	}
+	delete(client.channels, channelID)
+
+	shard := h.shard(channelID)
+	shard.mu.Lock()
+	removeIndex(shard.channels, channelID, client)
+	shard.mu.Unlock()
+
+	h.listener.LeaveChannel(channelID)
+	h.leaveServer(client, serverID)
+	return true
+}
+
+// joinServer conta mais um canal inscrito do servidor; no primeiro a sessão entra no
+// índice do servidor. Chamado com o lock da sessão adquirido.
+func (h *hub) joinServer(client *WebSocketConn, serverID string) {
+	if serverID == "" {
+		return
+	}
+	client.servers[serverID]++
+	if client.servers[serverID] > 1 {
+		return
+	}
+
+	shard := h.shard(serverID)
+	shard.mu.Lock()
+	addIndex(shard.servers, serverID, client)
+	shard.mu.Unlock()
+
+	h.listener.JoinServer(serverID)
+}
+
+// leaveServer desconta um canal inscrito do servidor; no último a sessão sai do índice.
+// Chamado com o lock da sessão adquirido.
+func (h *hub) leaveServer(client *WebSocketConn, serverID string) {
+	if serverID == "" {
+		return
+	}
+	client.servers[serverID]--
+	if client.servers[serverID] > 0 {
+		return
+	}
+	delete(client.servers, serverID)
+
+	shard := h.shard(serverID)
+	shard.mu.Lock()
+	removeIndex(shard.servers, serverID, client)
+	shard.mu.Unlock()
+
+	h.listener.LeaveServer(serverID)
+}
+
+// ChannelClients retorna as sessões inscritas no canal
+func (h *hub) ChannelClients(channelID string) []*WebSocketConn {
+	shard := h.shard(channelID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return snapshot(shard.channels[channelID])
+}
+
+// ServerClients retorna as sessões inscritas em algum canal do servidor
+func (h *hub) ServerClients(serverID string) []*WebSocketConn {
+	shard := h.shard(serverID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return snapshot(shard.servers[serverID])
+}
+
+// UserClients retorna as sessões do usuário neste nó
+func (h *hub) UserClients(userID string) []*WebSocketConn {
+	shard := h.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return snapshot(shard.users[userID])
+}
+
+// HasUser indica se o usuário tem alguma sessão neste nó
+func (h *hub) HasUser(userID string) bool {
+	shard := h.shard(userID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return len(shard.users[userID]) > 0
+}
+
+// Session retorna a sessão pelo ID, ou nil
+func (h *hub) Session(sessionID string) *WebSocketConn {
+	shard := h.shard(sessionID)
+	shard.mu.RLock()
+	defer shard.mu.RUnlock()
+	return shard.sessions[sessionID]
+}
+
+// All retorna todas as sessões do nó
+func (h *hub) All() []*WebSocketConn {
+	var clients []*WebSocketConn
+	for _, shard := range h.shards {
+		shard.mu.RLock()
+		for _, set := range shard.users {
+			clients = append(clients, snapshot(set)...)
+		}
+		shard.mu.RUnlock()
+	}
+	return clients
+}
+
+func addIndex(index map[string]map[*WebSocketConn]struct{}, key string, client *WebSocketConn) {
+	set, ok := index[key]
+	if !ok {
+		set = make(map[*WebSocketConn]struct{})
+		index[key] = set
+	}
+	set[client] = struct{}{}
+}
+
+func removeIndex(index map[string]map[*WebSocketConn]struct{}, key string, client *WebSocketConn) bool {
+	set, ok := index[key]
+	if !ok {
+		return false
+	}
+	if _, ok := set[client]; !ok {
+		return false
+	}
+	delete(set, client)
+	if len(set) == 0 {
+		delete(index, key)
+	}
+	return true
+}
+
+func snapshot(set map[*WebSocketConn]struct{}) []*WebSocketConn {
+	clients := make([]*WebSocketConn, 0, len(set))
+	for client := range set {
+		clients = append(clients, client)
+	}
+	return clients
+}
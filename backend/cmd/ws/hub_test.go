@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/gofrs/uuid"
+)
+
+// countingListener conta as referências de canais, usuários e servidores como o fanout faria
+type countingListener struct {
+	mu       sync.Mutex
+	channels map[string]int
+	users    map[string]int
+	servers  map[string]int
+}
+
+func newCountingListener() *countingListener {
+	return &countingListener{channels: make(map[string]int), users: make(map[string]int), servers: make(map[string]int)}
+}
+
+func (l *countingListener) JoinChannel(channelID string)  { l.add(l.channels, channelID, 1) }
+func (l *countingListener) LeaveChannel(channelID string) { l.add(l.channels, channelID, -1) }
+func (l *countingListener) JoinUser(userID string)        { l.add(l.users, userID, 1) }
+func (l *countingListener) LeaveUser(userID string)       { l.add(l.users, userID, -1) }
+func (l *countingListener) JoinServer(serverID string)    { l.add(l.servers, serverID, 1) }
+func (l *countingListener) LeaveServer(serverID string)   { l.add(l.servers, serverID, -1) }
+
+func (l *countingListener) add(index map[string]int, key string, delta int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	index[key] += delta
+	if index[key] < 0 {
+		panic("negative refcount for " + key)
+	}
+	if index[key] == 0 {
+		delete(index, key)
+	}
+}
+
+func newTestConn(userID uuid.UUID, n int) *WebSocketConn {
+	return &WebSocketConn{
+		userID:    userID,
+		username:  "user",
+		sessionID: fmt.Sprintf("%s-%d", userID, n),
+		send:      make(chan []byte, sendBufferSize),
+		channels:  make(map[string]string),
+		servers:   make(map[string]int),
+	}
+}
+
+// TestHubConcurrentLoad exercita o hub com inscrições, entregas e desconexões simultâneas.
+// Deve ser executado com -race.
+func TestHubConcurrentLoad(t *testing.T) {
+	const (
+		users           = 50
+		sessionsPerUser = 3
+		channels        = 20
+		iterations      = 200
+	)
+
+	listener := newCountingListener()
+	h := newHub(listener)
+
+	userIDs := make([]uuid.UUID, users)
+	for i := range userIDs {
+		userIDs[i] = uuid.Must(uuid.NewV4())
+	}
+	// Alguns canais pertencem a servidores (que agrupam vários canais); os demais são DMs
+	serverIDs := []string{"", uuid.Must(uuid.NewV4()).String(), uuid.Must(uuid.NewV4()).String()}
+	channelIDs := make([]string, channels)
+	channelServers := make(map[string]string, channels)
+	for i := range channelIDs {
+		channelIDs[i] = uuid.Must(uuid.NewV4()).String()
+		channelServers[channelIDs[i]] = serverIDs[i%len(serverIDs)]
+	}
+
+	var clients []*WebSocketConn
+	for _, userID := range userIDs {
+		for n := 0; n < sessionsPerUser; n++ {
+			clients = append(clients, newTestConn(userID, n))
+		}
+	}
+
+	// Esvazia as filas para que a entrega não bloqueie nem sature
+	var drain sync.WaitGroup
+	for _, client := range clients {
+		drain.Add(1)
+		go func(send chan []byte) {
+			defer drain.Done()
+			for range send {
+			}
+		}(client.send)
+	}
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(seed int64, client *WebSocketConn) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(seed))
+
+			h.Add(client)
+			for i := 0; i < iterations; i++ {
+				channelID := channelIDs[rng.Intn(channels)]
+				switch rng.Intn(6) {
+				case 0:
+					h.Subscribe(client, channelID, channelServers[channelID])
+				case 1:
+					h.Unsubscribe(client, channelID)
+				case 2:
+					for _, target := range h.ChannelClients(channelID) {
+						target.dispatch([]byte(`{"type":"message"}`))
+					}
+					for _, target := range h.ServerClients(channelServers[channelID]) {
+						target.dispatch([]byte(`{"type":"emoji.create"}`))
+					}
+				case 3:
+					for _, target := range h.UserClients(userIDs[rng.Intn(users)].String()) {
+						target.dispatch([]byte(`{"type":"offer"}`))
+					}
+				case 4:
+					for _, target := range h.All() {
+						target.dispatch([]byte(`{"type":"presence"}`))
+					}
+				case 5:
+					client.isSubscribed(channelID)
+					h.Session(client.sessionID)
+					h.HasUser(client.userID.String())
+				}
+			}
+			h.Remove(client)
+		}(int64(i), client)
+	}
+	wg.Wait()
+
+	for _, client := range clients {
+		if h.Remove(client) {
+			t.Fatalf("session %s removed twice", client.sessionID)
+		}
+		if h.Subscribe(client, channelIDs[0], channelServers[channelIDs[0]]) {
+			t.Fatalf("removed session %s was subscribed again", client.sessionID)
+		}
+		close(client.send)
+	}
+	drain.Wait()
+
+	if all := h.All(); len(all) != 0 {
+		t.Fatalf("expected empty hub, got %d sessions", len(all))
+	}
+	for _, channelID := range channelIDs {
+		if clients := h.ChannelClients(channelID); len(clients) != 0 {
+			t.Fatalf("channel %s still has %d sessions", channelID, len(clients))
+		}
+	}
+	for _, client := range clients {
+		if h.Session(client.sessionID) != nil {
+			t.Fatalf("session %s still registered", client.sessionID)
+		}
+	}
+	for _, serverID := range serverIDs {
+		if clients := h.ServerClients(serverID); len(clients) != 0 {
+			t.Fatalf("server %s still has %d sessions", serverID, len(clients))
+		}
+	}
+	if len(listener.channels) != 0 || len(listener.users) != 0 || len(listener.servers) != 0 {
+		t.Fatalf("leaked subscriptions: %d channels, %d users, %d servers", len(listener.channels), len(listener.users), len(listener.servers))
+	}
+}
+
+func TestWithSeq(t *testing.T) {
+	cases := map[string]string{
+		`{"type":"message"}`: `{"seq":7,"type":"message"}`,
+		`{}`:                 `{"seq":7}`,
+		`[]`:                 `[]`,
+	}
+	for frame, expected := range cases {
+		if got := string(withSeq([]byte(frame), 7)); got != expected {
+			t.Errorf("withSeq(%s) = %s, expected %s", frame, got, expected)
+		}
+	}
+}
@@ -5,11 +5,16 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -20,65 +25,186 @@ import (
 	"github.com/nats-io/nats.go"
 	"go.uber.org/zap"
 
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/commands"
 	"github.com/nexus/backend/internal/config"
+	"github.com/nexus/backend/internal/database"
 	"github.com/nexus/backend/internal/middleware"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/ratelimit"
 )
 
-// WebSocketMessage representa uma mensagem WebSocket
+// WebSocketMessage representa um frame do gateway (ver protocol.go)
 type WebSocketMessage struct {
-	Type      string          `json:"type"` // "message", "presence", "typing", "ping"
+	Op        int             `json:"op,omitempty"` // opcode (v2); ausente = OpDispatch
+	Type      string          `json:"type"`         // "message", "presence", "typing", "ping"
 	ChannelID string          `json:"channelId,omitempty"`
 	UserID    string          `json:"userId,omitempty"`
 	Data      json.RawMessage `json:"data,omitempty"`
+	Nonce     string          `json:"nonce,omitempty"` // identificador do cliente para deduplicar reenvios
 	Timestamp time.Time       `json:"timestamp"`
 }
 
 // MessageData representa os dados de uma mensagem de chat
 type MessageData struct {
 	ID        string    `json:"id"`
-	Content   string    `json:"content"`
+	Content   string    `json:"content" protocol:"required"`
 	AuthorID  string    `json:"authorId"`
 	Username  string    `json:"username"`
 	AvatarURL string    `json:"avatarUrl,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 }
 
-// PresenceData representa dados de presença
+// PresenceData representa dados de presença. CustomStatus e Activities são preenchidos pelo
+// servidor (o cliente define o status personalizado pela API e as atividades com "activities").
 type PresenceData struct {
-	Status   string    `json:"status"` // "online", "offline", "idle", "dnd"
-	LastSeen time.Time `json:"lastSeen"`
+	Status       string               `json:"status" protocol:"required"` // "online", "offline", "idle", "dnd", "invisible" (apenas do cliente)
+	LastSeen     time.Time            `json:"lastSeen"`
+	CustomStatus *models.CustomStatus `json:"customStatus,omitempty"`
+	Activities   []models.Activity    `json:"activities,omitempty"`
 }
 
-// TypingData representa dados de digitação
+// TypingData representa dados de digitação. Username é preenchido pelo servidor com o nome
+// da sessão (o valor enviado pelo cliente é ignorado).
 type TypingData struct {
 	IsTyping bool   `json:"isTyping"`
 	Username string `json:"username"`
 }
 
-// WebSocketConn representa uma conexão WebSocket ativa
+// ErrorData representa os dados de um frame de erro enviado ao cliente
+type ErrorData struct {
+	Code       string  `json:"code"` // "rate_limited", "forbidden", "not_found", ...
+	Message    string  `json:"message"`
+	RetryAfter float64 `json:"retry_after,omitempty"` // segundos
+}
+
+// ReadyData é enviado logo após a conexão: o usuário e os canais aos quais foi inscrito
+type ReadyData struct {
+	SessionID string         `json:"sessionId"` // usado no op "resume" após uma queda
+	UserID    string         `json:"userId"`
+	Username  string         `json:"username"`
+	Channels  []ReadyChannel `json:"channels"`
+}
+
+// ReadyChannel representa um canal inscrito automaticamente (canais dos servidores e DMs)
+type ReadyChannel struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	ServerID string `json:"serverId,omitempty"`
+}
+
+// WebSocketConn representa a sessão de um cliente: sobrevive à queda da conexão por resumeWindow (ver session.go)
 type WebSocketConn struct {
-	userID   uuid.UUID
-	username string
-	conn     *websocket.Conn
-	send     chan []byte
-	channels map[string]bool // canais aos quais o usuário está inscrito
+	userID    uuid.UUID
+	username  string
+	sessionID string
+	version   int // versão do protocolo negociada na conexão
+
+	mu       sync.Mutex        // protege todos os campos abaixo
+	conn     *websocket.Conn   // conexão atual; nil enquanto a sessão está desconectada
+	send     chan []byte       // fila da conexão atual; nil enquanto a sessão está desconectada
+	channels map[string]string // canal inscrito -> servidor do canal ("" para DMs), espelhado no hub
+	servers  map[string]int    // servidor -> quantos canais dele estão inscritos
+
+	voiceChannel string            // canal de voz em que o usuário está, "" se nenhum
+	activities   []models.Activity // atividades informadas pelo cliente nesta sessão
+	status       string            // status de presença desta sessão, "" = online
+	rtt          time.Duration     // latência da conexão (média móvel), medida pelos pings do servidor
+
+	lastActivity time.Time // último frame do cliente, exceto heartbeats
+	autoIdle     bool      // sem atividade por idleTimeout: a sessão aparece como ociosa
+
+	seq     uint64
+	replay  []sessionFrame
+	closed  bool // sessão encerrada: não pode mais ser retomada
+	removed bool // sessão fora do hub
+	expiry  *time.Timer
+
+	// Backpressure (ver backpressure.go)
+	pendingPresence map[string][]byte // userID -> último frame de presença não enviado
+	presenceTimer   *time.Timer
+	overloadedSince time.Time // primeiro frame perdido com a fila cheia; zero se não há sobrecarga
+	resyncFrom      uint64    // seq a partir do qual o cliente deve se ressincronizar
+}
+
+// isSubscribed indica se a sessão está inscrita no canal
+func (c *WebSocketConn) isSubscribed(channelID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.channels[channelID]
+	return ok
+}
+
+// setVoiceChannel define o canal de voz da sessão e retorna o anterior
+func (c *WebSocketConn) setVoiceChannel(channelID string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.voiceChannel
+	c.voiceChannel = channelID
+	return previous
+}
+
+// currentVoiceChannel retorna o canal de voz da sessão ("" se nenhum)
+func (c *WebSocketConn) currentVoiceChannel() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.voiceChannel
+}
+
+// clearVoiceChannel sai do canal de voz se for o atual
+func (c *WebSocketConn) clearVoiceChannel(channelID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.voiceChannel != channelID {
+		return false
+	}
+	c.voiceChannel = ""
+	return true
 }
 
 // WebSocketServer gerencia conexões WebSocket
 type WebSocketServer struct {
-	clients       map[*WebSocketConn]bool
-	register      chan *WebSocketConn
-	unregister    chan *WebSocketConn
-	broadcast     chan []byte
-	nc            *nats.Conn
-	presenceCache *cache.UserPresenceCache
-	logger        *zap.Logger
+	hub               *hub // sessões do nó (conectadas ou aguardando resume)
+	nc                *nats.Conn
+	nodeID            string  // identifica este nó entre as réplicas do gateway
+	fanout            *fanout // entrega entre nós via NATS
+	voice             *voiceRoster
+	db                *database.CassandraDB
+	policy            *access.Policy
+	presence          *presenceTracker   // status de cada usuário em cada nó do gateway
+	presenceMu        sync.Mutex         // serializa o recálculo e a entrega de presença
+	audienceCache     *cache.MemoryCache // userID -> quem pode ver a presença do usuário
+	customStatusCache *cache.MemoryCache // userID -> status personalizado (JSON)
+	typing            *typingTracker     // quem está digitando em cada canal
+	sentNonces        *cache.MemoryCache // "userID:nonce" -> sentNonce (envios deste nó)
+	channelCache      *cache.MemoryCache // slowmode, status de moderador e linha de cada canal
+	limiter           *ratelimit.MessageLimiter
+	logger            *zap.Logger
 }
 
+const (
+	// nonceWindow é por quanto tempo um nonce de envio é lembrado por usuário
+	nonceWindow = 10 * time.Minute
+
+	// defaultMetricsAddr é onde /debug/vars é servido quando WS_METRICS_ADDR não é definido
+	defaultMetricsAddr = "127.0.0.1:9090"
+
+	// maxNonceLength limita o tamanho do nonce aceito (o mesmo limite da API)
+	maxNonceLength = 64
+
+	// channelCacheTTL é por quanto tempo configurações de canal e decisões de acesso ficam em cache no gateway
+	channelCacheTTL = 30 * time.Second
+
+	// commandTimeout é quanto o gateway espera a API executar um comando de barra
+	commandTimeout = 5 * time.Second
+)
+
 var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
+	ReadBufferSize:    1024,
+	WriteBufferSize:   1024,
+	EnableCompression: true,
 	CheckOrigin: func(r *http.Request) bool {
 		origin := r.Header.Get("Origin")
 		// Allow no origin (e.g. mobile apps, curl)
@@ -103,49 +229,94 @@ var upgrader = websocket.Upgrader{
 }
 
 // NewWebSocketServer cria um novo servidor WebSocket
-func NewWebSocketServer(nc *nats.Conn, logger *zap.Logger) *WebSocketServer {
-	return &WebSocketServer{
-		clients:       make(map[*WebSocketConn]bool),
-		register:      make(chan *WebSocketConn),
-		unregister:    make(chan *WebSocketConn),
-		broadcast:     make(chan []byte, 256),
-		nc:            nc,
-		presenceCache: cache.NewUserPresenceCache(),
-		logger:        logger,
+func NewWebSocketServer(nc *nats.Conn, db *database.CassandraDB, logger *zap.Logger) *WebSocketServer {
+	nodeID := uuid.Must(uuid.NewV4()).String()
+	ws := &WebSocketServer{
+		nc:                nc,
+		nodeID:            nodeID,
+		fanout:            newFanout(nc, nodeID, logger),
+		voice:             newVoiceRoster(),
+		db:                db,
+		policy:            access.NewPolicy(db, channelCacheTTL),
+		presence:          newPresenceTracker(),
+		audienceCache:     cache.NewMemoryCache(presenceAudienceTTL),
+		customStatusCache: cache.NewMemoryCache(presenceAudienceTTL),
+		typing:            newTypingTracker(typingTimeout, typingThrottle),
+		sentNonces:        cache.NewMemoryCache(nonceWindow),
+		channelCache:      cache.NewMemoryCache(channelCacheTTL),
+		limiter:           ratelimit.NewMessageLimiter(ratelimit.DefaultBurst, ratelimit.DefaultRefill, db),
+		logger:            logger,
 	}
+
+	ws.hub = newHub(ws.fanout)
+	ws.fanout.deliverChannel = ws.broadcastToChannel
+	ws.fanout.deliverUser = ws.sendToUser
+	ws.fanout.deliverServer = ws.broadcastToServer
+	ws.fanout.deliverAll = ws.broadcastAll
+	return ws
 }
 
-// Run inicia o loop do servidor
-func (ws *WebSocketServer) Run() {
-	for {
-		select {
-		case client := <-ws.register:
-			ws.clients[client] = true
-			ws.logger.Info("client connected",
-				zap.String("userID", client.userID.String()),
-				zap.String("username", client.username))
-			ws.presenceCache.SetPresence(client.userID, "online")
-
-		case client := <-ws.unregister:
-			if _, ok := ws.clients[client]; ok {
-				delete(ws.clients, client)
-				close(client.send)
-				ws.logger.Info("client disconnected",
-					zap.String("userID", client.userID.String()),
-					zap.String("username", client.username))
-				ws.presenceCache.RemovePresence(client.userID)
-			}
+// Start assina os subjects NATS compartilhados entre os nós do gateway
+func (ws *WebSocketServer) Start() error {
+	if err := ws.fanout.Start(); err != nil {
+		return err
+	}
+	expvar.Publish("gateway_sessions", expvar.Func(ws.sessionStats))
+	if err := ws.startPresence(); err != nil {
+		return err
+	}
+	_, err := ws.nc.Subscribe(gatewayVoiceSubject, ws.handleVoiceRosterEvent)
+	return err
+}
 
-		case message := <-ws.broadcast:
-			for client := range ws.clients {
-				select {
-				case client.send <- message:
-				default:
-					// Buffer cheio, remover cliente
-					go func(c *WebSocketConn) { ws.unregister <- c }(client)
-				}
-			}
-		}
+// subscribeClient inscreve o cliente no canal (e o nó nos subjects NATS do canal e do seu servidor)
+func (ws *WebSocketServer) subscribeClient(client *WebSocketConn, channelID, serverID string) {
+	ws.hub.Subscribe(client, channelID, serverID)
+}
+
+// unsubscribeClient remove a inscrição do cliente no canal
+func (ws *WebSocketServer) unsubscribeClient(client *WebSocketConn, channelID string) {
+	ws.hub.Unsubscribe(client, channelID)
+}
+
+// registerClient registra uma sessão nova
+func (ws *WebSocketServer) registerClient(client *WebSocketConn) {
+	ws.hub.Add(client)
+	ws.logger.Info("client connected",
+		zap.String("userID", client.userID.String()),
+		zap.String("username", client.username),
+		zap.String("sessionID", client.sessionID))
+	ws.updatePresence(client.userID)
+}
+
+// closeSession encerra a sessão: janela de resume expirada ou sessão substituída por um resume.
+// Sessões que voltaram a ter conexão são mantidas.
+func (ws *WebSocketServer) closeSession(client *WebSocketConn) {
+	client.mu.Lock()
+	if client.conn != nil || client.closed {
+		client.mu.Unlock()
+		return
+	}
+	client.closed = true
+	client.mu.Unlock()
+
+	ws.leaveVoice(client)
+	if !ws.hub.Remove(client) {
+		return
+	}
+
+	ws.logger.Info("client disconnected",
+		zap.String("userID", client.userID.String()),
+		zap.String("username", client.username),
+		zap.String("sessionID", client.sessionID))
+	ws.updatePresence(client.userID)
+}
+
+// broadcastAll envia o frame para todas as sessões do nó
+func (ws *WebSocketServer) broadcastAll(message []byte) {
+	for _, client := range ws.hub.All() {
+		// Filas cheias são tratadas pela política de backpressure
+		client.dispatch(message)
 	}
 }
 
@@ -203,6 +374,17 @@ func (ws *WebSocketServer) HandleWS(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	version, err := negotiateVersion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	codec, err := negotiateEncoding(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	// Upgrade para WebSocket
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -216,49 +398,123 @@ func (ws *WebSocketServer) HandleWS(w http.ResponseWriter, r *http.Request) {
 		username = "User-" + userID.String()[:8]
 	}
 
+	send := make(chan []byte, sendBufferSize)
 	client := &WebSocketConn{
-		userID:   userID,
-		username: username,
-		conn:     conn,
-		send:     make(chan []byte, 256),
-		channels: make(map[string]bool),
+		userID:    userID,
+		username:  username,
+		conn:      conn,
+		send:      send,
+		channels:  make(map[string]string),
+		servers:   make(map[string]int),
+		sessionID: uuid.Must(uuid.NewV4()).String(),
+		version:   version,
+
+		lastActivity: time.Now(),
 	}
 
-	ws.register <- client
+	if version == protocolV1 {
+		// Inscrever nos canais dos servidores e DMs do usuário e enviar o READY
+		ws.sendReady(client)
+		ws.registerClient(client)
+	} else {
+		// v2: o READY é enviado após o identify
+		ws.sendHello(client, codec.name)
+	}
 
 	// Goroutine para ler mensagens
-	go ws.readPump(client)
-	// Goroutine para escrever mensagens
-	go ws.writePump(client)
+	go ws.readPump(client, conn, codec)
+	// Goroutine para escrever mensagens (clientes v2 recebem os frames enfileirados agrupados)
+	go ws.writePump(&frameWriter{conn: conn, codec: codec, coalesce: version >= protocolV2, logger: ws.logger}, send)
+}
+
+// sendReady inscreve o cliente (ainda não registrado) em todos os canais acessíveis
+// e enfileira o frame "ready" com a lista. Outros canais podem ser inscritos via "subscribe".
+func (ws *WebSocketServer) sendReady(client *WebSocketConn) {
+	ready := ReadyData{
+		SessionID: client.sessionID,
+		UserID:    client.userID.String(),
+		Username:  client.username,
+		Channels:  []ReadyChannel{},
+	}
+
+	rows, err := ws.policy.UserChannels(ready.UserID)
+	if err != nil {
+		ws.logger.Error("failed to load user channels", zap.String("userID", ready.UserID), zap.Error(err))
+	}
+	for _, row := range rows {
+		channel := ReadyChannel{
+			ID:       row["channel_id"].(string),
+			ServerID: access.ServerID(row),
+		}
+		channel.Name, _ = row["name"].(string)
+		channel.Type, _ = row["type"].(string)
+
+		ws.subscribeClient(client, channel.ID, channel.ServerID)
+		ready.Channels = append(ready.Channels, channel)
+	}
+
+	data, _ := json.Marshal(ready)
+	frame, _ := json.Marshal(WebSocketMessage{
+		Type:      "ready",
+		UserID:    ready.UserID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	client.dispatch(frame)
+
+	ws.logger.Info("client ready",
+		zap.String("userID", ready.UserID),
+		zap.Int("channels", len(ready.Channels)))
 }
 
-// readPump lê mensagens do cliente
-func (ws *WebSocketServer) readPump(client *WebSocketConn) {
+// readPump lê mensagens da conexão. Após um "resume" a conexão passa a servir a sessão retomada.
+func (ws *WebSocketServer) readPump(client *WebSocketConn, conn *websocket.Conn, codec *frameCodec) {
 	defer func() {
-		ws.unregister <- client
-		client.conn.Close()
+		ws.detach(client, conn)
+		conn.Close()
 	}()
 
-	// Aumentar timeout para 5 minutos
-	client.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
-	client.conn.SetPongHandler(func(string) error {
-		client.conn.SetReadDeadline(time.Now().Add(5 * time.Minute))
+	// Qualquer frame ou pong renova o prazo; sem eles por zombieTimeout a conexão é encerrada
+	conn.SetReadDeadline(time.Now().Add(zombieTimeout))
+	conn.SetPongHandler(func(appData string) error {
+		conn.SetReadDeadline(time.Now().Add(zombieTimeout))
+		ws.recordLatency(client, appData)
 		return nil
 	})
 
+	// v2: a sessão começa com identify ou resume; sem eles a conexão é encerrada
+	identified := client.version == protocolV1
+	var identifyTimer *time.Timer
+	if !identified {
+		identifyTimer = time.AfterFunc(identifyTimeout, func() { conn.Close() })
+		defer identifyTimer.Stop()
+	}
+
 	for {
-		_, messageBytes, err := client.conn.ReadMessage()
+		_, messageBytes, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if isTimeout(err) {
+				gatewayHeartbeat.Add("zombie_disconnects", 1)
+				ws.logger.Info("closing zombie connection, no heartbeat received",
+					zap.String("userID", client.userID.String()),
+					zap.String("sessionID", client.sessionID))
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				ws.logger.Error("websocket error", zap.Error(err))
 			}
 			break
 		}
+		conn.SetReadDeadline(time.Now().Add(zombieTimeout))
 
 		// Parse mensagem WebSocket
 		var wsMsg WebSocketMessage
+		if messageBytes, err = codec.decode(messageBytes); err != nil {
+			ws.logger.Info("malformed gateway frame", zap.String("userID", client.userID.String()), zap.Error(err))
+			ws.sendError(client, &WebSocketMessage{}, ErrorData{Code: "invalid_frame", Message: "frame is not valid " + codec.name})
+			continue
+		}
 		if err := json.Unmarshal(messageBytes, &wsMsg); err != nil {
-			ws.logger.Error("failed to parse message", zap.Error(err))
+			ws.logger.Info("malformed gateway frame", zap.String("userID", client.userID.String()), zap.Error(err))
+			ws.sendError(client, &WebSocketMessage{}, ErrorData{Code: "invalid_frame", Message: "frame must be a JSON object"})
 			continue
 		}
 
@@ -266,8 +522,37 @@ func (ws *WebSocketServer) readPump(client *WebSocketConn) {
 		wsMsg.UserID = client.userID.String()
 		wsMsg.Timestamp = time.Now()
 
-		// Processar baseado no tipo
-		ws.handleMessage(client, &wsMsg)
+		switch {
+		case wsMsg.Op == OpResume || wsMsg.Type == "resume":
+			// Retomada de uma sessão anterior (session ID + último seq recebido)
+			if resumed := ws.resumeSession(client, &wsMsg); resumed != client {
+				client = resumed
+				identified = true
+			}
+		case wsMsg.Op == OpIdentify:
+			if identified {
+				ws.sendError(client, &wsMsg, ErrorData{Code: "already_identified", Message: "session already identified"})
+			} else {
+				identified = ws.identify(client, &wsMsg)
+			}
+		case !identified:
+			ws.sendError(client, &wsMsg, ErrorData{Code: "not_identified", Message: "identify or resume first"})
+		case wsMsg.Op == OpHeartbeat:
+			ws.heartbeatAck(client, &wsMsg)
+		case wsMsg.Op == OpDispatch:
+			if wsMsg.Type != "ping" {
+				ws.markActive(client)
+			}
+			// Processar baseado no tipo
+			ws.handleMessage(client, &wsMsg)
+		default:
+			ws.sendError(client, &wsMsg, ErrorData{Code: "unknown_opcode", Message: "unknown opcode " + strconv.Itoa(wsMsg.Op)})
+		}
+
+		if identified && identifyTimer != nil {
+			identifyTimer.Stop()
+			identifyTimer = nil
+		}
 	}
 }
 
@@ -276,17 +561,23 @@ func (ws *WebSocketServer) handleMessage(client *WebSocketConn, msg *WebSocketMe
 	switch msg.Type {
 	case "message":
 		// Mensagem de chat
-		ws.handleChatMessage(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleChatMessage(client, msg)
+		}
 	case "typing":
 		// Indicador de digitação
-		ws.handleTypingMessage(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleTypingMessage(client, msg)
+		}
+	case "activities":
+		ws.handleActivitiesMessage(client, msg)
 	case "presence":
 		// Atualização de presença
 		ws.handlePresenceMessage(client, msg)
 	case "subscribe":
-		// Inscrever em canal
-		if msg.ChannelID != "" {
-			client.channels[msg.ChannelID] = true
+		// Inscrever em canal (apenas canais aos quais o usuário tem acesso)
+		if msg.ChannelID != "" && ws.authorizeChannel(client, msg) {
+			ws.subscribeClient(client, msg.ChannelID, ws.channelServer(msg.ChannelID))
 			ws.logger.Info("client subscribed to channel",
 				zap.String("userID", client.userID.String()),
 				zap.String("channelID", msg.ChannelID))
@@ -294,20 +585,24 @@ func (ws *WebSocketServer) handleMessage(client *WebSocketConn, msg *WebSocketMe
 	case "unsubscribe":
 		// Desinscrever de canal
 		if msg.ChannelID != "" {
-			delete(client.channels, msg.ChannelID)
+			ws.unsubscribeClient(client, msg.ChannelID)
 			ws.logger.Info("client unsubscribed from channel",
 				zap.String("userID", client.userID.String()),
 				zap.String("channelID", msg.ChannelID))
 		}
 	case "ping":
-		// Heartbeat - apenas responder com pong
-		ws.logger.Debug("received ping", zap.String("userID", client.userID.String()))
+		// Heartbeat v1: responder com o ack (e a latência medida)
+		ws.heartbeatAck(client, msg)
 	
 	// WebRTC Signaling
 	case "voice:join":
-		ws.handleVoiceJoin(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleVoiceJoin(client, msg)
+		}
 	case "voice:leave":
-		ws.handleVoiceLeave(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleVoiceLeave(client, msg)
+		}
 	case "voice:offer":
 		ws.handleVoiceOffer(client, msg)
 	case "voice:answer":
@@ -315,82 +610,420 @@ func (ws *WebSocketServer) handleMessage(client *WebSocketConn, msg *WebSocketMe
 	case "voice:ice-candidate":
 		ws.handleVoiceIceCandidate(client, msg)
 	case "voice:mute-status":
-		ws.handleVoiceMuteStatus(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleVoiceMuteStatus(client, msg)
+		}
 	case "voice:video-status":
-		ws.handleVoiceVideoStatus(client, msg)
+		if ws.authorizeChannel(client, msg) {
+			ws.handleVoiceVideoStatus(client, msg)
+		}
 	
 	default:
 		ws.logger.Warn("unknown message type", zap.String("type", msg.Type))
+		ws.sendError(client, msg, ErrorData{Code: "unknown_type", Message: "unknown event type " + strconv.Quote(msg.Type)})
 	}
 }
 
 // handleChatMessage processa mensagens de chat
 func (ws *WebSocketServer) handleChatMessage(client *WebSocketConn, msg *WebSocketMessage) {
+	var messageData MessageData
+	if !ws.decodePayload(client, msg, &messageData) {
+		return
+	}
+
+	// Reenvio com o mesmo nonce: devolver a mensagem original apenas ao remetente, antes do
+	// slowmode e dos comandos. Mensagens do gateway não são salvas, então o nonce é lembrado
+	// apenas por este nó (e não em message_nonces, que a API consulta para replay).
+	nonceKey := ""
+	if msg.Nonce != "" {
+		if len(msg.Nonce) > maxNonceLength {
+			ws.sendError(client, msg, ErrorData{Code: "bad_request", Message: fmt.Sprintf("nonce must be at most %d characters", maxNonceLength)})
+			return
+		}
+		nonceKey = client.userID.String() + ":" + msg.Nonce
+		if !ws.sentNonces.Add(nonceKey, sentNonce{}) {
+			ws.logger.Info("duplicate message send ignored",
+				zap.String("userID", client.userID.String()),
+				zap.String("nonce", msg.Nonce))
+			ws.replayMessage(client, msg, nonceKey)
+			return
+		}
+		// ID atribuído pelo servidor (o cliente reconcilia a mensagem otimista pelo nonce)
+		messageData.ID = uuid.Must(uuid.NewV1()).String()
+		msg.Data, _ = json.Marshal(messageData)
+	}
+	msgBytes, _ := json.Marshal(msg)
+
+	// Slowmode do canal e limite de rajada por usuário
+	if !ws.allowSend(client, msg) {
+		ws.releaseNonce(nonceKey)
+		return
+	}
+
+	// Enviar uma mensagem encerra a digitação do autor no canal
+	ws.stopTyping(client.userID.String(), msg.ChannelID)
+
+	// Comandos de barra são executados pela API
+	if _, _, ok := commands.Parse(messageData.Content); ok {
+		go ws.invokeCommand(client, msg, msgBytes, nonceKey, messageData)
+		return
+	}
+
+	ws.publishChatMessage(msg, msgBytes, nonceKey)
+}
+
+// sentNonce é um envio com nonce lembrado por este nó: o frame entregue ao cliente ou, para
+// comandos com resposta pública, a mensagem salva pela API. Vazio enquanto o envio está em
+// processamento.
+type sentNonce struct {
+	frame     []byte
+	channelID string
+	messageID string
+}
+
+// replayMessage devolve ao remetente o resultado do envio original com o mesmo nonce.
+// Envios ainda em processamento recebem um erro duplicate_nonce.
+func (ws *WebSocketServer) replayMessage(client *WebSocketConn, msg *WebSocketMessage, nonceKey string) {
+	value, _ := ws.sentNonces.Get(nonceKey)
+	sent, _ := value.(sentNonce)
+
+	frame := sent.frame
+	if frame == nil && sent.messageID != "" {
+		if row, err := ws.db.GetMessage(sent.channelID, sent.messageID); err == nil {
+			createdAt, _ := row["ts"].(time.Time)
+			data, _ := json.Marshal(MessageData{
+				ID:        sent.messageID,
+				Content:   row["content"].(string),
+				AuthorID:  row["author_id"].(string),
+				Username:  client.username,
+				CreatedAt: createdAt,
+			})
+			frame, _ = json.Marshal(WebSocketMessage{
+				Type:      "message",
+				ChannelID: sent.channelID,
+				UserID:    client.userID.String(),
+				Data:      data,
+				Nonce:     msg.Nonce,
+				Timestamp: time.Now(),
+			})
+		}
+	}
+	if frame == nil {
+		ws.sendError(client, msg, ErrorData{Code: "duplicate_nonce", Message: "a message with this nonce is still being processed"})
+		return
+	}
+
+	if !client.dispatch(frame) {
+		ws.logger.Warn("failed to replay message to sender, buffer full", zap.String("userID", client.userID.String()))
+	}
+}
+
+// releaseNonce esquece o nonce de um envio que não chegou a ser publicado, para que o
+// reenvio do cliente seja processado
+func (ws *WebSocketServer) releaseNonce(nonceKey string) {
+	if nonceKey != "" {
+		ws.sentNonces.Delete(nonceKey)
+	}
+}
+
+// publishChatMessage publica a mensagem de chat no NATS e a transmite aos clientes do canal
+func (ws *WebSocketServer) publishChatMessage(msg *WebSocketMessage, msgBytes []byte, nonceKey string) {
+	if nonceKey != "" {
+		// Frame original para reenvios (o nonce já foi reservado)
+		ws.sentNonces.Set(nonceKey, sentNonce{frame: msgBytes})
+	}
+
 	// Publicar no NATS para persistência
 	natsSubject := "chat.messages." + msg.ChannelID
-	msgBytes, _ := json.Marshal(msg)
 	if err := ws.nc.Publish(natsSubject, msgBytes); err != nil {
 		ws.logger.Error("failed to publish to NATS", zap.Error(err))
 	}
 
-	// Broadcast para clientes inscritos no canal
-	ws.broadcastToChannel(msg.ChannelID, msgBytes)
+	// Broadcast para clientes inscritos no canal, em todos os nós
+	ws.fanout.PublishChannel(msg.ChannelID, msgBytes, "")
 }
 
-// handleTypingMessage processa indicadores de digitação
-func (ws *WebSocketServer) handleTypingMessage(client *WebSocketConn, msg *WebSocketMessage) {
-	msgBytes, _ := json.Marshal(msg)
-	ws.broadcastToChannel(msg.ChannelID, msgBytes)
+// invokeCommand pede à API a execução de um comando de barra. Respostas públicas chegam
+// pelos eventos do canal; respostas efêmeras e erros são enviados apenas ao autor.
+// Conteúdos que não são comandos conhecidos seguem como mensagens comuns.
+func (ws *WebSocketServer) invokeCommand(client *WebSocketConn, msg *WebSocketMessage, msgBytes []byte, nonceKey string, messageData MessageData) {
+	invoke := commands.InvokeRequest{
+		ChannelID: msg.ChannelID,
+		UserID:    client.userID.String(),
+		Username:  client.username,
+		Content:   messageData.Content,
+	}
+	if nonceKey != "" {
+		// Resposta pública com o ID atribuído ao envio, para que reenvios a encontrem
+		invoke.MessageID = messageData.ID
+	}
+	request, _ := json.Marshal(invoke)
+
+	reply, err := ws.nc.Request(commands.InvokeSubject, request, commandTimeout)
+	if err != nil {
+		ws.logger.Error("command request failed", zap.String("channelID", msg.ChannelID), zap.Error(err))
+		ws.releaseNonce(nonceKey)
+		ws.sendError(client, msg, ErrorData{Code: "command_error", Message: "command is unavailable, try again later"})
+		return
+	}
+
+	var response commands.InvokeResponse
+	if err := json.Unmarshal(reply.Data, &response); err != nil {
+		ws.logger.Error("invalid command response", zap.Error(err))
+		ws.releaseNonce(nonceKey)
+		ws.sendError(client, msg, ErrorData{Code: "command_error", Message: "command is unavailable, try again later"})
+		return
+	}
+
+	switch {
+	case !response.Handled:
+		ws.publishChatMessage(msg, msgBytes, nonceKey)
+	case response.Error != "":
+		ws.releaseNonce(nonceKey)
+		ws.sendError(client, msg, ErrorData{Code: "command_error", Message: response.Error})
+	case response.Ephemeral:
+		frame, _ := json.Marshal(WebSocketMessage{
+			Type:      "message.ephemeral",
+			ChannelID: msg.ChannelID,
+			Data:      response.Message,
+			Nonce:     msg.Nonce,
+			Timestamp: time.Now(),
+		})
+		if nonceKey != "" {
+			ws.sentNonces.Set(nonceKey, sentNonce{frame: frame})
+		}
+		if !client.dispatch(frame) {
+			ws.logger.Warn("failed to send command reply, buffer full", zap.String("userID", client.userID.String()))
+		}
+	default:
+		if nonceKey != "" {
+			ws.sentNonces.Set(nonceKey, sentNonce{channelID: msg.ChannelID, messageID: messageData.ID})
+		}
+	}
+}
+
+// allowSend aplica slowmode e limite de rajada a uma mensagem de chat.
+// Se o envio for bloqueado, envia um frame de erro com retry_after ao cliente e retorna false.
+func (ws *WebSocketServer) allowSend(client *WebSocketConn, msg *WebSocketMessage) bool {
+	userID := client.userID.String()
+
+	// Moderadores do servidor não são limitados
+	isModerator := func() bool { return ws.isModerator(msg.ChannelID, userID) }
+
+	allowed, reason, retryAfter := ws.limiter.Allow(userID, msg.ChannelID, ws.channelSlowmode(msg.ChannelID), isModerator)
+	if allowed {
+		return true
+	}
+
+	ws.logger.Info("message send rate limited",
+		zap.String("userID", userID),
+		zap.String("channelID", msg.ChannelID),
+		zap.String("reason", string(reason)),
+		zap.Duration("retryAfter", retryAfter))
+
+	message := "you are sending messages too fast"
+	if reason == ratelimit.ReasonSlowmode {
+		message = "slowmode is enabled in this channel"
+	}
+
+	ws.sendError(client, msg, ErrorData{
+		Code:       "rate_limited",
+		Message:    message,
+		RetryAfter: retryAfter.Seconds(),
+	})
+	return false
+}
+
+// channelSlowmode retorna o slowmode configurado para o canal (com cache)
+func (ws *WebSocketServer) channelSlowmode(channelID string) time.Duration {
+	key := "slowmode:" + channelID
+	if cached, ok := ws.channelCache.Get(key); ok {
+		return cached.(time.Duration)
+	}
+
+	slowmode := time.Duration(0)
+	channelRow, err := ws.db.GetChannelByID(channelID)
+	if err != nil {
+		ws.logger.Warn("failed to get channel settings", zap.String("channelID", channelID), zap.Error(err))
+	} else if seconds, ok := channelRow["slowmode"].(int); ok {
+		slowmode = time.Duration(seconds) * time.Second
+	}
+
+	ws.channelCache.Set(key, slowmode)
+	return slowmode
+}
+
+// channelRow retorna o canal (com cache). Retorna false se não puder ser carregado.
+func (ws *WebSocketServer) channelRow(channelID string) (map[string]interface{}, bool) {
+	key := "channel:" + channelID
+	if cached, ok := ws.channelCache.Get(key); ok {
+		return cached.(map[string]interface{}), true
+	}
+
+	channelRow, err := ws.db.GetChannelByID(channelID)
+	if err != nil {
+		ws.logger.Warn("failed to get channel", zap.String("channelID", channelID), zap.Error(err))
+		return nil, false
+	}
+	ws.channelCache.Set(key, channelRow)
+	return channelRow, true
+}
+
+// channelServer retorna o servidor do canal ("" para DMs e grupos ou se o canal não puder ser carregado)
+func (ws *WebSocketServer) channelServer(channelID string) string {
+	channelRow, ok := ws.channelRow(channelID)
+	if !ok {
+		return ""
+	}
+	return access.ServerID(channelRow)
+}
+
+// isModerator verifica (com cache) se o usuário modera o servidor do canal
+func (ws *WebSocketServer) isModerator(channelID, userID string) bool {
+	key := "moderator:" + channelID + ":" + userID
+	if cached, ok := ws.channelCache.Get(key); ok {
+		return cached.(bool)
+	}
+
+	isModerator, err := ws.db.IsChannelModerator(channelID, userID)
+	if err != nil {
+		ws.logger.Warn("failed to check moderator status", zap.String("channelID", channelID), zap.Error(err))
+	}
+
+	ws.channelCache.Set(key, isModerator)
+	return isModerator
+}
+
+// authorizeChannel aplica a política de acesso ao canal da mensagem (decisões em cache por
+// channelCacheTTL). Canais já inscritos foram autorizados no subscribe ou no READY. Se o acesso for negado, envia um frame de erro e retorna false.
+func (ws *WebSocketServer) authorizeChannel(client *WebSocketConn, msg *WebSocketMessage) bool {
+	if msg.ChannelID == "" {
+		ws.sendError(client, msg, ErrorData{Code: "bad_request", Message: "channel id required"})
+		return false
+	}
+	if client.isSubscribed(msg.ChannelID) {
+		return true
+	}
+
+	_, err := ws.policy.Channel(msg.ChannelID, client.userID.String())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, access.ErrChannelNotFound):
+		ws.sendError(client, msg, ErrorData{Code: "not_found", Message: err.Error()})
+	case errors.Is(err, access.ErrForbidden):
+		ws.sendError(client, msg, ErrorData{Code: "forbidden", Message: err.Error()})
+	default:
+		ws.logger.Error("failed to check channel access", zap.String("channelID", msg.ChannelID), zap.Error(err))
+		ws.sendError(client, msg, ErrorData{Code: "internal_error", Message: "internal server error"})
+	}
+
+	ws.logger.Info("channel access denied",
+		zap.String("userID", client.userID.String()),
+		zap.String("channelID", msg.ChannelID),
+		zap.String("type", msg.Type))
+	return false
+}
+
+// sendError envia um frame de erro ao cliente referente à mensagem recebida
+func (ws *WebSocketServer) sendError(client *WebSocketConn, msg *WebSocketMessage, data ErrorData) {
+	dataBytes, _ := json.Marshal(data)
+	errorMsg := WebSocketMessage{
+		Type:      "error",
+		ChannelID: msg.ChannelID,
+		Data:      dataBytes,
+		Nonce:     msg.Nonce,
+		Timestamp: time.Now(),
+	}
+	errorBytes, _ := json.Marshal(errorMsg)
+
+	if !client.dispatch(errorBytes) {
+		ws.logger.Warn("failed to send error frame, buffer full", zap.String("userID", client.userID.String()))
+	}
 }
 
 // handlePresenceMessage processa atualizações de presença
 func (ws *WebSocketServer) handlePresenceMessage(client *WebSocketConn, msg *WebSocketMessage) {
 	var presenceData PresenceData
-	if err := json.Unmarshal(msg.Data, &presenceData); err != nil {
-		ws.logger.Error("failed to parse presence data", zap.Error(err))
+	if !ws.decodePayload(client, msg, &presenceData) {
 		return
 	}
 
-	ws.presenceCache.SetPresence(client.userID, presenceData.Status)
+	// O status é por sessão; os outros usuários recebem o agregado de todas as sessões
+	client.setStatus(presenceData.Status)
+	ws.updatePresence(client.userID)
+}
 
-	// Broadcast para todos os clientes
-	msgBytes, _ := json.Marshal(msg)
-	ws.broadcast <- msgBytes
-}
-
-// broadcastToChannel envia mensagem para todos os clientes de um canal
-func (ws *WebSocketServer) broadcastToChannel(channelID string, message []byte) {
-	for client := range ws.clients {
-		if client.channels[channelID] {
-			select {
-			case client.send <- message:
-			default:
-				// Buffer cheio, desconectar cliente
-				go func(c *WebSocketConn) { ws.unregister <- c }(client)
-			}
+// handleActivitiesMessage substitui as atividades informadas pela sessão
+func (ws *WebSocketServer) handleActivitiesMessage(client *WebSocketConn, msg *WebSocketMessage) {
+	var activitiesData ActivitiesData
+	if !ws.decodePayload(client, msg, &activitiesData) {
+		return
+	}
+
+	client.setActivities(activitiesData.Activities)
+	ws.updatePresence(client.userID)
+}
+
+// channelEventsPrefix é o prefixo dos subjects NATS de eventos de canal publicados pela API
+// (ex: message.delete); cada nó assina apenas os canais dos seus clientes (ver fanout)
+const channelEventsPrefix = "events.channel."
+
+// userEventsPrefix é o prefixo dos subjects NATS de eventos direcionados a um usuário
+// (ex: lembretes de /remind)
+const userEventsPrefix = "events.user."
+
+// serverEventsPrefix é o prefixo dos subjects NATS de eventos de servidor publicados pela API
+// (ex: emoji.create); cada nó assina apenas os servidores das sessões inscritas em seus canais
+const serverEventsPrefix = "events.server."
+
+// broadcastToChannel envia mensagem para os clientes locais de um canal, exceto excludeUserID
+// (indicadores de digitação vão apenas a quem ainda tem acesso ao canal, ver broadcastTyping)
+func (ws *WebSocketServer) broadcastToChannel(channelID string, message []byte, excludeUserID string) {
+	eventType := frameType(message)
+	if eventType == "typing" {
+		ws.broadcastTyping(channelID, message, excludeUserID)
+		return
+	}
+	for _, client := range ws.hub.ChannelClients(channelID) {
+		if client.userID.String() != excludeUserID {
+			// Filas cheias são tratadas pela política de backpressure
+			client.dispatch(message)
 		}
 	}
+
+	if eventType == "message.create" {
+		ws.stopTypingOnMessage(channelID, message)
+	}
+}
+
+// broadcastToServer envia o evento às sessões locais inscritas em algum canal do servidor
+func (ws *WebSocketServer) broadcastToServer(serverID string, message []byte) {
+	for _, client := range ws.hub.ServerClients(serverID) {
+		// Filas cheias são tratadas pela política de backpressure
+		client.dispatch(message)
+	}
 }
 
-// sendToUser envia mensagem para um usuário específico
-func (ws *WebSocketServer) sendToUser(userID string, message []byte) {
+// sendToUser envia mensagem para as sessões locais de um usuário (outros nós entregam às suas).
+// Se sessionID não for vazio, apenas essa sessão recebe.
+func (ws *WebSocketServer) sendToUser(userID string, message []byte, sessionID string) {
 	targetUUID, err := uuid.FromString(userID)
 	if err != nil {
 		ws.logger.Error("invalid target user ID", zap.String("userID", userID), zap.Error(err))
 		return
 	}
 
-	for client := range ws.clients {
-		if client.userID == targetUUID {
-			select {
-			case client.send <- message:
-			default:
-				ws.logger.Warn("failed to send to user, buffer full", zap.String("userID", userID))
-			}
-			return
+	for _, client := range ws.hub.UserClients(targetUUID.String()) {
+		if sessionID != "" && client.sessionID != sessionID {
+			continue
+		}
+		if !client.dispatch(message) {
+			ws.logger.Warn("failed to send to user, buffer full",
+				zap.String("userID", userID),
+				zap.String("sessionID", client.sessionID))
 		}
 	}
-	ws.logger.Warn("user not found", zap.String("userID", userID))
 }
 
 // ============================================
@@ -399,79 +1032,66 @@ func (ws *WebSocketServer) sendToUser(userID string, message []byte) {
 
 // handleVoiceJoin processa entrada em canal de voz
 func (ws *WebSocketServer) handleVoiceJoin(client *WebSocketConn, msg *WebSocketMessage) {
+	var joinData VoiceJoinData
+	if !ws.decodePayload(client, msg, &joinData) {
+		return
+	}
+
 	ws.logger.Info("user joining voice channel",
 		zap.String("userID", client.userID.String()),
 		zap.String("username", client.username),
 		zap.String("channelID", msg.ChannelID))
 
 	// Inscrever automaticamente no canal para receber notificações
-	if msg.ChannelID != "" {
-		client.channels[msg.ChannelID] = true
-	}
+	ws.subscribeClient(client, msg.ChannelID, ws.channelServer(msg.ChannelID))
 
-	// Primeiro, enviar lista de usuários já conectados para o novo usuário
-	existingUsers := []map[string]interface{}{}
-	for c := range ws.clients {
-		if c.channels[msg.ChannelID] && c.userID != client.userID {
-			existingUsers = append(existingUsers, map[string]interface{}{
-				"userId":   c.userID.String(),
-				"username": c.username,
-			})
-		}
+	// Trocar de canal de voz equivale a sair do anterior
+	if previous := client.setVoiceChannel(msg.ChannelID); previous != "" && previous != msg.ChannelID {
+		ws.leaveVoiceChannel(client, previous)
 	}
 
+	// Primeiro, enviar lista de usuários já conectados (em qualquer nó) para o novo usuário
+	existingUsers := ws.voice.Members(msg.ChannelID, client.userID.String())
 	if len(existingUsers) > 0 {
-		existingUsersMsg := map[string]interface{}{
-			"type":  "voice:existing-users",
-			"users": existingUsers,
+		existingUsersMsg := VoiceExistingUsersFrame{
+			Type:  "voice:existing-users",
+			Users: existingUsers,
 		}
 		existingUsersBytes, _ := json.Marshal(existingUsersMsg)
-		select {
-		case client.send <- existingUsersBytes:
+		if client.dispatch(existingUsersBytes) {
 			ws.logger.Info("sent existing users list",
 				zap.Int("count", len(existingUsers)),
 				zap.String("to", client.userID.String()))
-		default:
+		} else {
 			ws.logger.Warn("failed to send existing users list")
 		}
 	}
 
-	// Notificar outros usuários no canal que alguém entrou
-	notification := map[string]interface{}{
-		"type":      "voice:user-joined",
-		"userId":    client.userID.String(),
-		"username":  client.username,
-		"channelId": msg.ChannelID,
+	ws.updateVoiceRoster(voiceRosterEvent{
+		ChannelID: msg.ChannelID,
+		UserID:    client.userID.String(),
+		Username:  client.username,
+		SessionID: client.sessionID,
+		Joined:    true,
+	})
+
+	// Notificar outros usuários no canal que alguém entrou (exceto o próprio usuário)
+	notification := VoiceUserJoinedFrame{
+		Type:      "voice:user-joined",
+		UserID:    client.userID.String(),
+		Username:  client.username,
+		SessionID: client.sessionID,
+		ChannelID: msg.ChannelID,
 	}
 	notificationBytes, _ := json.Marshal(notification)
-	
-	// Broadcast para todos no canal (exceto o próprio usuário)
-	count := 0
-	totalInChannel := 0
-	for c := range ws.clients {
-		if c.channels[msg.ChannelID] {
-			totalInChannel++
-			if c.userID != client.userID {
-				ws.logger.Info("attempting to notify user",
-					zap.String("targetUserID", c.userID.String()),
-					zap.String("targetUsername", c.username))
-				select {
-				case c.send <- notificationBytes:
-					count++
-					ws.logger.Info("successfully notified user",
-						zap.String("targetUserID", c.userID.String()))
-				default:
-					ws.logger.Warn("failed to notify user, buffer full", 
-						zap.String("userID", c.userID.String()))
-				}
-			}
-		}
-	}
-	
-	ws.logger.Info("notified users in voice channel", 
-		zap.Int("notified", count),
-		zap.Int("totalInChannel", totalInChannel),
+	ws.fanout.PublishChannel(msg.ChannelID, notificationBytes, client.userID.String())
+
+	ws.logger.Info("notified users in voice channel",
+		zap.Int("existing", len(existingUsers)),
 		zap.String("channelID", msg.ChannelID))
+
+	// O canal de voz aparece nas atividades da presença
+	ws.updatePresence(client.userID)
 }
 
 // handleVoiceLeave processa saída de canal de voz
@@ -480,175 +1100,207 @@ func (ws *WebSocketServer) handleVoiceLeave(client *WebSocketConn, msg *WebSocke
 		zap.String("userID", client.userID.String()),
 		zap.String("channelID", msg.ChannelID))
 
-	// Notificar outros usuários que alguém saiu
-	notification := map[string]interface{}{
-		"type":      "voice:user-left",
-		"userId":    client.userID.String(),
-		"channelId": msg.ChannelID,
+	if !client.clearVoiceChannel(msg.ChannelID) {
+		ws.sendError(client, msg, ErrorData{Code: "not_in_voice", Message: "you are not in this voice channel"})
+		return
+	}
+	ws.leaveVoiceChannel(client, msg.ChannelID)
+}
+
+// leaveVoice remove o cliente do canal de voz atual (saída explícita, troca de canal ou desconexão)
+func (ws *WebSocketServer) leaveVoice(client *WebSocketConn) {
+	if channelID := client.setVoiceChannel(""); channelID != "" {
+		ws.leaveVoiceChannel(client, channelID)
+	}
+}
+
+// leaveVoiceChannel remove o usuário da lista do canal de voz e avisa os participantes
+func (ws *WebSocketServer) leaveVoiceChannel(client *WebSocketConn, channelID string) {
+	ws.updateVoiceRoster(voiceRosterEvent{
+		ChannelID: channelID,
+		UserID:    client.userID.String(),
+		SessionID: client.sessionID,
+		Joined:    false,
+	})
+	ws.publishVoiceLeft(client, channelID)
+	ws.updatePresence(client.userID)
+}
+
+// publishVoiceLeft notifica o canal (em todos os nós) que o usuário saiu da voz
+func (ws *WebSocketServer) publishVoiceLeft(client *WebSocketConn, channelID string) {
+	notification := VoiceUserLeftFrame{
+		Type:      "voice:user-left",
+		UserID:    client.userID.String(),
+		ChannelID: channelID,
 	}
 	notificationBytes, _ := json.Marshal(notification)
-	ws.broadcastToChannel(msg.ChannelID, notificationBytes)
+	ws.fanout.PublishChannel(channelID, notificationBytes, "")
 }
 
 // handleVoiceOffer processa offer WebRTC
 func (ws *WebSocketServer) handleVoiceOffer(client *WebSocketConn, msg *WebSocketMessage) {
-	// Parse mensagem para obter targetUserId e offer
-	var offerData map[string]interface{}
-	if err := json.Unmarshal(msg.Data, &offerData); err != nil {
-		ws.logger.Error("failed to parse offer data", zap.Error(err))
-		return
-	}
-
-	targetUserID, ok := offerData["targetUserId"].(string)
-	if !ok {
-		ws.logger.Error("missing targetUserId in offer")
+	var offerData VoiceOfferData
+	if !ws.decodePayload(client, msg, &offerData) {
 		return
 	}
 
 	ws.logger.Info("forwarding voice offer",
 		zap.String("from", client.userID.String()),
-		zap.String("to", targetUserID))
+		zap.String("to", offerData.TargetUserID))
 
 	// Encaminhar offer para o usuário alvo
-	forwardMsg := map[string]interface{}{
-		"type":   "voice:offer",
-		"userId": client.userID.String(),
-		"offer":  offerData["offer"],
-	}
-	forwardBytes, _ := json.Marshal(forwardMsg)
-	ws.sendToUser(targetUserID, forwardBytes)
+	ws.forwardSignal(client, msg, offerData.TargetUserID, offerData.TargetSessionID, VoiceSignalFrame{
+		Type:  "voice:offer",
+		Offer: offerData.Offer,
+	})
 }
 
 // handleVoiceAnswer processa answer WebRTC
 func (ws *WebSocketServer) handleVoiceAnswer(client *WebSocketConn, msg *WebSocketMessage) {
-	// Parse mensagem para obter targetUserId e answer
-	var answerData map[string]interface{}
-	if err := json.Unmarshal(msg.Data, &answerData); err != nil {
-		ws.logger.Error("failed to parse answer data", zap.Error(err))
-		return
-	}
-
-	targetUserID, ok := answerData["targetUserId"].(string)
-	if !ok {
-		ws.logger.Error("missing targetUserId in answer")
+	var answerData VoiceAnswerData
+	if !ws.decodePayload(client, msg, &answerData) {
 		return
 	}
 
 	ws.logger.Info("forwarding voice answer",
 		zap.String("from", client.userID.String()),
-		zap.String("to", targetUserID))
+		zap.String("to", answerData.TargetUserID))
 
 	// Encaminhar answer para o usuário alvo
-	forwardMsg := map[string]interface{}{
-		"type":   "voice:answer",
-		"userId": client.userID.String(),
-		"answer": answerData["answer"],
-	}
-	forwardBytes, _ := json.Marshal(forwardMsg)
-	ws.sendToUser(targetUserID, forwardBytes)
+	ws.forwardSignal(client, msg, answerData.TargetUserID, answerData.TargetSessionID, VoiceSignalFrame{
+		Type:   "voice:answer",
+		Answer: answerData.Answer,
+	})
 }
 
 // handleVoiceIceCandidate processa ICE candidates
 func (ws *WebSocketServer) handleVoiceIceCandidate(client *WebSocketConn, msg *WebSocketMessage) {
-	// Parse mensagem para obter targetUserId e candidate
-	var candidateData map[string]interface{}
-	if err := json.Unmarshal(msg.Data, &candidateData); err != nil {
-		ws.logger.Error("failed to parse ice candidate data", zap.Error(err))
-		return
-	}
-
-	targetUserID, ok := candidateData["targetUserId"].(string)
-	if !ok {
-		ws.logger.Error("missing targetUserId in ice candidate")
+	var candidateData VoiceIceCandidateData
+	if !ws.decodePayload(client, msg, &candidateData) {
 		return
 	}
 
 	ws.logger.Debug("forwarding ice candidate",
 		zap.String("from", client.userID.String()),
-		zap.String("to", targetUserID))
+		zap.String("to", candidateData.TargetUserID))
 
 	// Encaminhar ICE candidate para o usuário alvo
-	forwardMsg := map[string]interface{}{
-		"type":      "voice:ice-candidate",
-		"userId":    client.userID.String(),
-		"candidate": candidateData["candidate"],
+	ws.forwardSignal(client, msg, candidateData.TargetUserID, candidateData.TargetSessionID, VoiceSignalFrame{
+		Type:      "voice:ice-candidate",
+		Candidate: candidateData.Candidate,
+	})
+}
+
+// forwardSignal encaminha a sinalização à sessão do usuário alvo que está na voz. Apenas
+// participantes do mesmo canal de voz podem trocar sinalização.
+func (ws *WebSocketServer) forwardSignal(client *WebSocketConn, msg *WebSocketMessage, targetUserID, targetSessionID string, frame VoiceSignalFrame) {
+	channelID := client.currentVoiceChannel()
+	if channelID == "" {
+		ws.sendError(client, msg, ErrorData{Code: "not_in_voice", Message: "you are not in a voice channel"})
+		return
+	}
+	target, ok := ws.voice.Member(channelID, targetUserID)
+	if !ok || (targetSessionID != "" && targetSessionID != target.SessionID) {
+		ws.sendError(client, msg, ErrorData{Code: "not_in_voice", Message: "target user is not in your voice channel"})
+		return
 	}
-	forwardBytes, _ := json.Marshal(forwardMsg)
-	ws.sendToUser(targetUserID, forwardBytes)
+
+	frame.UserID = client.userID.String()
+	frame.SessionID = client.sessionID
+	forwardBytes, _ := json.Marshal(frame)
+	ws.fanout.PublishUser(targetUserID, forwardBytes, target.SessionID)
 }
 
 // handleVoiceMuteStatus processa mudanças de status de mute
 func (ws *WebSocketServer) handleVoiceMuteStatus(client *WebSocketConn, msg *WebSocketMessage) {
-	var muteData map[string]interface{}
-	if err := json.Unmarshal(msg.Data, &muteData); err != nil {
-		ws.logger.Error("failed to parse mute data", zap.Error(err))
+	var muteData VoiceMuteData
+	if !ws.decodePayload(client, msg, &muteData) {
+		return
+	}
+
+	if client.currentVoiceChannel() != msg.ChannelID {
+		ws.sendError(client, msg, ErrorData{Code: "not_in_voice", Message: "you are not in this voice channel"})
 		return
 	}
 
 	ws.logger.Info("mute status changed",
 		zap.String("userID", client.userID.String()),
-		zap.Bool("isMuted", muteData["isMuted"].(bool)),
+		zap.Bool("isMuted", *muteData.IsMuted),
 		zap.String("channelID", msg.ChannelID))
 
 	// Broadcast para todos no canal
-	notification := map[string]interface{}{
-		"type":      "voice:mute-status",
-		"userId":    client.userID.String(),
-		"isMuted":   muteData["isMuted"],
-		"channelId": msg.ChannelID,
+	notification := VoiceMuteFrame{
+		Type:      "voice:mute-status",
+		UserID:    client.userID.String(),
+		IsMuted:   *muteData.IsMuted,
+		ChannelID: msg.ChannelID,
 	}
 	notificationBytes, _ := json.Marshal(notification)
-	ws.broadcastToChannel(msg.ChannelID, notificationBytes)
+	ws.fanout.PublishChannel(msg.ChannelID, notificationBytes, "")
 }
 
 // handleVoiceVideoStatus processa mudanças de status de vídeo
 func (ws *WebSocketServer) handleVoiceVideoStatus(client *WebSocketConn, msg *WebSocketMessage) {
-	var videoData map[string]interface{}
-	if err := json.Unmarshal(msg.Data, &videoData); err != nil {
-		ws.logger.Error("failed to parse video data", zap.Error(err))
+	var videoData VoiceVideoData
+	if !ws.decodePayload(client, msg, &videoData) {
+		return
+	}
+
+	if client.currentVoiceChannel() != msg.ChannelID {
+		ws.sendError(client, msg, ErrorData{Code: "not_in_voice", Message: "you are not in this voice channel"})
 		return
 	}
 
 	ws.logger.Info("video status changed",
 		zap.String("userID", client.userID.String()),
-		zap.Bool("isVideoEnabled", videoData["isVideoEnabled"].(bool)),
+		zap.Bool("isVideoEnabled", *videoData.IsVideoEnabled),
 		zap.String("channelID", msg.ChannelID))
 
 	// Broadcast para todos no canal
-	notification := map[string]interface{}{
-		"type":           "voice:video-status",
-		"userId":         client.userID.String(),
-		"isVideoEnabled": videoData["isVideoEnabled"],
-		"channelId":      msg.ChannelID,
+	notification := VoiceVideoFrame{
+		Type:           "voice:video-status",
+		UserID:         client.userID.String(),
+		IsVideoEnabled: *videoData.IsVideoEnabled,
+		ChannelID:      msg.ChannelID,
 	}
 	notificationBytes, _ := json.Marshal(notification)
-	ws.broadcastToChannel(msg.ChannelID, notificationBytes)
+	ws.fanout.PublishChannel(msg.ChannelID, notificationBytes, "")
 }
 
-// writePump escreve mensagens para o cliente
-func (ws *WebSocketServer) writePump(client *WebSocketConn) {
-	ticker := time.NewTicker(54 * time.Second)
+// writePump escreve na conexão os frames da sua fila de envio
+func (ws *WebSocketServer) writePump(writer *frameWriter, send chan []byte) {
+	conn := writer.conn
+	ticker := time.NewTicker(pingPeriod)
 	defer func() {
 		ticker.Stop()
-		client.conn.Close()
+		conn.Close()
 	}()
 
 	for {
 		select {
-		case message, ok := <-client.send:
-			client.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		case message, ok := <-send:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				client.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
-			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			// Frames já enfileirados são escritos juntos
+			batch, open := [][]byte{message}, true
+			if writer.coalesce {
+				batch, open = drain(batch, send)
+			}
+			if err := writer.write(batch); err != nil {
+				return
+			}
+			if !open {
+				conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
 
 		case <-ticker.C:
-			client.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.PingMessage, pingPayload(time.Now())); err != nil {
 				return
 			}
 		}
@@ -656,6 +1308,16 @@ func (ws *WebSocketServer) writePump(client *WebSocketConn) {
 }
 
 func main() {
+	// "nexus-ws schema" imprime o JSON Schema do protocolo (ver make gateway-schema)
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(protocolSchema()); err != nil {
+			log.Fatalf("failed to encode protocol schema: %v", err)
+		}
+		return
+	}
+
 	// Carregar variáveis de ambiente
 	if err := godotenv.Load(); err != nil {
 		log.Println("Warning: .env file not found")
@@ -668,6 +1330,9 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// Política de backpressure das filas de envio (GATEWAY_LOW_PRIORITY_WATERMARK, GATEWAY_OVERLOAD_GRACE)
+	loadBackpressurePolicy()
+
 	// Validate and load environment configuration
 	envConfig, err := config.InitializeEnvironment(logger)
 	if err != nil {
@@ -679,8 +1344,9 @@ func main() {
 
 	// Initialize WebSocket upgrader with CORS-aware CheckOrigin
 	upgrader = websocket.Upgrader{
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
+		ReadBufferSize:    1024,
+		WriteBufferSize:   1024,
+		EnableCompression: true, // permessage-deflate, se o cliente oferecer
 		CheckOrigin: func(r *http.Request) bool {
 			origin := r.Header.Get("Origin")
 			
@@ -719,23 +1385,59 @@ func main() {
 
 	logger.Info("Connected to NATS", zap.String("url", envConfig.NatsURL))
 
+	// Conectar ao Cassandra (configurações de canal e permissões)
+	db, err := database.NewCassandraDB(envConfig.CassandraHosts, envConfig.CassandraKeyspace)
+	if err != nil {
+		logger.Fatal("failed to connect to Cassandra", zap.Error(err))
+	}
+	defer db.Close()
+
+	logger.Info("Connected to Cassandra", zap.Strings("hosts", envConfig.CassandraHosts))
+
 	// Criar servidor WebSocket
-	wsServer := NewWebSocketServer(nc, logger)
+	wsServer := NewWebSocketServer(nc, db, logger)
+
+	// Distribuição entre réplicas: cada nó assina os canais e usuários dos seus clientes
+	// (frames de outros nós e eventos da API) e os subjects globais de presença e voz
+	if err := wsServer.Start(); err != nil {
+		logger.Fatal("failed to subscribe to gateway subjects", zap.Error(err))
+	}
+	defer wsServer.fanout.Stop()
 
-	// Rotas HTTP
-	http.HandleFunc("/ws", wsServer.HandleWS)
+	logger.Info("Gateway node started", zap.String("nodeID", wsServer.nodeID))
 
-	// Iniciar loop do servidor
-	go wsServer.Run()
+	// Rotas HTTP públicas (o expvar registra /debug/vars no DefaultServeMux, que não é exposto)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", wsServer.HandleWS)
+	mux.HandleFunc("/ws/schema", wsServer.HandleSchema)
 
 	// Iniciar servidor HTTP
 	server := &http.Server{
 		Addr:         ":" + envConfig.WSPort,
-		Handler:      http.DefaultServeMux,
+		Handler:      mux,
 		ReadTimeout:  15 * time.Second,
 		WriteTimeout: 15 * time.Second,
 	}
 
+	// Métricas (/debug/vars) apenas em um listener interno, por padrão só no loopback
+	metricsAddr := os.Getenv("WS_METRICS_ADDR")
+	if metricsAddr == "" {
+		metricsAddr = defaultMetricsAddr
+	}
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/debug/vars", expvar.Handler())
+	metricsServer := &http.Server{
+		Addr:         metricsAddr,
+		Handler:      metricsMux,
+		ReadTimeout:  5 * time.Second,
+		WriteTimeout: 5 * time.Second,
+	}
+	go func() {
+		if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server error", zap.Error(err))
+		}
+	}()
+
 	logger.Info("WebSocket server starting", zap.String("port", envConfig.WSPort))
 
 	go func() {
@@ -757,6 +1459,7 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("server shutdown error", zap.Error(err))
 	}
+	metricsServer.Shutdown(ctx)
 
 	logger.Info("WebSocket server stopped")
 }
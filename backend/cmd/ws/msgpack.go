@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Conversão entre frames JSON (formato interno do gateway) e MessagePack. Cobre o modelo de
+// dados do JSON: nil, bool, números, strings, arrays e mapas com chaves string.
+// Arrays e mapas com mais de 15 itens usam sempre o formato de 32 bits.
+
+var errMsgpackTruncated = errors.New("msgpack: truncated frame")
+
+// jsonToMsgpack codifica um frame JSON em MessagePack numa única passada, sem decodificar
+// o frame para valores Go (é executado por conexão para cada frame entregue)
+func jsonToMsgpack(frame []byte) ([]byte, error) {
+	t := &jsonTranscoder{in: frame, out: make([]byte, 0, len(frame))}
+	t.skipSpace()
+	if err := t.value(); err != nil {
+		return nil, err
+	}
+	t.skipSpace()
+	if t.pos != len(t.in) {
+		return nil, errors.New("msgpack: trailing data after JSON frame")
+	}
+	return t.out, nil
+}
+
+// msgpackToJSON decodifica um frame MessagePack em JSON
+func msgpackToJSON(data []byte) ([]byte, error) {
+	d := &msgpackDecoder{data: data}
+	value, err := d.value()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != len(d.data) {
+		return nil, errors.New("msgpack: trailing data after frame")
+	}
+	return json.Marshal(value)
+}
+
+func appendMsgpackNumber(b []byte, number json.Number) ([]byte, error) {
+	if i, err := strconv.ParseInt(string(number), 10, 64); err == nil {
+		return appendMsgpackInt(b, i), nil
+	}
+	if u, err := strconv.ParseUint(string(number), 10, 64); err == nil {
+		b = append(b, 0xcf)
+		return binary.BigEndian.AppendUint64(b, u), nil
+	}
+	f, err := number.Float64()
+	if err != nil {
+		return nil, err
+	}
+	b = append(b, 0xcb)
+	return binary.BigEndian.AppendUint64(b, math.Float64bits(f)), nil
+}
+
+func appendMsgpackInt(b []byte, i int64) []byte {
+	switch {
+	case i >= 0 && i <= 0x7f:
+		return append(b, byte(i))
+	case i < 0 && i >= -32:
+		return append(b, byte(int8(i)))
+	case i >= 0 && i <= math.MaxUint8:
+		return append(b, 0xcc, byte(i))
+	case i >= 0 && i <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xcd), uint16(i))
+	case i >= 0 && i <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, 0xce), uint32(i))
+	case i >= math.MinInt8 && i < 0:
+		return append(b, 0xd0, byte(int8(i)))
+	case i >= math.MinInt16 && i < 0:
+		return binary.BigEndian.AppendUint16(append(b, 0xd1), uint16(int16(i)))
+	case i >= math.MinInt32 && i < 0:
+		return binary.BigEndian.AppendUint32(append(b, 0xd2), uint32(int32(i)))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(i))
+	}
+}
+
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+	return append(b, s...)
+}
+
+// jsonTranscoder converte JSON em MessagePack. Arrays e mapas reservam o cabeçalho de 32 bits
+// e, se couberem no formato fix, o conteúdo é deslocado ao final.
+type jsonTranscoder struct {
+	in  []byte
+	pos int
+	out []byte
+}
+
+var errJSONSyntax = errors.New("msgpack: invalid JSON frame")
+
+func (t *jsonTranscoder) skipSpace() {
+	for t.pos < len(t.in) {
+		switch t.in[t.pos] {
+		case ' ', '\t', '\n', '\r':
+			t.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (t *jsonTranscoder) value() error {
+	if t.pos >= len(t.in) {
+		return errJSONSyntax
+	}
+
+	switch c := t.in[t.pos]; {
+	case c == '{':
+		return t.container('}', 0x80, 0xdf)
+	case c == '[':
+		return t.container(']', 0x90, 0xdd)
+	case c == '"':
+		s, err := t.str()
+		if err != nil {
+			return err
+		}
+		t.out = appendMsgpackString(t.out, s)
+		return nil
+	case c == 't':
+		return t.literal("true", 0xc3)
+	case c == 'f':
+		return t.literal("false", 0xc2)
+	case c == 'n':
+		return t.literal("null", 0xc0)
+	default:
+		return t.number()
+	}
+}
+
+func (t *jsonTranscoder) literal(word string, code byte) error {
+	if !bytes.HasPrefix(t.in[t.pos:], []byte(word)) {
+		return errJSONSyntax
+	}
+	t.pos += len(word)
+	t.out = append(t.out, code)
+	return nil
+}
+
+func (t *jsonTranscoder) number() error {
+	start := t.pos
+	for t.pos < len(t.in) && strings.IndexByte("+-0123456789.eE", t.in[t.pos]) >= 0 {
+		t.pos++
+	}
+	if start == t.pos {
+		return errJSONSyntax
+	}
+
+	var err error
+	t.out, err = appendMsgpackNumber(t.out, json.Number(t.in[start:t.pos]))
+	return err
+}
+
+// str lê uma string JSON; strings com escapes são decodificadas pelo encoding/json
+func (t *jsonTranscoder) str() (string, error) {
+	start := t.pos
+	t.pos++
+	escaped := false
+	for t.pos < len(t.in) {
+		switch t.in[t.pos] {
+		case '\\':
+			escaped = true
+			t.pos += 2
+			continue
+		case '"':
+			t.pos++
+			if !escaped {
+				return string(t.in[start+1 : t.pos-1]), nil
+			}
+			var s string
+			if err := json.Unmarshal(t.in[start:t.pos], &s); err != nil {
+				return "", errJSONSyntax
+			}
+			return s, nil
+		}
+		t.pos++
+	}
+	return "", errJSONSyntax
+}
+
+// container converte um objeto ou array; fix é o código do formato curto, code32 o de 32 bits
+func (t *jsonTranscoder) container(end byte, fix, code32 byte) error {
+	t.pos++
+	header := len(t.out)
+	t.out = append(t.out, code32, 0, 0, 0, 0)
+
+	n := 0
+	t.skipSpace()
+	if t.pos < len(t.in) && t.in[t.pos] == end {
+		t.pos++
+	} else {
+		for {
+			t.skipSpace()
+			if end == '}' {
+				if t.pos >= len(t.in) || t.in[t.pos] != '"' {
+					return errJSONSyntax
+				}
+				key, err := t.str()
+				if err != nil {
+					return err
+				}
+				t.out = appendMsgpackString(t.out, key)
+				t.skipSpace()
+				if t.pos >= len(t.in) || t.in[t.pos] != ':' {
+					return errJSONSyntax
+				}
+				t.pos++
+				t.skipSpace()
+			}
+			if err := t.value(); err != nil {
+				return err
+			}
+			n++
+
+			t.skipSpace()
+			if t.pos >= len(t.in) {
+				return errJSONSyntax
+			}
+			if t.in[t.pos] == ',' {
+				t.pos++
+				continue
+			}
+			if t.in[t.pos] != end {
+				return errJSONSyntax
+			}
+			t.pos++
+			break
+		}
+	}
+
+	if n <= 15 {
+		t.out[header] = fix | byte(n)
+		copy(t.out[header+1:], t.out[header+5:])
+		t.out = t.out[:len(t.out)-4]
+		return nil
+	}
+	binary.BigEndian.PutUint32(t.out[header+1:], uint32(n))
+	return nil
+}
+
+type msgpackDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if n < 0 || len(d.data)-d.pos < n {
+		return nil, errMsgpackTruncated
+	}
+	b := d.data[d.pos : d.pos+n]
+	d.pos += n
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *msgpackDecoder) value() (interface{}, error) {
+	head, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+	code := head[0]
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return d.str(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return d.array(int(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return d.object(int(code & 0x0f))
+	}
+
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		return d.uint(1 << (code - 0xcc))
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (code - 0xd0)
+		u, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		shift := 64 - 8*size
+		return int64(u<<shift) >> shift, nil
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+	case 0xd9, 0xda, 0xdb, 0xc4, 0xc5, 0xc6:
+		// str8/16/32 e bin8/16/32 (bytes são entregues como string)
+		size := 1 << ((code - 0xd9) % 3)
+		if code >= 0xc4 && code <= 0xc6 {
+			size = 1 << (code - 0xc4)
+		}
+		n, err := d.uint(size)
+		if err != nil {
+			return nil, err
+		}
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (code - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (code - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return d.object(int(n))
+	default:
+		return nil, fmt.Errorf("msgpack: unsupported type 0x%02x", code)
+	}
+}
+
+func (d *msgpackDecoder) str(n int) (string, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) array(n int) ([]interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	items := make([]interface{}, 0, n)
+	for i := 0; i < n; i++ {
+		item, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (d *msgpackDecoder) object(n int) (map[string]interface{}, error) {
+	if n > len(d.data)-d.pos {
+		return nil, errMsgpackTruncated
+	}
+	object := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("msgpack: map keys must be strings")
+		}
+		if object[name], err = d.value(); err != nil {
+			return nil, err
+		}
+	}
+	return object, nil
+}
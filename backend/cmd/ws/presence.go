@@ -0,0 +1,575 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+
+	"github.com/nexus/backend/internal/models"
+)
+
+// Status de presença de uma sessão. O status do usuário é o agregado das suas sessões.
+const (
+	presenceOnline    = "online"
+	presenceIdle      = "idle"
+	presenceDND       = "dnd"
+	presenceInvisible = "invisible"
+	presenceOffline   = "offline"
+)
+
+const (
+	// gatewayPresenceSubject transmite a presença local dos usuários de cada nó (mudanças e anúncios periódicos)
+	gatewayPresenceSubject = "gateway.presence"
+
+	// gatewayPresenceSyncSubject é usado por um nó que inicia para pedir o estado dos demais
+	gatewayPresenceSyncSubject = "gateway.presence.sync"
+
+	// presenceEventsSubject recebe as mudanças de presença feitas pela API (status personalizado)
+	presenceEventsSubject = "events.presence"
+
+	// presenceAnnounceInterval é o intervalo do anúncio completo do estado de cada nó
+	presenceAnnounceInterval = 30 * time.Second
+
+	// presenceNodeTTL é quanto um nó pode ficar sem anunciar antes de ser considerado fora
+	// do ar (seus usuários passam a offline)
+	presenceNodeTTL = 3 * presenceAnnounceInterval
+
+	// idleTimeout é quanto uma sessão fica sem atividade do cliente (heartbeats não contam)
+	// antes de passar automaticamente a ociosa
+	idleTimeout       = 10 * time.Minute
+	idleCheckInterval = time.Minute
+
+	// presenceAudienceTTL é por quanto tempo o gateway guarda quem pode ver a presença de cada
+	// usuário e o seu status personalizado
+	presenceAudienceTTL = time.Minute
+)
+
+// userPresence é o status e as atividades de um usuário (numa sessão, num nó ou agregado)
+type userPresence struct {
+	Status     string            `json:"status"`
+	Activities []models.Activity `json:"activities,omitempty"`
+}
+
+// offlinePresence é a presença de quem não tem sessões
+var offlinePresence = userPresence{Status: presenceOffline}
+
+func (p userPresence) equal(other userPresence) bool {
+	return p.Status == other.Status && reflect.DeepEqual(p.Activities, other.Activities)
+}
+
+// visible é a presença mostrada aos outros usuários: invisível aparece como offline, sem atividades
+func (p userPresence) visible() userPresence {
+	status := visiblePresence(p.Status)
+	if status == presenceOffline {
+		return offlinePresence
+	}
+	return userPresence{Status: status, Activities: p.Activities}
+}
+
+// mergeActivities junta as atividades de várias sessões ou nós, sem repetições
+func mergeActivities(lists ...[]models.Activity) []models.Activity {
+	var merged []models.Activity
+	seen := make(map[models.Activity]bool)
+	for _, list := range lists {
+		for _, activity := range list {
+			key := activity
+			key.Since = 0
+			if !seen[key] {
+				seen[key] = true
+				merged = append(merged, activity)
+			}
+		}
+	}
+	return merged
+}
+
+// presenceRank ordena os status na agregação: vence o de maior rank. "Não perturbe" escolhido
+// em qualquer dispositivo prevalece; uma sessão ativa prevalece sobre as ociosas.
+var presenceRank = map[string]int{
+	presenceInvisible: 1,
+	presenceIdle:      2,
+	presenceOnline:    3,
+	presenceDND:       4,
+}
+
+// validPresence indica se o cliente pode definir o status
+func validPresence(status string) bool {
+	_, ok := presenceRank[status]
+	return ok
+}
+
+// setActivities define as atividades informadas pelo cliente na sessão
+func (c *WebSocketConn) setActivities(activities []models.Activity) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.activities = activities
+}
+
+// sessionActivities retorna as atividades da sessão: o canal de voz e as informadas pelo cliente
+func (c *WebSocketConn) sessionActivities() []models.Activity {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.voiceChannel == "" {
+		return c.activities
+	}
+	voice := models.Activity{Type: models.ActivityVoice, ChannelID: c.voiceChannel}
+	return append([]models.Activity{voice}, c.activities...)
+}
+
+// setStatus define o status de presença da sessão
+func (c *WebSocketConn) setStatus(status string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.status = status
+}
+
+// presenceStatus retorna o status de presença da sessão. Uma sessão online sem atividade
+// por idleTimeout aparece como ociosa.
+func (c *WebSocketConn) presenceStatus() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.status != "" && c.status != presenceOnline {
+		return c.status
+	}
+	if c.autoIdle {
+		return presenceIdle
+	}
+	return presenceOnline
+}
+
+// touch registra atividade do cliente. Retorna true se a sessão deixou de estar ociosa.
+func (c *WebSocketConn) touch(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.lastActivity = now
+	if !c.autoIdle {
+		return false
+	}
+	c.autoIdle = false
+	return true
+}
+
+// checkIdle marca a sessão como ociosa se não houve atividade por idleTimeout.
+// Retorna true se a sessão passou a ociosa.
+func (c *WebSocketConn) checkIdle(now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.autoIdle || now.Sub(c.lastActivity) < idleTimeout {
+		return false
+	}
+	c.autoIdle = true
+	return true
+}
+
+// aggregatePresence calcula a presença do usuário a partir das suas sessões
+func aggregatePresence(sessions []*WebSocketConn) userPresence {
+	aggregate := offlinePresence
+	lists := make([][]models.Activity, 0, len(sessions))
+	for _, session := range sessions {
+		if status := session.presenceStatus(); presenceRank[status] > presenceRank[aggregate.Status] {
+			aggregate.Status = status
+		}
+		lists = append(lists, session.sessionActivities())
+	}
+	aggregate.Activities = mergeActivities(lists...)
+	return aggregate
+}
+
+// visiblePresence é o status mostrado aos outros usuários (invisível aparece como offline)
+func visiblePresence(status string) string {
+	if status == "" || status == presenceInvisible {
+		return presenceOffline
+	}
+	return status
+}
+
+// presenceTracker guarda a presença local de cada usuário em cada nó do gateway. A presença do
+// usuário é o agregado entre os nós, com as mesmas regras da agregação entre sessões.
+type presenceTracker struct {
+	mu    sync.Mutex
+	users map[string]map[string]userPresence // userID -> nodeID -> presença no nó
+	nodes map[string]map[string]bool         // nodeID -> usuários com sessões no nó
+	seen  map[string]time.Time               // nodeID -> último anúncio ou mudança recebida
+}
+
+// presenceChange é a mudança da presença agregada de um usuário
+type presenceChange struct {
+	UserID string
+	Before userPresence
+	After  userPresence
+}
+
+func newPresenceTracker() *presenceTracker {
+	return &presenceTracker{
+		users: make(map[string]map[string]userPresence),
+		nodes: make(map[string]map[string]bool),
+		seen:  make(map[string]time.Time),
+	}
+}
+
+// Presence retorna a presença agregada do usuário
+func (t *presenceTracker) Presence(userID string) userPresence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.aggregate(userID)
+}
+
+// Local retorna a presença do usuário num nó
+func (t *presenceTracker) Local(nodeID, userID string) userPresence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if presence, ok := t.users[userID][nodeID]; ok {
+		return presence
+	}
+	return offlinePresence
+}
+
+// Node retorna a presença dos usuários com sessões no nó
+func (t *presenceTracker) Node(nodeID string) map[string]userPresence {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	users := make(map[string]userPresence, len(t.nodes[nodeID]))
+	for userID := range t.nodes[nodeID] {
+		users[userID] = t.users[userID][nodeID]
+	}
+	return users
+}
+
+// Set atualiza a presença do usuário num nó (offline remove) e retorna a mudança no agregado
+func (t *presenceTracker) Set(nodeID, userID string, presence userPresence, now time.Time) presenceChange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen[nodeID] = now
+	return t.set(nodeID, userID, presence)
+}
+
+// Replace substitui o estado do nó pelo seu anúncio completo: usuários ausentes estão offline no nó
+func (t *presenceTracker) Replace(nodeID string, users map[string]userPresence, now time.Time) []presenceChange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.seen[nodeID] = now
+
+	var changes []presenceChange
+	for userID := range t.nodes[nodeID] {
+		if _, ok := users[userID]; !ok {
+			changes = append(changes, t.set(nodeID, userID, offlinePresence))
+		}
+	}
+	for userID, presence := range users {
+		changes = append(changes, t.set(nodeID, userID, presence))
+	}
+	return changes
+}
+
+// Expire remove os nós (exceto self) sem anúncio há mais de presenceNodeTTL
+func (t *presenceTracker) Expire(self string, now time.Time) []presenceChange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var changes []presenceChange
+	for nodeID, last := range t.seen {
+		if nodeID == self || now.Sub(last) < presenceNodeTTL {
+			continue
+		}
+		for userID := range t.nodes[nodeID] {
+			changes = append(changes, t.set(nodeID, userID, offlinePresence))
+		}
+		delete(t.nodes, nodeID)
+		delete(t.seen, nodeID)
+	}
+	return changes
+}
+
+// aggregate calcula a presença do usuário entre os nós (atividades na ordem dos nós, para que
+// todos os nós cheguem ao mesmo resultado). Chamado com t.mu adquirido.
+func (t *presenceTracker) aggregate(userID string) userPresence {
+	nodeIDs := make([]string, 0, len(t.users[userID]))
+	for nodeID := range t.users[userID] {
+		nodeIDs = append(nodeIDs, nodeID)
+	}
+	sort.Strings(nodeIDs)
+
+	aggregate := offlinePresence
+	lists := make([][]models.Activity, 0, len(nodeIDs))
+	for _, nodeID := range nodeIDs {
+		presence := t.users[userID][nodeID]
+		if presenceRank[presence.Status] > presenceRank[aggregate.Status] {
+			aggregate.Status = presence.Status
+		}
+		lists = append(lists, presence.Activities)
+	}
+	aggregate.Activities = mergeActivities(lists...)
+	return aggregate
+}
+
+// set é Set sem o lock e sem registrar o anúncio do nó
+func (t *presenceTracker) set(nodeID, userID string, presence userPresence) presenceChange {
+	change := presenceChange{UserID: userID, Before: t.aggregate(userID)}
+
+	if _, ok := presenceRank[presence.Status]; !ok {
+		delete(t.users[userID], nodeID)
+		if len(t.users[userID]) == 0 {
+			delete(t.users, userID)
+		}
+		delete(t.nodes[nodeID], userID)
+	} else {
+		if t.users[userID] == nil {
+			t.users[userID] = make(map[string]userPresence)
+		}
+		if t.nodes[nodeID] == nil {
+			t.nodes[nodeID] = make(map[string]bool)
+		}
+		t.users[userID][nodeID] = presence
+		t.nodes[nodeID][userID] = true
+	}
+
+	change.After = t.aggregate(userID)
+	return change
+}
+
+// presenceAnnouncement é a presença local dos usuários de um nó, publicada em gatewayPresenceSubject.
+// Com Full é o estado completo do nó (usuários ausentes estão offline); senão, apenas mudanças.
+type presenceAnnouncement struct {
+	Users map[string]userPresence `json:"users"`
+	Full  bool                    `json:"full,omitempty"`
+}
+
+// startPresence assina os subjects de presença, pede o estado dos outros nós e inicia os
+// anúncios periódicos e a detecção de ociosidade
+func (ws *WebSocketServer) startPresence() error {
+	if _, err := ws.nc.Subscribe(gatewayPresenceSubject, ws.handlePresenceAnnouncement); err != nil {
+		return err
+	}
+	_, err := ws.nc.Subscribe(gatewayPresenceSyncSubject, func(msg *nats.Msg) {
+		if !ws.fanout.isEcho(msg) {
+			ws.announcePresence()
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := ws.nc.Subscribe(presenceEventsSubject, ws.handlePresenceEvent); err != nil {
+		return err
+	}
+
+	ws.fanout.publishMsg(ws.fanout.newMsg(gatewayPresenceSyncSubject, nil))
+	go ws.runPresence()
+	return nil
+}
+
+func (ws *WebSocketServer) runPresence() {
+	announce := time.NewTicker(presenceAnnounceInterval)
+	idle := time.NewTicker(idleCheckInterval)
+	defer announce.Stop()
+	defer idle.Stop()
+
+	for {
+		select {
+		case now := <-announce.C:
+			ws.announcePresence()
+			ws.expirePresence(now)
+		case now := <-idle.C:
+			ws.detectIdle(now)
+		}
+	}
+}
+
+// updatePresence recalcula a presença do usuário neste nó a partir das suas sessões e a publica
+// para os demais nós; se a presença visível agregada mudou, persiste e entrega a mudança
+func (ws *WebSocketServer) updatePresence(userID uuid.UUID) {
+	ws.presenceMu.Lock()
+	defer ws.presenceMu.Unlock()
+
+	id := userID.String()
+	local := aggregatePresence(ws.hub.UserClients(id))
+	if local.equal(ws.presence.Local(ws.nodeID, id)) {
+		return
+	}
+
+	change := ws.presence.Set(ws.nodeID, id, local, time.Now())
+	ws.publishPresence(presenceAnnouncement{Users: map[string]userPresence{id: local}})
+	ws.applyPresence(change, true)
+}
+
+// markActive registra atividade do cliente na sessão, encerrando a ociosidade automática
+func (ws *WebSocketServer) markActive(client *WebSocketConn) {
+	if client.touch(time.Now()) {
+		ws.updatePresence(client.userID)
+	}
+}
+
+// detectIdle passa a ociosas as sessões sem atividade por idleTimeout
+func (ws *WebSocketServer) detectIdle(now time.Time) {
+	users := make(map[uuid.UUID]bool)
+	for _, session := range ws.hub.All() {
+		if session.checkIdle(now) {
+			users[session.userID] = true
+		}
+	}
+	for userID := range users {
+		ws.updatePresence(userID)
+	}
+}
+
+// handlePresenceAnnouncement aplica o status local publicado por outro nó
+func (ws *WebSocketServer) handlePresenceAnnouncement(msg *nats.Msg) {
+	if ws.fanout.isEcho(msg) {
+		return
+	}
+
+	var announcement presenceAnnouncement
+	if err := json.Unmarshal(msg.Data, &announcement); err != nil {
+		ws.logger.Error("invalid presence announcement", zap.Error(err))
+		return
+	}
+
+	nodeID := msg.Header.Get(nodeHeader)
+	now := time.Now()
+
+	ws.presenceMu.Lock()
+	defer ws.presenceMu.Unlock()
+
+	var changes []presenceChange
+	if announcement.Full {
+		changes = ws.presence.Replace(nodeID, announcement.Users, now)
+	} else {
+		for userID, presence := range announcement.Users {
+			changes = append(changes, ws.presence.Set(nodeID, userID, presence, now))
+		}
+	}
+	// O nó de origem persiste as próprias mudanças
+	for _, change := range changes {
+		ws.applyPresence(change, false)
+	}
+}
+
+// announcePresence publica o estado completo deste nó (mantém o nó vivo para os demais)
+func (ws *WebSocketServer) announcePresence() {
+	ws.publishPresence(presenceAnnouncement{Users: ws.presence.Node(ws.nodeID), Full: true})
+}
+
+// expirePresence coloca offline os usuários dos nós que pararam de anunciar
+func (ws *WebSocketServer) expirePresence(now time.Time) {
+	ws.presenceMu.Lock()
+	defer ws.presenceMu.Unlock()
+
+	for _, change := range ws.presence.Expire(ws.nodeID, now) {
+		ws.applyPresence(change, true)
+	}
+}
+
+func (ws *WebSocketServer) publishPresence(announcement presenceAnnouncement) {
+	data, _ := json.Marshal(announcement)
+	ws.fanout.publishMsg(ws.fanout.newMsg(gatewayPresenceSubject, data))
+}
+
+// applyPresence persiste (se persist) e entrega às sessões locais a mudança da presença visível.
+// Chamado com ws.presenceMu adquirido.
+func (ws *WebSocketServer) applyPresence(change presenceChange, persist bool) {
+	presence := change.After.visible()
+	if presence.equal(change.Before.visible()) {
+		return
+	}
+
+	if persist {
+		// last_seen registra o momento da mudança; para quem ficou offline, a última vez visto
+		activities := ""
+		if len(presence.Activities) > 0 {
+			data, _ := json.Marshal(presence.Activities)
+			activities = string(data)
+		}
+		if err := ws.db.UpdateUserPresence(change.UserID, presence.Status, activities); err != nil {
+			ws.logger.Error("failed to persist presence", zap.String("userID", change.UserID), zap.Error(err))
+		}
+	}
+
+	ws.deliverPresence(change.UserID, presence, time.Now())
+}
+
+// deliverPresence entrega a presença do usuário às sessões locais de quem compartilha
+// um servidor ou amizade com ele (e às suas próprias sessões)
+func (ws *WebSocketServer) deliverPresence(userID string, presence userPresence, lastSeen time.Time) {
+	presenceData := PresenceData{Status: presence.Status, LastSeen: lastSeen, Activities: presence.Activities}
+	if presence.Status != presenceOffline {
+		presenceData.CustomStatus = ws.customStatus(userID)
+	}
+	data, _ := json.Marshal(presenceData)
+	frame, _ := json.Marshal(WebSocketMessage{
+		Type:      "presence",
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+
+	for audienceID := range ws.presenceAudience(userID) {
+		for _, client := range ws.hub.UserClients(audienceID) {
+			// Filas cheias são tratadas pela política de backpressure
+			client.dispatch(frame)
+		}
+	}
+}
+
+// presenceAudience retorna quem pode ver a presença do usuário (ver access.Policy.RelatedUsers)
+func (ws *WebSocketServer) presenceAudience(userID string) map[string]bool {
+	if cached, ok := ws.audienceCache.Get(userID); ok {
+		return cached.(map[string]bool)
+	}
+
+	audience, err := ws.policy.RelatedUsers(userID)
+	if err != nil {
+		ws.logger.Error("failed to load presence audience", zap.String("userID", userID), zap.Error(err))
+		return map[string]bool{userID: true}
+	}
+	ws.audienceCache.Set(userID, audience)
+	return audience
+}
+
+// customStatus retorna o status personalizado do usuário (nil se não houver ou se expirou)
+func (ws *WebSocketServer) customStatus(userID string) *models.CustomStatus {
+	stored, ok := ws.customStatusCache.Get(userID)
+	if !ok {
+		user, err := ws.db.GetUserByID(userID)
+		if err != nil {
+			ws.logger.Error("failed to load custom status", zap.String("userID", userID), zap.Error(err))
+			return nil
+		}
+		stored, _ = user["custom_status"].(string)
+		ws.customStatusCache.Set(userID, stored)
+	}
+	return models.ParseCustomStatus(stored.(string), time.Now())
+}
+
+// presenceEvent é uma mudança de presença publicada pela API (ver services.PublishPresenceEvent)
+type presenceEvent struct {
+	Type   string               `json:"type"` // "custom_status"
+	UserID string               `json:"userId"`
+	Data   *models.CustomStatus `json:"data"`
+}
+
+// handlePresenceEvent aplica a mudança do status personalizado e a entrega se o usuário está visível
+func (ws *WebSocketServer) handlePresenceEvent(msg *nats.Msg) {
+	var event presenceEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil || event.Type != "custom_status" {
+		return
+	}
+
+	stored := ""
+	if event.Data != nil {
+		data, _ := json.Marshal(event.Data)
+		stored = string(data)
+	}
+
+	ws.presenceMu.Lock()
+	defer ws.presenceMu.Unlock()
+
+	ws.customStatusCache.Set(event.UserID, stored)
+	if presence := ws.presence.Presence(event.UserID).visible(); presence.Status != presenceOffline {
+		ws.deliverPresence(event.UserID, presence, time.Now())
+	}
+}
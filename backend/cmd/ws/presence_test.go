@@ -0,0 +1,104 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+
+	"github.com/nexus/backend/internal/models"
+)
+
+func withStatus(s string) userPresence { return userPresence{Status: s} }
+
+// TestPresenceTracker verifica a agregação entre nós, o anúncio completo e a expiração de nós
+func TestPresenceTracker(t *testing.T) {
+	tracker := newPresenceTracker()
+	now := time.Now()
+
+	if change := tracker.Set("a", "u1", withStatus(presenceIdle), now); change.Before.Status != presenceOffline || change.After.Status != presenceIdle {
+		t.Fatalf("unexpected change %+v", change)
+	}
+	if change := tracker.Set("b", "u1", withStatus(presenceOnline), now); change.After.Status != presenceOnline {
+		t.Fatalf("active session on another node should win, got %+v", change)
+	}
+	if change := tracker.Set("b", "u1", offlinePresence, now); change.After.Status != presenceIdle {
+		t.Fatalf("user should stay idle on node a, got %+v", change)
+	}
+
+	// Anúncio completo: u2 deixa o nó a, u3 entra
+	tracker.Set("a", "u2", withStatus(presenceDND), now)
+	changes := tracker.Replace("a", map[string]userPresence{"u1": withStatus(presenceIdle), "u3": withStatus(presenceOnline)}, now)
+	after := make(map[string]string)
+	for _, change := range changes {
+		after[change.UserID] = change.After.Status
+	}
+	if after["u2"] != presenceOffline || after["u3"] != presenceOnline {
+		t.Fatalf("unexpected replace changes %+v", changes)
+	}
+
+	// Nós que param de anunciar expiram; o próprio nó não
+	tracker.Set("self", "u4", withStatus(presenceOnline), now)
+	changes = tracker.Expire("self", now.Add(presenceNodeTTL+time.Second))
+	if len(changes) != 2 || tracker.Presence("u1").Status != presenceOffline || tracker.Presence("u4").Status != presenceOnline {
+		t.Fatalf("unexpected expire changes %+v", changes)
+	}
+	if len(tracker.Node("a")) != 0 {
+		t.Fatal("expired node should have no users")
+	}
+}
+
+// TestPresenceActivities verifica a junção das atividades entre sessões e nós e a presença visível
+func TestPresenceActivities(t *testing.T) {
+	voice := models.Activity{Type: models.ActivityVoice, ChannelID: "c1"}
+	game := models.Activity{Type: models.ActivityPlaying, Name: "chess", Since: 1}
+
+	client := newTestConn(uuid.Must(uuid.NewV4()), 0)
+	client.setVoiceChannel("c1")
+	client.setActivities([]models.Activity{game})
+	other := newTestConn(client.userID, 1)
+	game.Since = 2
+	other.setActivities([]models.Activity{game})
+
+	presence := aggregatePresence([]*WebSocketConn{client, other})
+	if presence.Status != presenceOnline || len(presence.Activities) != 2 || presence.Activities[0] != voice {
+		t.Fatalf("unexpected aggregate %+v", presence)
+	}
+
+	tracker := newPresenceTracker()
+	now := time.Now()
+	tracker.Set("b", "u1", userPresence{Status: presenceIdle, Activities: []models.Activity{game}}, now)
+	change := tracker.Set("a", "u1", presence, now)
+	if len(change.After.Activities) != 2 || change.After.Activities[0] != voice {
+		t.Fatalf("unexpected activities across nodes %+v", change.After)
+	}
+
+	hidden := userPresence{Status: presenceInvisible, Activities: []models.Activity{game}}.visible()
+	if !hidden.equal(offlinePresence) {
+		t.Fatalf("invisible user should appear offline without activities, got %+v", hidden)
+	}
+}
+
+// TestIdleDetection verifica a ociosidade automática e que um status escolhido prevalece
+func TestIdleDetection(t *testing.T) {
+	client := newTestConn(uuid.Must(uuid.NewV4()), 0)
+	now := time.Now()
+	client.touch(now)
+
+	if client.checkIdle(now.Add(idleTimeout / 2)) {
+		t.Fatal("session should not be idle before idleTimeout")
+	}
+	if !client.checkIdle(now.Add(idleTimeout)) || client.presenceStatus() != presenceIdle {
+		t.Fatal("inactive session should become idle")
+	}
+
+	client.setStatus(presenceDND)
+	if client.presenceStatus() != presenceDND {
+		t.Fatal("chosen status should prevail over auto idle")
+	}
+
+	client.setStatus(presenceOnline)
+	if !client.touch(now.Add(idleTimeout+time.Second)) || client.presenceStatus() != presenceOnline {
+		t.Fatal("activity should end auto idle")
+	}
+}
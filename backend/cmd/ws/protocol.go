@@ -0,0 +1,370 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"go.uber.org/zap"
+
+	"github.com/nexus/backend/internal/models"
+)
+
+// Versões do protocolo do gateway, negociadas pelo parâmetro "v" da URL de conexão
+const (
+	// protocolV1 é o protocolo original: frames "type", READY logo após conectar, sem hello/identify
+	protocolV1 = 1
+
+	// protocolV2 acrescenta opcodes, hello/identify e heartbeat com ack
+	protocolV2 = 2
+
+	protocolLatest = protocolV2
+)
+
+// Opcodes dos frames (campo "op"; ausente = OpDispatch). Eventos de aplicação, nas duas
+// direções, são frames OpDispatch identificados pelo campo "type".
+const (
+	OpDispatch       = 0  // evento (cliente <-> servidor)
+	OpHeartbeat      = 1  // cliente -> servidor
+	OpIdentify       = 2  // cliente -> servidor: primeiro frame de uma sessão nova
+	OpResume         = 6  // cliente -> servidor: retomar sessão anterior
+	OpInvalidSession = 9  // servidor -> cliente
+	OpHello          = 10 // servidor -> cliente: primeiro frame da conexão
+	OpHeartbeatAck   = 11 // servidor -> cliente
+)
+
+// identifyTimeout é quanto o servidor espera o identify (ou resume) após o hello
+const identifyTimeout = 30 * time.Second
+
+// HelloData é o payload do hello, enviado assim que a conexão é aberta (v2)
+type HelloData struct {
+	Version           int    `json:"version"`
+	Encoding          string `json:"encoding"`          // frames agrupados numa mensagem são separados por "\n" (json) ou concatenados (msgpack)
+	HeartbeatInterval int64  `json:"heartbeatInterval"` // milissegundos
+}
+
+// IdentifyData é o payload do identify. A autenticação continua sendo o token da URL de conexão.
+type IdentifyData struct {
+	Properties ClientProperties `json:"properties"`
+}
+
+// ClientProperties descreve o cliente conectado (apenas para logs e métricas)
+type ClientProperties struct {
+	OS      string `json:"os,omitempty"`
+	Browser string `json:"browser,omitempty"`
+	Device  string `json:"device,omitempty"`
+}
+
+// HeartbeatData é o payload do heartbeat: o último seq recebido pelo cliente
+type HeartbeatData struct {
+	Seq uint64 `json:"seq"`
+}
+
+// ActivitiesData é o payload de "activities": as atividades da sessão (substitui as anteriores;
+// lista vazia remove). A atividade de voz é gerada pelo servidor.
+type ActivitiesData struct {
+	Activities []models.Activity `json:"activities"`
+}
+
+// VoiceJoinData é o payload de voice:join (dados de exibição do participante)
+type VoiceJoinData struct {
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"displayName,omitempty"`
+	Avatar      string `json:"avatar,omitempty"`
+}
+
+// VoiceOfferData é o payload de voice:offer
+type VoiceOfferData struct {
+	TargetUserID    string          `json:"targetUserId" protocol:"required"`
+	TargetSessionID string          `json:"targetSessionId,omitempty"`
+	Offer           json.RawMessage `json:"offer" protocol:"required"`
+}
+
+// VoiceAnswerData é o payload de voice:answer
+type VoiceAnswerData struct {
+	TargetUserID    string          `json:"targetUserId" protocol:"required"`
+	TargetSessionID string          `json:"targetSessionId,omitempty"`
+	Answer          json.RawMessage `json:"answer" protocol:"required"`
+}
+
+// VoiceIceCandidateData é o payload de voice:ice-candidate
+type VoiceIceCandidateData struct {
+	TargetUserID    string          `json:"targetUserId" protocol:"required"`
+	TargetSessionID string          `json:"targetSessionId,omitempty"`
+	Candidate       json.RawMessage `json:"candidate" protocol:"required"`
+}
+
+// VoiceMuteData é o payload de voice:mute-status
+type VoiceMuteData struct {
+	IsMuted *bool `json:"isMuted" protocol:"required"`
+}
+
+// VoiceVideoData é o payload de voice:video-status
+type VoiceVideoData struct {
+	IsVideoEnabled *bool `json:"isVideoEnabled" protocol:"required"`
+}
+
+// Frames de voz enviados pelo servidor (campos no nível do frame, sem "data")
+
+// VoiceExistingUsersFrame lista os participantes do canal de voz para quem acabou de entrar
+type VoiceExistingUsersFrame struct {
+	Type  string        `json:"type"` // "voice:existing-users"
+	Users []voiceMember `json:"users"`
+}
+
+// VoiceUserJoinedFrame avisa o canal que alguém entrou na voz
+type VoiceUserJoinedFrame struct {
+	Type      string `json:"type"` // "voice:user-joined"
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	SessionID string `json:"sessionId"`
+	ChannelID string `json:"channelId"`
+}
+
+// VoiceUserLeftFrame avisa o canal que alguém saiu da voz
+type VoiceUserLeftFrame struct {
+	Type      string `json:"type"` // "voice:user-left"
+	UserID    string `json:"userId"`
+	ChannelID string `json:"channelId"`
+}
+
+// VoiceSignalFrame encaminha offer, answer ou ICE candidate ao par
+type VoiceSignalFrame struct {
+	Type      string          `json:"type"` // "voice:offer", "voice:answer", "voice:ice-candidate"
+	UserID    string          `json:"userId"`
+	SessionID string          `json:"sessionId"`
+	Offer     json.RawMessage `json:"offer,omitempty"`
+	Answer    json.RawMessage `json:"answer,omitempty"`
+	Candidate json.RawMessage `json:"candidate,omitempty"`
+}
+
+// VoiceMuteFrame transmite o status de mute ao canal
+type VoiceMuteFrame struct {
+	Type      string `json:"type"` // "voice:mute-status"
+	UserID    string `json:"userId"`
+	IsMuted   bool   `json:"isMuted"`
+	ChannelID string `json:"channelId"`
+}
+
+// VoiceVideoFrame transmite o status de vídeo ao canal
+type VoiceVideoFrame struct {
+	Type           string `json:"type"` // "voice:video-status"
+	UserID         string `json:"userId"`
+	IsVideoEnabled bool   `json:"isVideoEnabled"`
+	ChannelID      string `json:"channelId"`
+}
+
+// payload é um payload de evento do cliente. Campos marcados com `protocol:"required"` são
+// verificados na decodificação; validate aplica as demais regras.
+type payload interface {
+	validate() error
+}
+
+// clientEvents são os eventos aceitos do cliente (op 0) e o payload de cada um (nil = sem payload)
+var clientEvents = map[string]payload{
+	"message":             &MessageData{},
+	"typing":              &TypingData{},
+	"presence":            &PresenceData{},
+	"activities":          &ActivitiesData{},
+	"subscribe":           nil,
+	"unsubscribe":         nil,
+	"ping":                nil,
+	"resume":              &ResumeData{},
+	"voice:join":          &VoiceJoinData{},
+	"voice:leave":         nil,
+	"voice:offer":         &VoiceOfferData{},
+	"voice:answer":        &VoiceAnswerData{},
+	"voice:ice-candidate": &VoiceIceCandidateData{},
+	"voice:mute-status":   &VoiceMuteData{},
+	"voice:video-status":  &VoiceVideoData{},
+}
+
+// serverEvents são os eventos enviados pelo servidor e o campo "data" de cada um
+var serverEvents = map[string]interface{}{
+	"hello":           HelloData{},
+	"heartbeat_ack":   HeartbeatAckData{},
+	"ready":           ReadyData{},
+	"resumed":         ResumedData{},
+	"invalid_session": InvalidSessionData{},
+	"resync":          ResyncData{},
+	"error":           ErrorData{},
+	"presence":        PresenceData{},
+	"message":         MessageData{},
+	"typing":          TypingData{},
+}
+
+// serverFrames são os frames do servidor com os campos no nível do frame
+var serverFrames = map[string]interface{}{
+	"voice:existing-users": VoiceExistingUsersFrame{},
+	"voice:user-joined":    VoiceUserJoinedFrame{},
+	"voice:user-left":      VoiceUserLeftFrame{},
+	"voice:offer":          VoiceSignalFrame{},
+	"voice:answer":         VoiceSignalFrame{},
+	"voice:ice-candidate":  VoiceSignalFrame{},
+	"voice:mute-status":    VoiceMuteFrame{},
+	"voice:video-status":   VoiceVideoFrame{},
+}
+
+func (d *MessageData) validate() error {
+	if strings.TrimSpace(d.Content) == "" {
+		return errors.New("content is required")
+	}
+	return nil
+}
+
+func (d *TypingData) validate() error { return nil }
+
+func (d *PresenceData) validate() error {
+	if !validPresence(d.Status) {
+		return errors.New("invalid presence status")
+	}
+	return nil
+}
+
+func (d *ActivitiesData) validate() error {
+	if len(d.Activities) > models.MaxActivities {
+		return errors.New("too many activities (max 5)")
+	}
+	for i := range d.Activities {
+		if err := d.Activities[i].Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *ResumeData) validate() error      { return nil }
+func (d *IdentifyData) validate() error    { return nil }
+func (d *HeartbeatData) validate() error   { return nil }
+func (d *VoiceJoinData) validate() error   { return nil }
+func (d *VoiceMuteData) validate() error   { return nil }
+func (d *VoiceVideoData) validate() error  { return nil }
+func (d *VoiceOfferData) validate() error  { return validTarget(d.TargetUserID) }
+func (d *VoiceAnswerData) validate() error { return validTarget(d.TargetUserID) }
+func (d *VoiceIceCandidateData) validate() error {
+	return validTarget(d.TargetUserID)
+}
+
+func validTarget(userID string) error {
+	if _, err := uuid.FromString(userID); err != nil {
+		return errors.New("targetUserId must be a valid user id")
+	}
+	return nil
+}
+
+// negotiateVersion lê a versão pedida pelo cliente (parâmetro "v"; padrão v1)
+func negotiateVersion(r *http.Request) (int, error) {
+	requested := r.URL.Query().Get("v")
+	if requested == "" {
+		return protocolV1, nil
+	}
+
+	version, err := strconv.Atoi(requested)
+	if err != nil || version < protocolV1 || version > protocolLatest {
+		return 0, fmt.Errorf("unsupported protocol version %q (supported: %d-%d)", requested, protocolV1, protocolLatest)
+	}
+	return version, nil
+}
+
+// decodePayload decodifica e valida o payload do frame. Se for inválido, envia um frame de
+// erro (invalid_payload) ao cliente e retorna false.
+func (ws *WebSocketServer) decodePayload(client *WebSocketConn, msg *WebSocketMessage, v payload) bool {
+	raw := []byte(msg.Data)
+
+	// Clientes v1 enviam o payload serializado como string JSON
+	if client.version == protocolV1 && len(raw) > 0 && raw[0] == '"' {
+		var encoded string
+		if err := json.Unmarshal(raw, &encoded); err == nil {
+			raw = []byte(encoded)
+		}
+	}
+	if len(raw) == 0 || string(raw) == "null" {
+		raw = []byte("{}")
+	}
+
+	err := json.Unmarshal(raw, v)
+	if err != nil {
+		err = fmt.Errorf("malformed %s payload", msg.Type)
+	} else if err = checkRequired(v); err == nil {
+		err = v.validate()
+	}
+	if err != nil {
+		ws.logger.Info("invalid gateway payload",
+			zap.String("userID", client.userID.String()),
+			zap.String("type", msg.Type),
+			zap.Error(err))
+		ws.sendError(client, msg, ErrorData{Code: "invalid_payload", Message: err.Error()})
+		return false
+	}
+	return true
+}
+
+// checkRequired verifica os campos marcados com `protocol:"required"`
+func checkRequired(v interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("protocol") != "required" || !value.Field(i).IsZero() {
+			continue
+		}
+		return fmt.Errorf("%s is required", jsonName(field))
+	}
+	return nil
+}
+
+// sendControl envia um frame de controle (hello, heartbeat ack): sem seq e fora do buffer de replay
+func (c *WebSocketConn) sendControl(frame []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.send == nil {
+		return false
+	}
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendHello anuncia a versão e a codificação negociadas e o intervalo de heartbeat
+func (ws *WebSocketServer) sendHello(client *WebSocketConn, encoding string) {
+	data, _ := json.Marshal(HelloData{
+		Version:           client.version,
+		Encoding:          encoding,
+		HeartbeatInterval: heartbeatInterval.Milliseconds(),
+	})
+	frame, _ := json.Marshal(WebSocketMessage{
+		Op:        OpHello,
+		Type:      "hello",
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	client.sendControl(frame)
+}
+
+// identify inicia a sessão v2: inscreve nos canais, envia o READY e registra a sessão
+func (ws *WebSocketServer) identify(client *WebSocketConn, msg *WebSocketMessage) bool {
+	var data IdentifyData
+	if !ws.decodePayload(client, msg, &data) {
+		return false
+	}
+
+	ws.logger.Info("client identified",
+		zap.String("userID", client.userID.String()),
+		zap.String("sessionID", client.sessionID),
+		zap.String("os", data.Properties.OS),
+		zap.String("browser", data.Properties.Browser),
+		zap.String("device", data.Properties.Device))
+
+	ws.sendReady(client)
+	ws.registerClient(client)
+	return true
+}
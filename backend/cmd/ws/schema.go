@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// protocolSchema gera o JSON Schema do protocolo do gateway a partir dos tipos Go:
+// o envelope dos frames, os opcodes e o payload de cada evento
+func protocolSchema() map[string]interface{} {
+	g := &schemaGenerator{defs: make(map[string]interface{})}
+
+	clientPayloads := make(map[string]interface{})
+	for eventType, payload := range clientEvents {
+		clientPayloads[eventType] = g.payloadRef(payload)
+	}
+	clientOps := map[string]interface{}{
+		"identify":  g.ref(reflect.TypeOf(IdentifyData{})),
+		"heartbeat": g.ref(reflect.TypeOf(HeartbeatData{})),
+		"resume":    g.ref(reflect.TypeOf(ResumeData{})),
+	}
+
+	serverPayloads := make(map[string]interface{})
+	for eventType, payload := range serverEvents {
+		serverPayloads[eventType] = g.payloadRef(payload)
+	}
+	frames := make(map[string]interface{})
+	for eventType, frame := range serverFrames {
+		frames[eventType] = g.ref(reflect.TypeOf(frame))
+	}
+
+	return map[string]interface{}{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         "https://nexus.app/schemas/gateway-protocol.json",
+		"title":       "Nexus gateway protocol",
+		"version":     protocolLatest,
+		"description": "Frames WebSocket do gateway. Eventos (op 0) são identificados por \"type\" e levam o payload em \"data\".",
+		"opcodes": map[string]int{
+			"dispatch":        OpDispatch,
+			"heartbeat":       OpHeartbeat,
+			"identify":        OpIdentify,
+			"resume":          OpResume,
+			"invalid_session": OpInvalidSession,
+			"hello":           OpHello,
+			"heartbeat_ack":   OpHeartbeatAck,
+		},
+		"frame":        g.ref(reflect.TypeOf(WebSocketMessage{})),
+		"clientOps":    clientOps,
+		"clientEvents": clientPayloads,
+		"serverEvents": serverPayloads,
+		"serverFrames": frames,
+		"$defs":        g.defs,
+	}
+}
+
+// HandleSchema publica o JSON Schema do protocolo
+func (ws *WebSocketServer) HandleSchema(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/schema+json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(protocolSchema())
+}
+
+// schemaGenerator converte tipos Go em JSON Schema; structs viram definições em $defs
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// payloadRef referencia o payload de um evento; eventos sem payload aceitam "data" ausente
+func (g *schemaGenerator) payloadRef(payload interface{}) interface{} {
+	if payload == nil {
+		return map[string]interface{}{"type": "null"}
+	}
+	return g.ref(reflect.TypeOf(payload))
+}
+
+func (g *schemaGenerator) ref(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType {
+		return g.schema(t)
+	}
+
+	name := t.Name()
+	if _, ok := g.defs[name]; !ok {
+		g.defs[name] = nil // evita recursão
+		g.defs[name] = g.object(t)
+	}
+	return map[string]interface{}{"$ref": "#/$defs/" + name}
+}
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Struct:
+		return g.ref(t)
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := jsonName(field)
+		if field.PkgPath != "" || name == "-" {
+			continue
+		}
+		properties[name] = g.schema(field.Type)
+		if field.Tag.Get("protocol") == "required" {
+			required = append(required, name)
+		}
+	}
+	sort.Strings(required)
+
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+// jsonName retorna o nome do campo no JSON
+func jsonName(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	// resumeWindow é por quanto tempo uma sessão desconectada continua recebendo eventos e pode ser retomada
+	resumeWindow = 2 * time.Minute
+
+	// replayBufferSize é quantos frames despachados cada sessão guarda para replay.
+	// Menor que o buffer de envio, para que o replay caiba na fila da nova conexão.
+	replayBufferSize = 200
+
+	// sendBufferSize é o tamanho da fila de envio de cada conexão
+	sendBufferSize = 256
+)
+
+// ResumeData é o payload do op "resume": a sessão anterior e o último seq recebido pelo cliente
+type ResumeData struct {
+	SessionID string `json:"sessionId" protocol:"required"`
+	Seq       uint64 `json:"seq"`
+}
+
+// ResumedData confirma a retomada da sessão
+type ResumedData struct {
+	SessionID string `json:"sessionId"`
+	Replayed  int    `json:"replayed"`
+}
+
+// InvalidSessionData indica que a sessão não pode ser retomada: o cliente deve seguir com a
+// sessão nova (já recebeu o READY dela) e recarregar o estado pela API
+type InvalidSessionData struct {
+	Reason string `json:"reason"`
+}
+
+// sessionFrame é um frame despachado, já com o seq da sessão
+type sessionFrame struct {
+	seq  uint64
+	data []byte
+}
+
+// dispatch numera o frame, guarda-o no buffer de replay e o enfileira na conexão atual,
+// aplicando a política de backpressure (ver backpressure.go). Sessões desconectadas apenas
+// acumulam no buffer. Retorna false se o frame não foi enfileirado.
+func (c *WebSocketConn) dispatch(frame []byte) bool {
+	eventType := frameType(frame)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.send != nil && lowPriority(eventType) && len(c.send) >= backpressure.lowPriorityWatermark {
+		c.deferLowPriority(eventType, frame)
+		return false
+	}
+	c.relieve()
+	return c.enqueue(frame, eventType)
+}
+
+// enqueue numera o frame, guarda-o no buffer de replay e o enfileira sem bloquear.
+// Chamado com c.mu adquirido.
+func (c *WebSocketConn) enqueue(frame []byte, eventType string) bool {
+	c.seq++
+	stamped := withSeq(frame, c.seq)
+
+	c.replay = append(c.replay, sessionFrame{seq: c.seq, data: stamped})
+	if len(c.replay) > replayBufferSize {
+		c.replay = append(c.replay[:0:0], c.replay[len(c.replay)-replayBufferSize:]...)
+	}
+
+	if c.send == nil {
+		return true
+	}
+	select {
+	case c.send <- stamped:
+		return true
+	default:
+		c.overloaded(eventType)
+		return false
+	}
+}
+
+// detach desassocia a conexão encerrada, se ainda for a atual, e agenda o fim da sessão
+func (ws *WebSocketServer) detach(client *WebSocketConn, conn *websocket.Conn) {
+	client.mu.Lock()
+	defer client.mu.Unlock()
+
+	if client.conn != conn || client.closed {
+		return
+	}
+	close(client.send)
+	client.send = nil
+	client.conn = nil
+	client.expiry = time.AfterFunc(resumeWindow, func() { ws.closeSession(client) })
+
+	ws.logger.Info("client connection lost, session kept for resume",
+		zap.String("userID", client.userID.String()),
+		zap.String("sessionID", client.sessionID))
+}
+
+// resumeSession processa o op "resume" recebido pela conexão de fresh (sessão recém-criada).
+// Em caso de sucesso a conexão passa para a sessão anterior, que recebe os frames perdidos,
+// e fresh é descartada; retorna a sessão que deve ser usada pela conexão a partir de agora.
+func (ws *WebSocketServer) resumeSession(fresh *WebSocketConn, msg *WebSocketMessage) *WebSocketConn {
+	var data ResumeData
+	if !ws.decodePayload(fresh, msg, &data) {
+		return fresh
+	}
+
+	// A sessão só pode ser retomada com a mesma versão do protocolo
+	session := ws.hub.Session(data.SessionID)
+	if session == nil || session == fresh || session.userID != fresh.userID || session.version != fresh.version {
+		ws.invalidSession(fresh, "session not found or expired")
+		return fresh
+	}
+
+	fresh.mu.Lock()
+	conn, send := fresh.conn, fresh.send
+	fresh.mu.Unlock()
+
+	session.mu.Lock()
+	if session.closed {
+		session.mu.Unlock()
+		ws.invalidSession(fresh, "session not found or expired")
+		return fresh
+	}
+
+	// Frames a partir de data.Seq+1 precisam estar no buffer
+	oldest := session.seq + 1
+	if len(session.replay) > 0 {
+		oldest = session.replay[0].seq
+	}
+	if data.Seq > session.seq || data.Seq+1 < oldest {
+		session.mu.Unlock()
+		ws.invalidSession(fresh, "missed events are no longer available")
+		return fresh
+	}
+
+	// A conexão anterior pode ainda não ter caído do lado do servidor
+	if session.conn != nil {
+		session.conn.Close()
+		close(session.send)
+	}
+	session.conn = conn
+	session.send = send
+	if session.expiry != nil {
+		session.expiry.Stop()
+		session.expiry = nil
+	}
+	// O replay substitui o resync pendente
+	session.overloadedSince = time.Time{}
+
+	replayed := 0
+replay:
+	for _, frame := range session.replay {
+		if frame.seq <= data.Seq {
+			continue
+		}
+		select {
+		case send <- frame.data:
+			replayed++
+		default:
+			// Fila cheia: a conexão é encerrada e o cliente pode retomar de novo a partir do último seq
+			conn.Close()
+			break replay
+		}
+	}
+	session.mu.Unlock()
+
+	// A sessão nova deixa de usar a conexão e é encerrada sem afetar a presença
+	fresh.mu.Lock()
+	fresh.conn = nil
+	fresh.send = nil
+	fresh.mu.Unlock()
+	ws.closeSession(fresh)
+
+	resumed, _ := json.Marshal(ResumedData{SessionID: session.sessionID, Replayed: replayed})
+	frame, _ := json.Marshal(WebSocketMessage{
+		Type:      "resumed",
+		UserID:    session.userID.String(),
+		Data:      resumed,
+		Timestamp: time.Now(),
+	})
+	session.dispatch(frame)
+
+	ws.logger.Info("session resumed",
+		zap.String("userID", session.userID.String()),
+		zap.String("sessionID", session.sessionID),
+		zap.Uint64("seq", data.Seq),
+		zap.Int("replayed", replayed))
+	return session
+}
+
+// invalidSession avisa o cliente que deve recarregar o estado (resync completo)
+func (ws *WebSocketServer) invalidSession(client *WebSocketConn, reason string) {
+	data, _ := json.Marshal(InvalidSessionData{Reason: reason})
+	frame, _ := json.Marshal(WebSocketMessage{
+		Op:        OpInvalidSession,
+		Type:      "invalid_session",
+		UserID:    client.userID.String(),
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	client.dispatch(frame)
+}
+
+// withSeq insere o campo "seq" no início de um frame JSON (objeto), sem decodificá-lo
+func withSeq(frame []byte, seq uint64) []byte {
+	if len(frame) < 2 || frame[0] != '{' {
+		return frame
+	}
+
+	prefix := `{"seq":` + strconv.FormatUint(seq, 10)
+	stamped := make([]byte, 0, len(frame)+len(prefix)+1)
+	stamped = append(stamped, prefix...)
+	if frame[1] != '}' {
+		stamped = append(stamped, ',')
+	}
+	return append(stamped, frame[1:]...)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// typingTimeout é quanto um indicador de digitação dura sem um novo "typing" do usuário
+	typingTimeout = 10 * time.Second
+
+	// typingThrottle é o intervalo mínimo entre retransmissões de "digitando" por usuário e canal
+	typingThrottle = 5 * time.Second
+)
+
+// typingState é a digitação de um usuário em um canal
+type typingState struct {
+	username string
+	lastSent time.Time // última retransmissão de "digitando"
+	deadline time.Time // parada automática
+	timer    *time.Timer
+}
+
+// typingTracker guarda quem está digitando em cada canal deste nó. A digitação é por usuário
+// (não por sessão) e para sozinha após timeout sem um novo "typing".
+type typingTracker struct {
+	mu       sync.Mutex
+	active   map[string]*typingState // "userID:channelID" -> estado
+	timeout  time.Duration
+	throttle time.Duration
+}
+
+func newTypingTracker(timeout, throttle time.Duration) *typingTracker {
+	return &typingTracker{
+		active:   make(map[string]*typingState),
+		timeout:  timeout,
+		throttle: throttle,
+	}
+}
+
+// Start registra que o usuário está digitando e adia a parada automática. Retorna false se a
+// retransmissão deve ser descartada (dentro de throttle desde a anterior). onExpire é chamada
+// quando a digitação para por timeout.
+func (t *typingTracker) Start(key, username string, now time.Time, onExpire func(username string)) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.active[key]; ok {
+		state.deadline = now.Add(t.timeout)
+		if now.Sub(state.lastSent) < t.throttle {
+			return false
+		}
+		state.lastSent = now
+		return true
+	}
+
+	state := &typingState{username: username, lastSent: now, deadline: now.Add(t.timeout)}
+	state.timer = time.AfterFunc(t.timeout, func() {
+		if t.expire(key, state) {
+			onExpire(state.username)
+		}
+	})
+	t.active[key] = state
+	return true
+}
+
+// expire remove a digitação se o prazo passou; caso contrário reagenda o timer
+func (t *typingTracker) expire(key string, state *typingState) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.active[key] != state {
+		// Parada explícita antes do timer
+		return false
+	}
+	if remaining := time.Until(state.deadline); remaining > 0 {
+		state.timer.Reset(remaining)
+		return false
+	}
+	delete(t.active, key)
+	return true
+}
+
+// Stop encerra a digitação. Retorna o nome do usuário e false se ele não estava digitando.
+func (t *typingTracker) Stop(key string) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	state, ok := t.active[key]
+	if !ok {
+		return "", false
+	}
+	state.timer.Stop()
+	delete(t.active, key)
+	return state.username, true
+}
+
+func typingKey(userID, channelID string) string {
+	return userID + ":" + channelID
+}
+
+// handleTypingMessage processa indicadores de digitação. O servidor decide o que é
+// retransmitido: identidade da sessão, throttle por usuário e canal e parada automática.
+func (ws *WebSocketServer) handleTypingMessage(client *WebSocketConn, msg *WebSocketMessage) {
+	var typingData TypingData
+	if !ws.decodePayload(client, msg, &typingData) {
+		return
+	}
+
+	userID := client.userID.String()
+	channelID := msg.ChannelID
+	if !typingData.IsTyping {
+		ws.stopTyping(userID, channelID)
+		return
+	}
+
+	started := ws.typing.Start(typingKey(userID, channelID), client.username, time.Now(), func(username string) {
+		ws.publishTyping(userID, username, channelID, false)
+	})
+	if started {
+		ws.publishTyping(userID, client.username, channelID, true)
+	}
+}
+
+// stopTyping encerra a digitação do usuário no canal (pedido do cliente ou mensagem enviada)
+func (ws *WebSocketServer) stopTyping(userID, channelID string) {
+	if username, ok := ws.typing.Stop(typingKey(userID, channelID)); ok {
+		ws.publishTyping(userID, username, channelID, false)
+	}
+}
+
+// stopTypingOnMessage encerra a digitação do autor de uma mensagem criada pela API (envios
+// via REST não passam pelo gateway). Cada nó recebe o evento; só o que acompanha a digitação
+// do autor publica a parada.
+func (ws *WebSocketServer) stopTypingOnMessage(channelID string, frame []byte) {
+	var event struct {
+		Data struct {
+			UserID string `json:"userId"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(frame, &event); err != nil || event.Data.UserID == "" {
+		return
+	}
+	ws.stopTyping(event.Data.UserID, channelID)
+}
+
+// publishTyping transmite o indicador aos outros usuários do canal, em todos os nós
+func (ws *WebSocketServer) publishTyping(userID, username, channelID string, isTyping bool) {
+	data, _ := json.Marshal(TypingData{IsTyping: isTyping, Username: username})
+	frame, _ := json.Marshal(WebSocketMessage{
+		Type:      "typing",
+		ChannelID: channelID,
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now(),
+	})
+	ws.fanout.PublishChannel(channelID, frame, userID)
+}
+
+// broadcastTyping entrega o indicador às sessões inscritas no canal cujo usuário ainda tem
+// acesso a ele. Inscrições não são revogadas quando o acesso é removido, então a política
+// (com cache de channelCacheTTL) é consultada para cada destinatário.
+func (ws *WebSocketServer) broadcastTyping(channelID string, frame []byte, excludeUserID string) {
+	allowed := make(map[string]bool)
+	for _, client := range ws.hub.ChannelClients(channelID) {
+		userID := client.userID.String()
+		if userID == excludeUserID {
+			continue
+		}
+		canSee, ok := allowed[userID]
+		if !ok {
+			canSee = ws.canAccessChannel(channelID, userID)
+			allowed[userID] = canSee
+		}
+		if canSee {
+			// Filas cheias são tratadas pela política de backpressure
+			client.dispatch(frame)
+		}
+	}
+}
+
+// canAccessChannel verifica (com cache) se o usuário ainda pode acessar o canal
+func (ws *WebSocketServer) canAccessChannel(channelID, userID string) bool {
+	channelRow, ok := ws.channelRow(channelID)
+	if !ok {
+		return false
+	}
+
+	allowed, err := ws.policy.CanAccess(channelRow, userID)
+	if err != nil {
+		ws.logger.Warn("failed to check channel access", zap.String("channelID", channelID), zap.Error(err))
+		return false
+	}
+	return allowed
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTypingTracker verifica o throttle, a parada explícita e a parada automática por timeout
+func TestTypingTracker(t *testing.T) {
+	tracker := newTypingTracker(50*time.Millisecond, time.Second)
+	expired := make(chan string, 1)
+	onExpire := func(username string) { expired <- username }
+	now := time.Now()
+
+	if !tracker.Start("u1:c1", "alice", now, onExpire) {
+		t.Fatal("first typing should be broadcast")
+	}
+	if tracker.Start("u1:c1", "alice", now.Add(time.Millisecond), onExpire) {
+		t.Fatal("typing within throttle should be dropped")
+	}
+	if !tracker.Start("u1:c2", "alice", now, onExpire) {
+		t.Fatal("throttle should be per channel")
+	}
+
+	if username, ok := tracker.Stop("u1:c2"); !ok || username != "alice" {
+		t.Fatal("stop should end typing")
+	}
+	if _, ok := tracker.Stop("u1:c2"); ok {
+		t.Fatal("second stop should be a no-op")
+	}
+
+	select {
+	case username := <-expired:
+		if username != "alice" {
+			t.Fatalf("unexpected username %q", username)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("typing should stop after timeout")
+	}
+	if _, ok := tracker.Stop("u1:c1"); ok {
+		t.Fatal("expired typing should be removed")
+	}
+	if !tracker.Start("u1:c1", "alice", time.Now(), onExpire) {
+		t.Fatal("typing after expiry should be broadcast")
+	}
+}
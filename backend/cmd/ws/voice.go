@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"go.uber.org/zap"
+)
+
+// gatewayVoiceSubject propaga entradas e saídas de canais de voz entre os nós do gateway
+const gatewayVoiceSubject = "gateway.voice"
+
+// voiceMember é um participante de um canal de voz
+type voiceMember struct {
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	SessionID string `json:"sessionId"` // sessão que está na voz; alvo da sinalização WebRTC
+}
+
+// voiceRosterEvent é publicado quando um usuário entra ou sai de um canal de voz
+type voiceRosterEvent struct {
+	ChannelID string `json:"channelId"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username,omitempty"`
+	SessionID string `json:"sessionId"`
+	Joined    bool   `json:"joined"`
+	Node      string `json:"node"`
+}
+
+// voiceRoster guarda os participantes de cada canal de voz, de todos os nós.
+// Participar de um canal de voz é diferente de estar inscrito no canal de texto.
+type voiceRoster struct {
+	mu       sync.Mutex
+	channels map[string]map[string]voiceMember // channelID -> userID -> participante
+}
+
+func newVoiceRoster() *voiceRoster {
+	return &voiceRoster{channels: make(map[string]map[string]voiceMember)}
+}
+
+// Apply atualiza a lista com uma entrada ou saída. O usuário participa por uma sessão de
+// cada vez: entrar por outra sessão a substitui, e a saída de uma sessão substituída é ignorada.
+func (vr *voiceRoster) Apply(event voiceRosterEvent) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	members := vr.channels[event.ChannelID]
+	if event.Joined {
+		if members == nil {
+			members = make(map[string]voiceMember)
+			vr.channels[event.ChannelID] = members
+		}
+		members[event.UserID] = voiceMember{UserID: event.UserID, Username: event.Username, SessionID: event.SessionID}
+		return
+	}
+
+	if member, ok := members[event.UserID]; !ok || member.SessionID != event.SessionID {
+		return
+	}
+	delete(members, event.UserID)
+	if len(members) == 0 {
+		delete(vr.channels, event.ChannelID)
+	}
+}
+
+// Members retorna os participantes do canal de voz, exceto excludeUserID
+func (vr *voiceRoster) Members(channelID, excludeUserID string) []voiceMember {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	members := make([]voiceMember, 0, len(vr.channels[channelID]))
+	for userID, member := range vr.channels[channelID] {
+		if userID != excludeUserID {
+			members = append(members, member)
+		}
+	}
+	return members
+}
+
+// Member retorna o participante do canal de voz, se o usuário estiver nele
+func (vr *voiceRoster) Member(channelID, userID string) (voiceMember, bool) {
+	vr.mu.Lock()
+	defer vr.mu.Unlock()
+
+	member, ok := vr.channels[channelID][userID]
+	return member, ok
+}
+
+// updateVoiceRoster aplica a mudança localmente e a propaga para os outros nós
+func (ws *WebSocketServer) updateVoiceRoster(event voiceRosterEvent) {
+	event.Node = ws.nodeID
+	ws.voice.Apply(event)
+
+	payload, _ := json.Marshal(event)
+	if err := ws.nc.Publish(gatewayVoiceSubject, payload); err != nil {
+		ws.logger.Error("failed to publish voice roster update", zap.Error(err))
+	}
+}
+
+// handleVoiceRosterEvent aplica as mudanças publicadas pelos outros nós
+func (ws *WebSocketServer) handleVoiceRosterEvent(msg *nats.Msg) {
+	var event voiceRosterEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		ws.logger.Warn("invalid voice roster event", zap.Error(err))
+		return
+	}
+	if event.Node == ws.nodeID {
+		return
+	}
+	ws.voice.Apply(event)
+}
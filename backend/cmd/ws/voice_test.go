@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+// TestVoiceRoster verifica a lista de participantes usada para autorizar a sinalização WebRTC
+func TestVoiceRoster(t *testing.T) {
+	vr := newVoiceRoster()
+	vr.Apply(voiceRosterEvent{ChannelID: "voice", UserID: "ana", SessionID: "s1", Joined: true})
+	vr.Apply(voiceRosterEvent{ChannelID: "voice", UserID: "bia", SessionID: "s2", Joined: true})
+
+	if member, ok := vr.Member("voice", "ana"); !ok || member.SessionID != "s1" {
+		t.Fatalf("Member(ana) = %+v, %v", member, ok)
+	}
+	if _, ok := vr.Member("other", "ana"); ok {
+		t.Fatal("user found in a voice channel they did not join")
+	}
+
+	// Entrar por outra sessão substitui a anterior; a saída da sessão antiga é ignorada
+	vr.Apply(voiceRosterEvent{ChannelID: "voice", UserID: "ana", SessionID: "s3", Joined: true})
+	vr.Apply(voiceRosterEvent{ChannelID: "voice", UserID: "ana", SessionID: "s1", Joined: false})
+	if member, ok := vr.Member("voice", "ana"); !ok || member.SessionID != "s3" {
+		t.Fatalf("Member(ana) after session switch = %+v, %v", member, ok)
+	}
+
+	vr.Apply(voiceRosterEvent{ChannelID: "voice", UserID: "ana", SessionID: "s3", Joined: false})
+	if _, ok := vr.Member("voice", "ana"); ok {
+		t.Fatal("user still in voice after leaving")
+	}
+	if members := vr.Members("voice", ""); len(members) != 1 || members[0].UserID != "bia" {
+		t.Fatalf("Members() = %+v", members)
+	}
+}
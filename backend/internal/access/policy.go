@@ -0,0 +1,203 @@
+package access
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/database"
+)
+
+var (
+	// ErrChannelNotFound indica que o canal não existe
+	ErrChannelNotFound = errors.New("channel not found")
+
+	// ErrServerNotFound indica que o servidor não existe
+	ErrServerNotFound = errors.New("server not found")
+
+	// ErrForbidden indica que o usuário não tem acesso ao canal ou servidor
+	ErrForbidden = errors.New("forbidden: you don't have access to this resource")
+)
+
+// Policy decide quem pode acessar um canal:
+//   - canais de servidor (e de grupos): membros do servidor (group_members)
+//   - DMs, grupos privados e canais avulsos: participantes (channel_members) ou o dono do canal
+//
+// É usada pela API em todos os endpoints que recebem um canal e pelo gateway WebSocket.
+type Policy struct {
+	db    *database.CassandraDB
+	cache *cache.MemoryCache // "channelID:userID" -> bool; nil desativa o cache
+}
+
+// NewPolicy cria a política de acesso. cacheTTL > 0 guarda as decisões em memória
+// (usado pelo gateway); a API consulta o banco a cada requisição.
+func NewPolicy(db *database.CassandraDB, cacheTTL time.Duration) *Policy {
+	p := &Policy{db: db}
+	if cacheTTL > 0 {
+		p.cache = cache.NewMemoryCache(cacheTTL)
+	}
+	return p
+}
+
+// Channel retorna o canal se o usuário puder acessá-lo.
+// Retorna ErrChannelNotFound ou ErrForbidden caso contrário.
+func (p *Policy) Channel(channelID, userID string) (map[string]interface{}, error) {
+	if _, err := gocql.ParseUUID(channelID); err != nil {
+		return nil, ErrChannelNotFound
+	}
+
+	channelRow, err := p.db.GetChannelByID(channelID)
+	if err == gocql.ErrNotFound {
+		return nil, ErrChannelNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, err := p.CanAccess(channelRow, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, ErrForbidden
+	}
+	return channelRow, nil
+}
+
+// CanAccess verifica se o usuário pode acessar um canal já carregado
+func (p *Policy) CanAccess(channelRow map[string]interface{}, userID string) (bool, error) {
+	channelID := channelRow["channel_id"].(string)
+	key := channelID + ":" + userID
+	if p.cache != nil {
+		if cached, ok := p.cache.Get(key); ok {
+			return cached.(bool), nil
+		}
+	}
+
+	var allowed bool
+	var err error
+	if serverID := ServerID(channelRow); serverID != "" {
+		allowed, err = p.db.IsServerMember(serverID, userID)
+	} else if ownerID, _ := channelRow["owner_id"].(string); ownerID == userID {
+		allowed = true
+	} else {
+		allowed, err = p.db.IsChannelMember(channelID, userID)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if p.cache != nil {
+		p.cache.Set(key, allowed)
+	}
+	return allowed, nil
+}
+
+// Server verifica se o usuário é membro do servidor.
+// Retorna ErrServerNotFound ou ErrForbidden caso contrário.
+func (p *Policy) Server(serverID, userID string) error {
+	if _, err := gocql.ParseUUID(serverID); err != nil {
+		return ErrServerNotFound
+	}
+
+	isMember, err := p.db.IsServerMember(serverID, userID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return nil
+	}
+
+	// Diferenciar servidor inexistente de servidor do qual o usuário não participa
+	if _, err := p.db.GetGroupByID(serverID); err == gocql.ErrNotFound {
+		return ErrServerNotFound
+	} else if err != nil {
+		return err
+	}
+	return ErrForbidden
+}
+
+// UserChannels retorna os canais acessíveis ao usuário: os canais dos servidores e grupos
+// dos quais é membro e as DMs e grupos privados dos quais participa
+func (p *Policy) UserChannels(userID string) ([]map[string]interface{}, error) {
+	seen := make(map[string]bool)
+	var channels []map[string]interface{}
+
+	groupIDs, err := p.db.GetUserGroupIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupID := range groupIDs {
+		rows, err := p.db.GetServerChannels(groupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if id := row["channel_id"].(string); !seen[id] {
+				seen[id] = true
+				channels = append(channels, row)
+			}
+		}
+	}
+
+	channelIDs, err := p.db.GetUserChannelIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, channelID := range channelIDs {
+		if seen[channelID] {
+			continue
+		}
+		row, err := p.db.GetChannelByID(channelID)
+		if err == gocql.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		seen[channelID] = true
+		channels = append(channels, row)
+	}
+
+	return channels, nil
+}
+
+// RelatedUsers retorna quem pode ver a presença do usuário: os amigos, os membros dos servidores
+// e grupos dos quais ele participa e o próprio usuário
+func (p *Policy) RelatedUsers(userID string) (map[string]bool, error) {
+	related := map[string]bool{userID: true}
+
+	friends, err := p.db.GetFriends(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, friend := range friends {
+		related[friend["friend_id"].(string)] = true
+	}
+
+	groupIDs, err := p.db.GetUserGroupIDs(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, groupID := range groupIDs {
+		memberIDs, err := p.db.GetGroupMemberIDs(groupID)
+		if err != nil {
+			return nil, err
+		}
+		for _, memberID := range memberIDs {
+			related[memberID] = true
+		}
+	}
+
+	return related, nil
+}
+
+// ServerID retorna o servidor de um canal, ou "" para DMs, grupos privados e canais avulsos
+func ServerID(channelRow map[string]interface{}) string {
+	serverID, _ := channelRow["server_id"].(string)
+	if serverID == (gocql.UUID{}).String() {
+		return ""
+	}
+	return serverID
+}
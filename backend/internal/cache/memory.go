@@ -50,6 +50,23 @@ func (c *MemoryCache) Set(key string, value interface{}) {
 	}
 }
 
+// Add insere um valor apenas se a chave não existir (ou estiver expirada).
+// Retorna false se a chave já estava presente
+func (c *MemoryCache) Add(key string, value interface{}) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if entry, exists := c.data[key]; exists && time.Now().Before(entry.expiresAt) {
+		return false
+	}
+
+	c.data[key] = cacheEntry{
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	return true
+}
+
 // Get retorna um valor do cache
 func (c *MemoryCache) Get(key string) (interface{}, bool) {
 	c.mu.RLock()
@@ -0,0 +1,27 @@
+package commands
+
+import "encoding/json"
+
+// InvokeSubject é o subject NATS (request/reply) usado pelo gateway WebSocket
+// para executar comandos na API
+const InvokeSubject = "commands.invoke"
+
+// InvokeRequest é o pedido de execução de um comando enviado pelo gateway
+type InvokeRequest struct {
+	ChannelID string `json:"channelId"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+	Content   string `json:"content"`
+	MessageID string `json:"messageId,omitempty"` // TimeUUID da resposta pública (reservado com o nonce do envio)
+}
+
+// InvokeResponse é a resposta da API. Handled é false quando o conteúdo não é um
+// comando conhecido (e deve ser enviado como mensagem comum). Respostas públicas
+// chegam aos clientes pelo evento message.create; Message só é preenchida para
+// respostas efêmeras, que devem ser entregues apenas ao autor do comando.
+type InvokeResponse struct {
+	Handled   bool            `json:"handled"`
+	Ephemeral bool            `json:"ephemeral,omitempty"`
+	Message   json.RawMessage `json:"message,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
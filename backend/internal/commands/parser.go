@@ -0,0 +1,258 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Parse separa o nome do comando e os argumentos de um conteúdo iniciado por "/".
+// Retorna ok=false para conteúdos que não têm a forma de um comando (ex.: "/ texto", "//")
+func Parse(content string) (name, args string, ok bool) {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "/") {
+		return "", "", false
+	}
+
+	rest := content[1:]
+	end := strings.IndexFunc(rest, unicode.IsSpace)
+	if end == -1 {
+		end = len(rest)
+	}
+
+	name = strings.ToLower(rest[:end])
+	if !ValidName(name) {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(rest[end:]), true
+}
+
+// Values são os valores das opções de uma invocação, já convertidos para o tipo declarado
+type Values map[string]interface{}
+
+// String retorna o valor de uma opção string ou user ("" se ausente)
+func (v Values) String(name string) string {
+	s, _ := v[name].(string)
+	return s
+}
+
+// Int retorna o valor de uma opção integer (0 se ausente)
+func (v Values) Int(name string) int64 {
+	n, _ := v[name].(int64)
+	return n
+}
+
+// Bool retorna o valor de uma opção boolean (false se ausente)
+func (v Values) Bool(name string) bool {
+	b, _ := v[name].(bool)
+	return b
+}
+
+// Duration retorna o valor de uma opção duration (0 se ausente)
+func (v Values) Duration(name string) time.Duration {
+	d, _ := v[name].(time.Duration)
+	return d
+}
+
+// Has indica se a opção foi informada
+func (v Values) Has(name string) bool {
+	_, ok := v[name]
+	return ok
+}
+
+// ParseOptions converte os argumentos de texto nas opções do comando.
+// Argumentos podem ser posicionais (na ordem declarada) ou nomeados (nome:valor),
+// e aceitam aspas para valores com espaços. A última opção string posicional
+// recebe o restante do texto, sem precisar de aspas.
+func (c *Command) ParseOptions(args string) (Values, error) {
+	values := make(Values)
+	positional := 0
+	rest := args
+
+	for {
+		rest = strings.TrimLeftFunc(rest, unicode.IsSpace)
+		if rest == "" {
+			break
+		}
+
+		// Argumento nomeado: nome:valor
+		if name, value, remaining, ok := c.namedArg(rest); ok {
+			option := c.option(name)
+			if values.Has(name) {
+				return nil, fmt.Errorf("option %q given more than once", name)
+			}
+			converted, err := convertOption(option, value)
+			if err != nil {
+				return nil, err
+			}
+			values[name] = converted
+			rest = remaining
+			continue
+		}
+
+		// Próxima opção posicional ainda não informada
+		for positional < len(c.Options) && values.Has(c.Options[positional].Name) {
+			positional++
+		}
+		if positional >= len(c.Options) {
+			return nil, fmt.Errorf("too many arguments. Usage: %s", c.Usage())
+		}
+		option := c.Options[positional]
+
+		var value string
+		if option.Type == OptionString && c.lastPositional(positional) && !strings.HasPrefix(rest, `"`) {
+			value, rest = strings.TrimSpace(rest), ""
+		} else {
+			var err error
+			value, rest, err = nextToken(rest)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		converted, err := convertOption(option, value)
+		if err != nil {
+			return nil, err
+		}
+		values[option.Name] = converted
+		positional++
+	}
+
+	for _, option := range c.Options {
+		if option.Required && !values.Has(option.Name) {
+			return nil, fmt.Errorf("missing required option %q. Usage: %s", option.Name, c.Usage())
+		}
+	}
+
+	return values, nil
+}
+
+// Usage retorna a forma de uso do comando, ex.: /remind <when> <text>
+func (c *Command) Usage() string {
+	var b strings.Builder
+	b.WriteString("/" + c.Name)
+	for _, option := range c.Options {
+		if option.Required {
+			b.WriteString(" <" + option.Name + ">")
+		} else {
+			b.WriteString(" [" + option.Name + "]")
+		}
+	}
+	return b.String()
+}
+
+func (c *Command) option(name string) Option {
+	for _, option := range c.Options {
+		if option.Name == name {
+			return option
+		}
+	}
+	return Option{}
+}
+
+// lastPositional indica se não há opções depois da posição informada
+func (c *Command) lastPositional(index int) bool {
+	return index == len(c.Options)-1
+}
+
+// namedArg reconhece "nome:valor" quando nome é uma opção do comando
+func (c *Command) namedArg(s string) (name, value, rest string, ok bool) {
+	colon := strings.IndexByte(s, ':')
+	if colon <= 0 {
+		return "", "", "", false
+	}
+	name = s[:colon]
+	if strings.IndexFunc(name, unicode.IsSpace) != -1 || c.option(name).Name == "" {
+		return "", "", "", false
+	}
+
+	value, rest, err := nextToken(s[colon+1:])
+	if err != nil {
+		return "", "", "", false
+	}
+	return name, value, rest, true
+}
+
+// nextToken lê um valor (entre aspas ou até o próximo espaço)
+func nextToken(s string) (token, rest string, err error) {
+	if strings.HasPrefix(s, `"`) {
+		end := strings.IndexByte(s[1:], '"')
+		if end == -1 {
+			return "", "", fmt.Errorf("unterminated quote")
+		}
+		return s[1 : end+1], s[end+2:], nil
+	}
+
+	end := strings.IndexFunc(s, unicode.IsSpace)
+	if end == -1 {
+		return s, "", nil
+	}
+	return s[:end], s[end:], nil
+}
+
+// convertOption converte o texto para o tipo da opção
+func convertOption(option Option, value string) (interface{}, error) {
+	switch option.Type {
+	case OptionInteger:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("option %q must be an integer", option.Name)
+		}
+		return n, nil
+	case OptionBoolean:
+		switch strings.ToLower(value) {
+		case "true", "yes", "on", "1":
+			return true, nil
+		case "false", "no", "off", "0":
+			return false, nil
+		}
+		return nil, fmt.Errorf("option %q must be true or false", option.Name)
+	case OptionDuration:
+		d, err := ParseDuration(value)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("option %q must be a duration like 30s, 10m, 2h or 1d", option.Name)
+		}
+		return d, nil
+	case OptionUser:
+		userID := strings.TrimSuffix(strings.TrimPrefix(value, "<@"), ">")
+		if len(userID) != 36 {
+			return nil, fmt.Errorf("option %q must be a user mention", option.Name)
+		}
+		return userID, nil
+	default:
+		if value == "" {
+			return nil, fmt.Errorf("option %q must not be empty", option.Name)
+		}
+		if len(option.Choices) > 0 {
+			for _, choice := range option.Choices {
+				if strings.EqualFold(choice, value) {
+					return choice, nil
+				}
+			}
+			return nil, fmt.Errorf("option %q must be one of: %s", option.Name, strings.Join(option.Choices, ", "))
+		}
+		return value, nil
+	}
+}
+
+// ParseDuration aceita durações do Go (90s, 1h30m) e também dias (1d, 2d12h)
+func ParseDuration(value string) (time.Duration, error) {
+	if days, rest, found := strings.Cut(value, "d"); found {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		d := time.Duration(n) * 24 * time.Hour
+		if rest == "" {
+			return d, nil
+		}
+		extra, err := time.ParseDuration(rest)
+		if err != nil {
+			return 0, err
+		}
+		return d + extra, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		content string
+		name    string
+		args    string
+		ok      bool
+	}{
+		{"/roll", "roll", "", true},
+		{"  /Remind 10m  beber água ", "remind", "10m  beber água", true},
+		{"/poll\tpergunta", "poll", "pergunta", true},
+		{"olá /roll", "", "", false},
+		{"/ texto", "", "", false},
+		{"//", "", "", false},
+		{"/nome.invalido", "", "", false},
+	}
+
+	for _, tt := range tests {
+		name, args, ok := Parse(tt.content)
+		if name != tt.name || args != tt.args || ok != tt.ok {
+			t.Errorf("Parse(%q) = %q, %q, %v, want %q, %q, %v", tt.content, name, args, ok, tt.name, tt.args, tt.ok)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+		valid bool
+	}{
+		{"30s", 30 * time.Second, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"1d", 24 * time.Hour, true},
+		{"2d12h", 60 * time.Hour, true},
+		{"d", 0, false},
+		{"1dx", 0, false},
+		{"amanhã", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if (err == nil) != tt.valid || (tt.valid && got != tt.want) {
+			t.Errorf("ParseDuration(%q) = %v, %v, want %v (valid=%v)", tt.value, got, err, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	cmd := Command{
+		Name:        "test",
+		Description: "test",
+		Options: []Option{
+			{Name: "when", Type: OptionDuration, Required: true},
+			{Name: "count", Type: OptionInteger},
+			{Name: "loud", Type: OptionBoolean},
+			{Name: "text", Type: OptionString},
+		},
+	}
+	const userID = "123e4567-e89b-12d3-a456-426614174000"
+
+	tests := []struct {
+		name string
+		cmd  Command
+		args string
+		want Values
+		err  bool
+	}{
+		{name: "positional", cmd: cmd, args: "10m 3 yes olá mundo",
+			want: Values{"when": 10 * time.Minute, "count": int64(3), "loud": true, "text": "olá mundo"}},
+		{name: "named in any order", cmd: cmd, args: "text:oi loud:off when:1d",
+			want: Values{"when": 24 * time.Hour, "loud": false, "text": "oi"}},
+		{name: "named then positional", cmd: cmd, args: "count:2 2h on resto do texto",
+			want: Values{"when": 2 * time.Hour, "count": int64(2), "loud": true, "text": "resto do texto"}},
+		{name: "quoted named value", cmd: cmd, args: `30s text:"com espaços" count:7`,
+			want: Values{"when": 30 * time.Second, "count": int64(7), "text": "com espaços"}},
+		{name: "quoted positional string", cmd: cmd, args: `30s 1 true "só isso"`,
+			want: Values{"when": 30 * time.Second, "count": int64(1), "loud": true, "text": "só isso"}},
+		{name: "unknown option is positional text", cmd: cmd, args: "5m 1 no cor:azul",
+			want: Values{"when": 5 * time.Minute, "count": int64(1), "loud": false, "text": "cor:azul"}},
+		{name: "missing required", cmd: cmd, args: "count:1", err: true},
+		{name: "invalid duration", cmd: cmd, args: "amanhã", err: true},
+		{name: "zero duration", cmd: cmd, args: "0s", err: true},
+		{name: "invalid integer", cmd: cmd, args: "1m muitos", err: true},
+		{name: "invalid boolean", cmd: cmd, args: "1m 1 talvez", err: true},
+		{name: "repeated option", cmd: cmd, args: "1m count:1 count:2", err: true},
+		{name: "unterminated quote", cmd: cmd, args: `1m 1 yes "aberta`, err: true},
+		{name: "too many arguments", cmd: Command{Name: "n", Options: []Option{{Name: "n", Type: OptionInteger}}}, args: "1 2", err: true},
+		{name: "unknown option without string rest", cmd: Command{Name: "n", Options: []Option{{Name: "n", Type: OptionInteger}}}, args: "x:1", err: true},
+		{name: "user mention", cmd: Command{Name: "u", Options: []Option{{Name: "who", Type: OptionUser}}}, args: "<@" + userID + ">",
+			want: Values{"who": userID}},
+		{name: "invalid user", cmd: Command{Name: "u", Options: []Option{{Name: "who", Type: OptionUser}}}, args: "@ana", err: true},
+		{name: "choice", cmd: Command{Name: "c", Options: []Option{{Name: "color", Type: OptionString, Choices: []string{"Red", "Blue"}}}}, args: "blue",
+			want: Values{"color": "Blue"}},
+		{name: "invalid choice", cmd: Command{Name: "c", Options: []Option{{Name: "color", Type: OptionString, Choices: []string{"Red", "Blue"}}}}, args: "green", err: true},
+		{name: "empty args", cmd: cmd, args: "", err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cmd.ParseOptions(tt.args)
+			if tt.err {
+				if err == nil {
+					t.Fatalf("ParseOptions(%q) = %v, want error", tt.args, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseOptions(%q): %v", tt.args, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseOptions(%q) = %#v, want %#v", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsage(t *testing.T) {
+	cmd := Command{Name: "remind", Options: []Option{
+		{Name: "when", Type: OptionDuration, Required: true},
+		{Name: "text", Type: OptionString},
+	}}
+	if got := cmd.Usage(); got != "/remind <when> [text]" {
+		t.Fatalf("Usage() = %q", got)
+	}
+}
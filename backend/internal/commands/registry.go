@@ -0,0 +1,168 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// Tipos de opção aceitos
+const (
+	OptionString   = "string"
+	OptionInteger  = "integer"
+	OptionBoolean  = "boolean"
+	OptionDuration = "duration" // ex.: 30s, 10m, 2h, 1d
+	OptionUser     = "user"     // ID ou menção <@id>
+)
+
+// Permissões exigidas para usar um comando
+const (
+	PermissionEveryone  = ""
+	PermissionModerator = "moderator" // owners, admins e moderadores do servidor
+	PermissionAdmin     = "admin"     // owners e admins do servidor
+)
+
+// Origem de um comando
+const (
+	SourceBuiltin = "builtin"
+	SourceBot     = "bot"
+)
+
+const (
+	MaxOptions           = 10
+	MaxDescriptionLength = 100
+)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// Option descreve um argumento de um comando
+type Option struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Choices     []string `json:"choices,omitempty"` // valores aceitos (apenas para string)
+}
+
+// Command é a declaração de um comando de barra
+type Command struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Options     []Option `json:"options,omitempty"`
+	Permission  string   `json:"permission,omitempty"`
+	Source      string   `json:"source"`
+	BotName     string   `json:"botName,omitempty"` // apenas para comandos de bots
+}
+
+// Validate verifica nome, descrição e opções de um comando
+func (c *Command) Validate() error {
+	if !ValidName(c.Name) {
+		return fmt.Errorf("invalid command name %q: use 1-32 lowercase letters, digits, '-' or '_'", c.Name)
+	}
+	if c.Description == "" || len(c.Description) > MaxDescriptionLength {
+		return fmt.Errorf("command description must be between 1 and %d characters", MaxDescriptionLength)
+	}
+	switch c.Permission {
+	case PermissionEveryone, PermissionModerator, PermissionAdmin:
+	default:
+		return fmt.Errorf("invalid permission %q", c.Permission)
+	}
+	if len(c.Options) > MaxOptions {
+		return fmt.Errorf("a command can have at most %d options", MaxOptions)
+	}
+
+	seen := make(map[string]bool)
+	optional := false
+	for _, option := range c.Options {
+		if !ValidName(option.Name) {
+			return fmt.Errorf("invalid option name %q", option.Name)
+		}
+		if seen[option.Name] {
+			return fmt.Errorf("duplicate option %q", option.Name)
+		}
+		seen[option.Name] = true
+
+		switch option.Type {
+		case OptionString, OptionInteger, OptionBoolean, OptionDuration, OptionUser:
+		default:
+			return fmt.Errorf("invalid type %q for option %q", option.Type, option.Name)
+		}
+		if len(option.Choices) > 0 && option.Type != OptionString {
+			return fmt.Errorf("choices are only supported for string options (%q)", option.Name)
+		}
+		if len(option.Description) > MaxDescriptionLength {
+			return fmt.Errorf("description of option %q is too long", option.Name)
+		}
+
+		// Opções obrigatórias vêm antes das opcionais (argumentos posicionais)
+		if option.Required && optional {
+			return fmt.Errorf("required option %q must come before optional options", option.Name)
+		}
+		if !option.Required {
+			optional = true
+		}
+	}
+
+	return nil
+}
+
+// ValidName indica se o nome é válido para comandos e opções
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ErrDuplicateCommand indica que já existe um comando com o mesmo nome
+var ErrDuplicateCommand = errors.New("command already registered")
+
+// Registry guarda as declarações dos comandos embutidos
+type Registry struct {
+	mu       sync.RWMutex
+	commands map[string]Command
+}
+
+// NewRegistry cria um registro vazio
+func NewRegistry() *Registry {
+	return &Registry{
+		commands: make(map[string]Command),
+	}
+}
+
+// Register adiciona um comando ao registro
+func (r *Registry) Register(cmd Command) error {
+	if err := cmd.Validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.commands[cmd.Name]; exists {
+		return fmt.Errorf("%w: /%s", ErrDuplicateCommand, cmd.Name)
+	}
+	r.commands[cmd.Name] = cmd
+	return nil
+}
+
+// Get retorna um comando pelo nome
+func (r *Registry) Get(name string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cmd, ok := r.commands[name]
+	return cmd, ok
+}
+
+// List retorna os comandos registrados, ordenados pelo nome
+func (r *Registry) List() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commands := make([]Command, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
@@ -0,0 +1,84 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCommandValidate(t *testing.T) {
+	valid := func() Command {
+		return Command{Name: "remind", Description: "lembrete", Options: []Option{
+			{Name: "when", Type: OptionDuration, Required: true},
+			{Name: "text", Type: OptionString},
+		}}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Command)
+		valid  bool
+	}{
+		{"valid", func(c *Command) {}, true},
+		{"moderator permission", func(c *Command) { c.Permission = PermissionModerator }, true},
+		{"uppercase name", func(c *Command) { c.Name = "Remind" }, false},
+		{"long name", func(c *Command) { c.Name = strings.Repeat("a", 33) }, false},
+		{"empty description", func(c *Command) { c.Description = "" }, false},
+		{"long description", func(c *Command) { c.Description = strings.Repeat("a", MaxDescriptionLength+1) }, false},
+		{"unknown permission", func(c *Command) { c.Permission = "owner" }, false},
+		{"unknown option type", func(c *Command) { c.Options[1].Type = "float" }, false},
+		{"invalid option name", func(c *Command) { c.Options[1].Name = "o texto" }, false},
+		{"duplicate option", func(c *Command) { c.Options[1].Name = "when" }, false},
+		{"choices on integer", func(c *Command) {
+			c.Options[0] = Option{Name: "n", Type: OptionInteger, Required: true, Choices: []string{"1"}}
+		}, false},
+		{"required after optional", func(c *Command) {
+			c.Options = append(c.Options, Option{Name: "extra", Type: OptionString, Required: true})
+		}, false},
+		{"too many options", func(c *Command) {
+			c.Options = nil
+			for i := 0; i <= MaxOptions; i++ {
+				c.Options = append(c.Options, Option{Name: "o" + string(rune('a'+i)), Type: OptionString})
+			}
+		}, false},
+	}
+
+	for _, tt := range tests {
+		cmd := valid()
+		tt.modify(&cmd)
+		if err := cmd.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid=%v", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+
+	for _, name := range []string{"roll", "poll", "remind"} {
+		if err := r.Register(Command{Name: name, Description: name}); err != nil {
+			t.Fatalf("Register(%s): %v", name, err)
+		}
+	}
+	if err := r.Register(Command{Name: "roll", Description: "outro"}); !errors.Is(err, ErrDuplicateCommand) {
+		t.Fatalf("duplicate Register = %v, want ErrDuplicateCommand", err)
+	}
+	if err := r.Register(Command{Name: "Bad Name", Description: "x"}); err == nil {
+		t.Fatal("Register accepted an invalid command")
+	}
+
+	if cmd, ok := r.Get("poll"); !ok || cmd.Name != "poll" {
+		t.Fatalf("Get(poll) = %+v, %v", cmd, ok)
+	}
+	if _, ok := r.Get("missing"); ok {
+		t.Fatal("Get returned an unregistered command")
+	}
+
+	var names []string
+	for _, cmd := range r.List() {
+		names = append(names, cmd.Name)
+	}
+	if strings.Join(names, ",") != "poll,remind,roll" {
+		t.Fatalf("List() = %v, want sorted by name", names)
+	}
+}
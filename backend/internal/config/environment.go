@@ -44,6 +44,9 @@ type EnvironmentConfig struct {
 	// CORS
 	CORSAllowedOrigins []string
 
+	// Outgoing webhooks
+	WebhookAllowHTTP bool
+
 	// Logging
 	LogLevel string
 }
@@ -166,6 +169,16 @@ func ValidateEnvironmentConfig() ValidationResult {
 		}
 	}
 
+	// Outgoing webhooks
+	if webhookHTTP := os.Getenv("WEBHOOK_ALLOW_HTTP"); webhookHTTP != "" {
+		allowHTTP, err := strconv.ParseBool(webhookHTTP)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("WEBHOOK_ALLOW_HTTP must be a boolean, got: %s", webhookHTTP))
+		} else if allowHTTP && env == "production" {
+			errors = append(errors, "WEBHOOK_ALLOW_HTTP must not be enabled in production")
+		}
+	}
+
 	// Log level
 	logLevel := os.Getenv("LOG_LEVEL")
 	if logLevel == "" {
@@ -237,6 +250,9 @@ func GetEnvironmentConfig() (*EnvironmentConfig, error) {
 		// CORS
 		CORSAllowedOrigins: parseCORSOrigins(),
 
+		// Outgoing webhooks
+		WebhookAllowHTTP: getEnvAsBool("WEBHOOK_ALLOW_HTTP", false),
+
 		// Logging
 		LogLevel: getEnvOrDefault("LOG_LEVEL", "info"),
 	}
@@ -340,6 +356,18 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	return value
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
 func parseCORSOrigins() []string {
 	originsEnv := os.Getenv("CORS_ALLOWED_ORIGINS")
 	if originsEnv == "" {
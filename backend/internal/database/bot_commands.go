@@ -0,0 +1,116 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ==================== COMANDOS DE BOTS ====================
+
+const botCommandColumns = `server_id, name, command_id, description, options, permission, bot_name, bot_avatar, callback_url, secret, created_by, created_at`
+
+// CreateBotCommand registra um comando de barra de um bot no servidor.
+// Retorna false se já existir um comando com o mesmo nome
+func (db *CassandraDB) CreateBotCommand(serverID, name, commandID, description, options, permission, botName, botAvatar, callbackURL, secret, createdBy string) (bool, error) {
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return false, err
+	}
+	commandUUID, err := gocql.ParseUUID(commandID)
+	if err != nil {
+		return false, err
+	}
+	creatorUUID, err := gocql.ParseUUID(createdBy)
+	if err != nil {
+		return false, err
+	}
+
+	query := `INSERT INTO nexus.bot_commands (` + botCommandColumns + `)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) IF NOT EXISTS`
+
+	existing := map[string]interface{}{}
+	return db.session.Query(query, serverUUID, name, commandUUID, description, options, permission,
+		botName, botAvatar, callbackURL, secret, creatorUUID, time.Now()).MapScanCAS(existing)
+}
+
+// GetBotCommand retorna um comando de bot pelo nome
+func (db *CassandraDB) GetBotCommand(serverID, name string) (map[string]interface{}, error) {
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + botCommandColumns + ` FROM nexus.bot_commands WHERE server_id = ? AND name = ?`
+
+	var row botCommandScan
+	if err := db.session.Query(query, serverUUID, name).Scan(row.dest()...); err != nil {
+		return nil, err
+	}
+	return row.row(), nil
+}
+
+// GetServerBotCommands retorna os comandos de bots de um servidor
+func (db *CassandraDB) GetServerBotCommands(serverID string) ([]map[string]interface{}, error) {
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + botCommandColumns + ` FROM nexus.bot_commands WHERE server_id = ?`
+
+	iter := db.session.Query(query, serverUUID).Iter()
+	defer iter.Close()
+
+	var results []map[string]interface{}
+	var row botCommandScan
+	for iter.Scan(row.dest()...) {
+		results = append(results, row.row())
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// DeleteBotCommand remove um comando de bot
+func (db *CassandraDB) DeleteBotCommand(serverID, name string) error {
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(`DELETE FROM nexus.bot_commands WHERE server_id = ? AND name = ?`, serverUUID, name).Exec()
+}
+
+// botCommandScan recebe uma linha de bot_commands
+type botCommandScan struct {
+	serverID, commandID, createdBy                                       gocql.UUID
+	name, description, options, permission, botName, botAvatar, callback string
+	secret                                                               string
+	createdAt                                                            time.Time
+}
+
+func (bs *botCommandScan) dest() []interface{} {
+	return []interface{}{&bs.serverID, &bs.name, &bs.commandID, &bs.description, &bs.options, &bs.permission,
+		&bs.botName, &bs.botAvatar, &bs.callback, &bs.secret, &bs.createdBy, &bs.createdAt}
+}
+
+func (bs *botCommandScan) row() map[string]interface{} {
+	return map[string]interface{}{
+		"server_id":    bs.serverID.String(),
+		"name":         bs.name,
+		"command_id":   bs.commandID.String(),
+		"description":  bs.description,
+		"options":      bs.options, // JSON ([]commands.Option)
+		"permission":   bs.permission,
+		"bot_name":     bs.botName,
+		"bot_avatar":   bs.botAvatar,
+		"callback_url": bs.callback,
+		"secret":       bs.secret,
+		"created_by":   bs.createdBy.String(),
+		"created_at":   bs.createdAt,
+	}
+}
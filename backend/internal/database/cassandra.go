@@ -2,8 +2,10 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"github.com/gocql/gocql"
@@ -124,6 +126,117 @@ func (db *CassandraDB) InitializeKeyspace() error {
 			email text PRIMARY KEY,
 			user_id uuid
 		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.message_nonces (
+			author_id uuid,
+			nonce text,
+			channel_id uuid,
+			msg_id timeuuid,
+			PRIMARY KEY ((author_id, nonce))
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.slowmode_sends (
+			channel_id uuid,
+			user_id uuid,
+			sent_at timestamp,
+			PRIMARY KEY ((channel_id, user_id))
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.polls (
+			poll_id timeuuid PRIMARY KEY,
+			channel_id uuid,
+			author_id uuid,
+			question text,
+			options list<text>,
+			multi_select boolean,
+			expires_at timestamp,
+			closed_at timestamp,
+			results map<int, int>,
+			created_at timestamp
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.poll_votes (
+			poll_id timeuuid,
+			user_id uuid,
+			options set<int>,
+			voted_at timestamp,
+			PRIMARY KEY (poll_id, user_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.webhooks (
+			webhook_id uuid PRIMARY KEY,
+			channel_id uuid,
+			server_id uuid,
+			name text,
+			avatar_url text,
+			token_hash text,
+			created_by uuid,
+			created_at timestamp,
+			updated_at timestamp
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.webhooks_by_channel (
+			channel_id uuid,
+			webhook_id uuid,
+			PRIMARY KEY (channel_id, webhook_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.outgoing_webhooks (
+			endpoint_id uuid PRIMARY KEY,
+			server_id uuid,
+			url text,
+			secret text,
+			events set<text>,
+			active boolean,
+			created_by uuid,
+			created_at timestamp,
+			updated_at timestamp
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.outgoing_webhooks_by_server (
+			server_id uuid,
+			endpoint_id uuid,
+			PRIMARY KEY (server_id, endpoint_id)
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.webhook_deliveries (
+			endpoint_id uuid,
+			delivery_id timeuuid,
+			attempt int,
+			event_type text,
+			status text,
+			status_code int,
+			error text,
+			duration_ms int,
+			request_body text,
+			response_body text,
+			created_at timestamp,
+			PRIMARY KEY (endpoint_id, delivery_id, attempt)
+		) WITH CLUSTERING ORDER BY (delivery_id DESC, attempt DESC)
+		  AND default_time_to_live = 604800`,
+		`CREATE TABLE IF NOT EXISTS nexus.bot_commands (
+			server_id uuid,
+			name text,
+			command_id uuid,
+			description text,
+			options text,
+			permission text,
+			bot_name text,
+			bot_avatar text,
+			callback_url text,
+			secret text,
+			created_by uuid,
+			created_at timestamp,
+			PRIMARY KEY (server_id, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.server_emojis (
+			server_id uuid,
+			name text,
+			emoji_id uuid,
+			filename text,
+			created_by uuid,
+			created_at timestamp,
+			PRIMARY KEY (server_id, name)
+		)`,
+		`CREATE TABLE IF NOT EXISTS nexus.emojis (
+			emoji_id uuid PRIMARY KEY,
+			server_id uuid,
+			name text,
+			filename text,
+			created_by uuid,
+			created_at timestamp
+		)`,
 	}
 
 	for _, query := range queries {
@@ -138,12 +251,55 @@ func (db *CassandraDB) InitializeKeyspace() error {
 		log.Printf("Info: Discriminator index not created (column may not exist yet): %v", err)
 	}
 
+	// Adicionar coluna type (mensagens especiais, ex.: "poll") se não existir
+	alterMessagesTypeQuery := `ALTER TABLE nexus.messages_by_channel ADD type text`
+	if err := db.session.Query(alterMessagesTypeQuery).Exec(); err != nil {
+		log.Printf("Info: Failed to add type to messages_by_channel (may already exist): %v", err)
+	}
+
+	// Colunas de embeds, de autoria de webhooks e de mensagens encaminhadas
+	alterMessagesQueries := []string{
+		`ALTER TABLE nexus.messages_by_channel ADD embeds text`,
+		`ALTER TABLE nexus.messages_by_channel ADD webhook_id uuid`,
+		`ALTER TABLE nexus.messages_by_channel ADD author_name text`,
+		`ALTER TABLE nexus.messages_by_channel ADD author_avatar text`,
+		`ALTER TABLE nexus.messages_by_channel ADD reference text`,
+	}
+	for _, query := range alterMessagesQueries {
+		if err := db.session.Query(query).Exec(); err != nil {
+			log.Printf("Info: Failed to alter messages_by_channel (column may already exist): %v | Query: %s", err, query)
+		}
+	}
+
 	// Adicionar coluna server_id na tabela channels se não existir
 	alterChannelsQuery := `ALTER TABLE nexus.channels ADD server_id uuid`
 	if err := db.session.Query(alterChannelsQuery).Exec(); err != nil {
 		log.Printf("Info: Failed to add server_id to channels (may already exist): %v", err)
 	}
 
+	// Adicionar coluna message_ttl (mensagens efêmeras por canal) se não existir
+	alterChannelsTTLQuery := `ALTER TABLE nexus.channels ADD message_ttl int`
+	if err := db.session.Query(alterChannelsTTLQuery).Exec(); err != nil {
+		log.Printf("Info: Failed to add message_ttl to channels (may already exist): %v", err)
+	}
+
+	// Adicionar coluna slowmode (segundos entre mensagens por usuário) se não existir
+	alterChannelsSlowmodeQuery := `ALTER TABLE nexus.channels ADD slowmode int`
+	if err := db.session.Query(alterChannelsSlowmodeQuery).Exec(); err != nil {
+		log.Printf("Info: Failed to add slowmode to channels (may already exist): %v", err)
+	}
+
+	// Status personalizado do usuário e atividades da presença (JSON)
+	alterPresenceQueries := []string{
+		`ALTER TABLE nexus.users ADD custom_status text`,
+		`ALTER TABLE nexus.user_presence ADD activities text`,
+	}
+	for _, query := range alterPresenceQueries {
+		if err := db.session.Query(query).Exec(); err != nil {
+			log.Printf("Info: Failed to add presence column (may already exist): %v | Query: %s", err, query)
+		}
+	}
+
 	// Criar índice para server_id
 	serverIndexQuery := `CREATE INDEX IF NOT EXISTS idx_channels_server_id ON nexus.channels(server_id)`
 	if err := db.session.Query(serverIndexQuery).Exec(); err != nil {
@@ -244,20 +400,88 @@ func (db *CassandraDB) GetTasks(channelID string) ([]map[string]interface{}, err
 	return results, nil
 }
 
-// UpdateUserPresence atualiza a presença de um usuário
-func (db *CassandraDB) UpdateUserPresence(userID string, status string) error {
-	query := `INSERT INTO nexus.user_presence (user_id, status, last_seen) VALUES (?, ?, ?)`
-	return db.session.Query(query, userID, status, time.Now()).Exec()
+// UpdateUserPresence atualiza a presença de um usuário (activities em JSON)
+func (db *CassandraDB) UpdateUserPresence(userID string, status string, activities string) error {
+	query := `INSERT INTO nexus.user_presence (user_id, status, last_seen, activities) VALUES (?, ?, ?, ?)`
+	return db.session.Query(query, userID, status, time.Now(), activities).Exec()
 }
 
 // GetUserPresence retorna a presença de um usuário
 func (db *CassandraDB) GetUserPresence(userID string) (map[string]interface{}, error) {
-	query := `SELECT status, last_seen FROM nexus.user_presence WHERE user_id = ?`
+	query := `SELECT status, last_seen, activities FROM nexus.user_presence WHERE user_id = ?`
 	row := make(map[string]interface{})
 	err := db.session.Query(query, userID).MapScan(row)
 	return row, err
 }
 
+// GetUserPresences retorna a presença de vários usuários (user_id, status, last_seen, activities);
+// usuários sem registro não aparecem no resultado
+func (db *CassandraDB) GetUserPresences(userIDs []string) ([]map[string]interface{}, error) {
+	ids := make([]gocql.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		id, err := gocql.ParseUUID(userID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	iter := db.session.Query(`SELECT user_id, status, last_seen, activities FROM nexus.user_presence WHERE user_id IN ?`, ids).Iter()
+	defer iter.Close()
+
+	var results []map[string]interface{}
+	var userID gocql.UUID
+	var status, activities string
+	var lastSeen time.Time
+	for iter.Scan(&userID, &status, &lastSeen, &activities) {
+		results = append(results, map[string]interface{}{
+			"user_id":    userID.String(),
+			"status":     status,
+			"last_seen":  lastSeen,
+			"activities": activities,
+		})
+	}
+
+	return results, iter.Close()
+}
+
+// UpdateUserCustomStatus grava o status personalizado do usuário (JSON; "" remove)
+func (db *CassandraDB) UpdateUserCustomStatus(userID, customStatus string) error {
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE nexus.users SET custom_status = ?, updated_at = ? WHERE user_id = ?`
+	return db.session.Query(query, customStatus, time.Now(), userUUID).Exec()
+}
+
+// GetUserCustomStatuses retorna o status personalizado (JSON) de vários usuários;
+// usuários sem status não aparecem no resultado
+func (db *CassandraDB) GetUserCustomStatuses(userIDs []string) (map[string]string, error) {
+	ids := make([]gocql.UUID, 0, len(userIDs))
+	for _, userID := range userIDs {
+		id, err := gocql.ParseUUID(userID)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	iter := db.session.Query(`SELECT user_id, custom_status FROM nexus.users WHERE user_id IN ?`, ids).Iter()
+	defer iter.Close()
+
+	statuses := make(map[string]string)
+	var userID gocql.UUID
+	var customStatus string
+	for iter.Scan(&userID, &customStatus) {
+		if customStatus != "" {
+			statuses[userID.String()] = customStatus
+		}
+	}
+
+	return statuses, iter.Close()
+}
+
 // Health verifica a saúde da conexão com Cassandra
 func (db *CassandraDB) Health(ctx context.Context) error {
 	return db.session.Query("SELECT now() FROM system.local").Exec()
@@ -357,7 +581,7 @@ func (db *CassandraDB) GetUserByUsernameAndDiscriminator(username, discriminator
 
 // GetUserByID retorna um usuário pelo ID
 func (db *CassandraDB) GetUserByID(userID string) (map[string]interface{}, error) {
-	query := `SELECT user_id, email, username, discriminator, display_name, avatar_url, bio, created_at FROM nexus.users WHERE user_id = ?`
+	query := `SELECT user_id, email, username, discriminator, display_name, avatar_url, bio, custom_status, created_at FROM nexus.users WHERE user_id = ?`
 
 	userUUID, err := gocql.ParseUUID(userID)
 	if err != nil {
@@ -365,10 +589,10 @@ func (db *CassandraDB) GetUserByID(userID string) (map[string]interface{}, error
 	}
 
 	var uid gocql.UUID
-	var userEmail, username, discriminator, displayName, avatarURL, bio string
+	var userEmail, username, discriminator, displayName, avatarURL, bio, customStatus string
 	var createdAt time.Time
 
-	err = db.session.Query(query, userUUID).Scan(&uid, &userEmail, &username, &discriminator, &displayName, &avatarURL, &bio, &createdAt)
+	err = db.session.Query(query, userUUID).Scan(&uid, &userEmail, &username, &discriminator, &displayName, &avatarURL, &bio, &customStatus, &createdAt)
 	if err != nil {
 		return nil, err
 	}
@@ -381,6 +605,7 @@ func (db *CassandraDB) GetUserByID(userID string) (map[string]interface{}, error
 		"display_name":  displayName,
 		"avatar_url":    avatarURL,
 		"bio":           bio,
+		"custom_status": customStatus,
 		"created_at":    createdAt,
 	}
 
@@ -429,13 +654,14 @@ func (db *CassandraDB) GetAllChannels() ([]map[string]interface{}, error) {
 
 // GetChannelByID retorna um canal específico
 func (db *CassandraDB) GetChannelByID(channelID string) (map[string]interface{}, error) {
-	query := `SELECT channel_id, name, type, owner_id, description, server_id, created_at, updated_at FROM nexus.channels WHERE channel_id = ?`
+	query := `SELECT channel_id, name, type, owner_id, description, server_id, message_ttl, slowmode, created_at, updated_at FROM nexus.channels WHERE channel_id = ?`
 
 	var chID, ownerID, serverID gocql.UUID
 	var name, channelType, description string
+	var messageTTL, slowmode int
 	var createdAt, updatedAt time.Time
 
-	err := db.session.Query(query, channelID).Scan(&chID, &name, &channelType, &ownerID, &description, &serverID, &createdAt, &updatedAt)
+	err := db.session.Query(query, channelID).Scan(&chID, &name, &channelType, &ownerID, &description, &serverID, &messageTTL, &slowmode, &createdAt, &updatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -447,6 +673,8 @@ func (db *CassandraDB) GetChannelByID(channelID string) (map[string]interface{},
 		"owner_id":    ownerID.String(),
 		"description": description,
 		"server_id":   serverID.String(),
+		"message_ttl": messageTTL,
+		"slowmode":    slowmode,
 		"created_at":  createdAt,
 		"updated_at":  updatedAt,
 	}
@@ -460,13 +688,94 @@ func (db *CassandraDB) DeleteChannel(channelID string) error {
 	return db.session.Query(query, channelID).Exec()
 }
 
-// SaveMessage salva uma mensagem no Cassandra
-func (db *CassandraDB) SaveMessage(channelID, messageID, authorID, content string) error {
-	// Bucket baseado no mês para particionar dados (YYYYMM)
-	bucket := time.Now().Year()*100 + int(time.Now().Month())
+// messageBucket retorna o bucket mensal (YYYYMM) usado para particionar mensagens
+func messageBucket(t time.Time) int {
+	return t.Year()*100 + int(t.Month())
+}
 
-	query := `INSERT INTO nexus.messages_by_channel (channel_id, bucket, ts, msg_id, author_id, content) 
-	          VALUES (?, ?, ?, ?, ?, ?)`
+// messageColumns são as colunas lidas de messages_by_channel, na ordem esperada por messageScan
+const messageColumns = `channel_id, ts, msg_id, author_id, content, type, embeds, webhook_id, author_name, author_avatar, reference, edited_at, TTL(content)`
+
+// messageScan recebe uma linha de messages_by_channel lida com messageColumns
+type messageScan struct {
+	channelID, msgID, authorID gocql.UUID
+	ts                         time.Time
+	content, messageType       string
+	embeds                     string
+	webhookID                  *gocql.UUID
+	authorName, authorAvatar   string
+	reference                  string
+	editedAt                   *time.Time
+	ttl                        *int
+}
+
+// dest retorna os destinos de Scan na ordem de messageColumns
+func (ms *messageScan) dest() []interface{} {
+	return []interface{}{&ms.channelID, &ms.ts, &ms.msgID, &ms.authorID, &ms.content, &ms.messageType,
+		&ms.embeds, &ms.webhookID, &ms.authorName, &ms.authorAvatar, &ms.reference, &ms.editedAt, &ms.ttl}
+}
+
+// row converte a linha lida no formato de mapa usado pelos handlers
+func (ms *messageScan) row(now time.Time) map[string]interface{} {
+	row := map[string]interface{}{
+		"channel_id": ms.channelID.String(),
+		"msg_id":     ms.msgID.String(),
+		"author_id":  ms.authorID.String(),
+		"content":    ms.content,
+		"type":       ms.messageType,
+		"embeds":     ms.embeds, // JSON ([]models.Embed) ou vazio
+		"ts":         ms.ts,
+	}
+
+	// Mensagens de webhook: nome e avatar exibidos no lugar do usuário
+	if ms.webhookID != nil {
+		row["webhook_id"] = ms.webhookID.String()
+		row["author_name"] = ms.authorName
+		row["author_avatar"] = ms.authorAvatar
+	}
+
+	// Mensagens encaminhadas: cópia da mensagem original (JSON)
+	if ms.reference != "" {
+		row["reference"] = ms.reference
+	}
+
+	if ms.editedAt != nil {
+		row["edited_at"] = *ms.editedAt
+	}
+
+	// TTL restante em segundos; nulo para mensagens permanentes
+	if ms.ttl != nil && *ms.ttl > 0 {
+		row["expires_at"] = now.Add(time.Duration(*ms.ttl) * time.Second)
+	}
+
+	return row
+}
+
+// SaveMessage salva uma mensagem no Cassandra.
+// messageID deve ser um TimeUUID: o timestamp e o bucket da mensagem são derivados dele.
+// messageType é vazio para mensagens comuns ou identifica mensagens especiais (ex.: "poll").
+// embeds é o JSON dos embeds da mensagem (vazio se não houver).
+// ttl (em segundos) aplica USING TTL à linha; 0 mantém a mensagem indefinidamente.
+func (db *CassandraDB) SaveMessage(channelID, messageID, authorID, content, messageType, embeds string, ttl int) error {
+	return db.insertMessage(channelID, messageID, authorID, content, messageType, embeds, "", "", "", ttl)
+}
+
+// SaveForwardedMessage salva uma mensagem que encaminha outra.
+// reference é o JSON da cópia da mensagem original (canal, autor, conteúdo e embeds).
+func (db *CassandraDB) SaveForwardedMessage(channelID, messageID, authorID, content, reference string, ttl int) error {
+	return db.insertMessage(channelID, messageID, authorID, content, "forward", "", "", "", reference, ttl)
+}
+
+// SaveWebhookMessage salva uma mensagem postada por um webhook.
+// O author_id é o ID do webhook; username e avatarURL são os exibidos nesta mensagem.
+func (db *CassandraDB) SaveWebhookMessage(channelID, messageID, webhookID, username, avatarURL, content, embeds string, ttl int) error {
+	return db.insertMessage(channelID, messageID, webhookID, content, "", embeds, username, avatarURL, "", ttl)
+}
+
+// insertMessage grava a linha em messages_by_channel. authorName != "" marca mensagem de webhook
+func (db *CassandraDB) insertMessage(channelID, messageID, authorID, content, messageType, embeds, authorName, authorAvatar, reference string, ttl int) error {
+	query := `INSERT INTO nexus.messages_by_channel (channel_id, bucket, ts, msg_id, author_id, content, type, embeds, webhook_id, author_name, author_avatar, reference) 
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`
 
 	// Converter string UUID para gocql.UUID
 	channelUUID, err := gocql.ParseUUID(channelID)
@@ -481,18 +790,144 @@ func (db *CassandraDB) SaveMessage(channelID, messageID, authorID, content strin
 		return err
 	}
 
-	log.Printf("DEBUG SaveMessage: channelID=%s, authorID=%s, authorUUID=%s", channelID, authorID, authorUUID.String())
+	msgTimeUUID, err := gocql.ParseUUID(messageID)
+	if err != nil {
+		log.Printf("ERROR: Failed to parse messageID: %s, error: %v", messageID, err)
+		return err
+	}
+
+	ts := msgTimeUUID.Time()
 
-	// Gerar TimeUUID para a mensagem
-	msgTimeUUID := gocql.TimeUUID()
+	// Colunas opcionais ficam nulas em vez de strings vazias
+	var typeValue, embedsValue, nameValue, avatarValue, referenceValue *string
+	var webhookUUID *gocql.UUID
+	if messageType != "" {
+		typeValue = &messageType
+	}
+	if embeds != "" {
+		embedsValue = &embeds
+	}
+	if reference != "" {
+		referenceValue = &reference
+	}
+	if authorName != "" {
+		webhookUUID = &authorUUID
+		nameValue = &authorName
+		avatarValue = &authorAvatar
+	}
 
-	return db.session.Query(query, channelUUID, bucket, time.Now(), msgTimeUUID, authorUUID, content).Exec()
+	return db.session.Query(query, channelUUID, messageBucket(ts), ts, msgTimeUUID, authorUUID, content,
+		typeValue, embedsValue, webhookUUID, nameValue, avatarValue, referenceValue, ttl).Exec()
+}
+
+// GetMessage retorna uma mensagem específica. O bucket é derivado do TimeUUID da mensagem
+func (db *CassandraDB) GetMessage(channelID, messageID string) (map[string]interface{}, error) {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	msgUUID, err := gocql.ParseUUID(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT ` + messageColumns + ` 
+	          FROM nexus.messages_by_channel 
+	          WHERE channel_id = ? AND bucket = ? AND msg_id = ?
+	          ALLOW FILTERING`
+
+	var scan messageScan
+	err = db.session.Query(query, channelUUID, messageBucket(msgUUID.Time()), msgUUID).Scan(scan.dest()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return scan.row(time.Now()), nil
+}
+
+// ReserveMessageNonce registra o nonce de envio de um autor (LWT com TTL = window segundos).
+// Se o nonce já foi usado dentro da janela, retorna applied=false e a mensagem original.
+func (db *CassandraDB) ReserveMessageNonce(authorID, nonce, channelID, messageID string, window int) (bool, string, string, error) {
+	query := `INSERT INTO nexus.message_nonces (author_id, nonce, channel_id, msg_id) 
+	          VALUES (?, ?, ?, ?) IF NOT EXISTS USING TTL ?`
+
+	authorUUID, err := gocql.ParseUUID(authorID)
+	if err != nil {
+		return false, "", "", err
+	}
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return false, "", "", err
+	}
+	msgUUID, err := gocql.ParseUUID(messageID)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	existing := make(map[string]interface{})
+	applied, err := db.session.Query(query, authorUUID, nonce, channelUUID, msgUUID, window).MapScanCAS(existing)
+	if err != nil {
+		return false, "", "", err
+	}
+	if applied {
+		return true, "", "", nil
+	}
+
+	var existingChannelID, existingMessageID string
+	if id, ok := existing["channel_id"].(gocql.UUID); ok {
+		existingChannelID = id.String()
+	}
+	if id, ok := existing["msg_id"].(gocql.UUID); ok {
+		existingMessageID = id.String()
+	}
+
+	return false, existingChannelID, existingMessageID, nil
+}
+
+// ReleaseMessageNonce remove a reserva de um nonce (usado quando o envio falha)
+func (db *CassandraDB) ReleaseMessageNonce(authorID, nonce string) error {
+	query := `DELETE FROM nexus.message_nonces WHERE author_id = ? AND nonce = ?`
+
+	authorUUID, err := gocql.ParseUUID(authorID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, authorUUID, nonce).Exec()
+}
+
+// ReserveSlowmode registra o envio de um usuário em um canal com slowmode (LWT com TTL =
+// slowmode), compartilhado por todas as réplicas. Se já houver um envio dentro do intervalo,
+// retorna applied=false e quando ele foi feito.
+func (db *CassandraDB) ReserveSlowmode(channelID, userID string, slowmode time.Duration) (bool, time.Time, error) {
+	query := `INSERT INTO nexus.slowmode_sends (channel_id, user_id, sent_at) 
+	          VALUES (?, ?, ?) IF NOT EXISTS USING TTL ?`
+
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return false, time.Time{}, err
+	}
+
+	ttl := int(math.Ceil(slowmode.Seconds()))
+	existing := make(map[string]interface{})
+	applied, err := db.session.Query(query, channelUUID, userUUID, time.Now(), ttl).MapScanCAS(existing)
+	if err != nil || applied {
+		return applied, time.Time{}, err
+	}
+
+	sentAt, _ := existing["sent_at"].(time.Time)
+	return false, sentAt, nil
 }
 
 // GetMessagesByChannel retorna mensagens de um canal com paginação
 func (db *CassandraDB) GetMessagesByChannel(channelID string, limit int, beforeTime *time.Time) ([]map[string]interface{}, error) {
 	// Bucket do mês atual
-	bucket := time.Now().Year()*100 + int(time.Now().Month())
+	bucket := messageBucket(time.Now())
 
 	channelUUID, err := gocql.ParseUUID(channelID)
 	if err != nil {
@@ -504,14 +939,14 @@ func (db *CassandraDB) GetMessagesByChannel(channelID string, limit int, beforeT
 
 	if beforeTime != nil {
 		// Paginação: buscar mensagens antes de um timestamp
-		query = `SELECT channel_id, ts, msg_id, author_id, content, edited_at 
+		query = `SELECT ` + messageColumns + ` 
 		         FROM nexus.messages_by_channel 
 		         WHERE channel_id = ? AND bucket = ? AND ts < ?
 		         ORDER BY ts DESC LIMIT ?`
 		iter = db.session.Query(query, channelUUID, bucket, *beforeTime, limit).Iter()
 	} else {
 		// Primeira página: buscar as mensagens mais recentes
-		query = `SELECT channel_id, ts, msg_id, author_id, content, edited_at 
+		query = `SELECT ` + messageColumns + ` 
 		         FROM nexus.messages_by_channel 
 		         WHERE channel_id = ? AND bucket = ?
 		         ORDER BY ts DESC LIMIT ?`
@@ -520,25 +955,11 @@ func (db *CassandraDB) GetMessagesByChannel(channelID string, limit int, beforeT
 	defer iter.Close()
 
 	var results []map[string]interface{}
-	var chID, authorID, msgID gocql.UUID
-	var ts time.Time
-	var content string
-	var editedAt *time.Time
-
-	for iter.Scan(&chID, &ts, &msgID, &authorID, &content, &editedAt) {
-		row := map[string]interface{}{
-			"channel_id": chID.String(),
-			"msg_id":     msgID.String(),
-			"author_id":  authorID.String(),
-			"content":    content,
-			"ts":         ts,
-		}
-
-		if editedAt != nil {
-			row["edited_at"] = *editedAt
-		}
+	var scan messageScan
 
-		results = append(results, row)
+	now := time.Now()
+	for iter.Scan(scan.dest()...) {
+		results = append(results, scan.row(now))
 	}
 
 	if err := iter.Close(); err != nil {
@@ -548,10 +969,56 @@ func (db *CassandraDB) GetMessagesByChannel(channelID string, limit int, beforeT
 	return results, nil
 }
 
+// ErrStopIteration pode ser retornado pelo callback de IterateBucketMessages para encerrar a iteração
+var ErrStopIteration = errors.New("stop iteration")
+
+// MessageBuckets retorna os buckets mensais que cobrem o intervalo [from, to], em ordem crescente
+func MessageBuckets(from, to time.Time) []int {
+	var buckets []int
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, from.Location())
+	for !month.After(to) {
+		buckets = append(buckets, messageBucket(month))
+		month = month.AddDate(0, 1, 0)
+	}
+	return buckets
+}
+
+// IterateBucketMessages percorre as mensagens de um bucket no intervalo [from, to], paginando no Cassandra.
+// A ordem é cronológica, ou da mais recente para a mais antiga com newestFirst.
+// A iteração para no primeiro erro retornado por fn (ErrStopIteration encerra sem erro).
+func (db *CassandraDB) IterateBucketMessages(channelID string, bucket int, from, to time.Time, newestFirst bool, fn func(row map[string]interface{}) error) error {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+
+	order := "ASC"
+	if newestFirst {
+		order = "DESC"
+	}
+
+	query := `SELECT ` + messageColumns + `
+	          FROM nexus.messages_by_channel
+	          WHERE channel_id = ? AND bucket = ? AND ts >= ? AND ts <= ?
+	          ORDER BY ts ` + order
+
+	iter := db.session.Query(query, channelUUID, bucket, from, to).PageSize(500).Iter()
+	defer iter.Close()
+
+	var scan messageScan
+	for iter.Scan(scan.dest()...) {
+		if err := fn(scan.row(time.Now())); err == ErrStopIteration {
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return iter.Close()
+}
+
 // UpdateMessage atualiza o conteúdo de uma mensagem
 func (db *CassandraDB) UpdateMessage(channelID, messageID, newContent string) error {
-	bucket := time.Now().Year()*100 + int(time.Now().Month())
-
 	channelUUID, err := gocql.ParseUUID(channelID)
 	if err != nil {
 		return err
@@ -562,30 +1029,79 @@ func (db *CassandraDB) UpdateMessage(channelID, messageID, newContent string) er
 		return err
 	}
 
-	// Buscar a mensagem para obter o ts
-	selectQuery := `SELECT ts FROM nexus.messages_by_channel 
+	// O bucket é derivado do TimeUUID da mensagem
+	bucket := messageBucket(msgUUID.Time())
+
+	// Buscar a mensagem para obter o ts e o TTL restante
+	selectQuery := `SELECT ts, TTL(content) FROM nexus.messages_by_channel 
 	                WHERE channel_id = ? AND bucket = ? AND msg_id = ?
 	                ALLOW FILTERING`
 
 	var ts time.Time
-	err = db.session.Query(selectQuery, channelUUID, bucket, msgUUID).Scan(&ts)
+	var ttl *int
+	err = db.session.Query(selectQuery, channelUUID, bucket, msgUUID).Scan(&ts, &ttl)
 	if err != nil {
 		return err
 	}
 
+	// Preservar o TTL de mensagens efêmeras, senão o conteúdo editado sobreviveria à expiração
+	remainingTTL := 0
+	if ttl != nil {
+		remainingTTL = *ttl
+	}
+
 	// Atualizar com todas as chaves primárias
-	updateQuery := `UPDATE nexus.messages_by_channel 
+	updateQuery := `UPDATE nexus.messages_by_channel USING TTL ?
 	                SET content = ?, edited_at = ?
 	                WHERE channel_id = ? AND bucket = ? AND ts = ? AND msg_id = ?`
 
-	return db.session.Query(updateQuery, newContent, time.Now(), channelUUID, bucket, ts, msgUUID).Exec()
+	return db.session.Query(updateQuery, remainingTTL, newContent, time.Now(), channelUUID, bucket, ts, msgUUID).Exec()
+}
+
+// UpdateMessageEmbeds substitui os embeds de uma mensagem (JSON, vazio para remover), preservando o TTL.
+// Retorna gocql.ErrNotFound se a mensagem não existir mais
+func (db *CassandraDB) UpdateMessageEmbeds(channelID, messageID, embeds string) error {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+
+	msgUUID, err := gocql.ParseUUID(messageID)
+	if err != nil {
+		return err
+	}
+
+	bucket := messageBucket(msgUUID.Time())
+
+	selectQuery := `SELECT ts, TTL(content) FROM nexus.messages_by_channel 
+	                WHERE channel_id = ? AND bucket = ? AND msg_id = ?
+	                ALLOW FILTERING`
+
+	var ts time.Time
+	var ttl *int
+	if err := db.session.Query(selectQuery, channelUUID, bucket, msgUUID).Scan(&ts, &ttl); err != nil {
+		return err
+	}
+
+	remainingTTL := 0
+	if ttl != nil {
+		remainingTTL = *ttl
+	}
+
+	var embedsValue *string
+	if embeds != "" {
+		embedsValue = &embeds
+	}
+
+	updateQuery := `UPDATE nexus.messages_by_channel USING TTL ?
+	                SET embeds = ?
+	                WHERE channel_id = ? AND bucket = ? AND ts = ? AND msg_id = ?`
+
+	return db.session.Query(updateQuery, remainingTTL, embedsValue, channelUUID, bucket, ts, msgUUID).Exec()
 }
 
 // DeleteMessage deleta uma mensagem
 func (db *CassandraDB) DeleteMessage(channelID, messageID string) error {
-	// Primeiro, buscar a mensagem para obter o timestamp
-	bucket := time.Now().Year()*100 + int(time.Now().Month())
-
 	channelUUID, err := gocql.ParseUUID(channelID)
 	if err != nil {
 		return err
@@ -596,6 +1112,9 @@ func (db *CassandraDB) DeleteMessage(channelID, messageID string) error {
 		return err
 	}
 
+	// O bucket é derivado do TimeUUID da mensagem
+	bucket := messageBucket(msgUUID.Time())
+
 	// Buscar a mensagem para obter o ts
 	selectQuery := `SELECT ts FROM nexus.messages_by_channel 
 	                WHERE channel_id = ? AND bucket = ? AND msg_id = ?
@@ -614,6 +1133,24 @@ func (db *CassandraDB) DeleteMessage(channelID, messageID string) error {
 	return db.session.Query(deleteQuery, channelUUID, bucket, ts, msgUUID).Exec()
 }
 
+// DeleteMessageByKey deleta uma mensagem cujo ts já é conhecido (evita a leitura feita por DeleteMessage)
+func (db *CassandraDB) DeleteMessageByKey(channelID string, ts time.Time, messageID string) error {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+
+	msgUUID, err := gocql.ParseUUID(messageID)
+	if err != nil {
+		return err
+	}
+
+	query := `DELETE FROM nexus.messages_by_channel 
+	          WHERE channel_id = ? AND bucket = ? AND ts = ? AND msg_id = ?`
+
+	return db.session.Query(query, channelUUID, messageBucket(msgUUID.Time()), ts, msgUUID).Exec()
+}
+
 // CreateTask cria uma nova task
 func (db *CassandraDB) CreateTask(channelID, taskID, title, status, assigneeID, columnID, priority string, labels []string, dueDate *time.Time, position int) error {
 	query := `INSERT INTO nexus.tasks_by_channel (channel_id, task_id, title, status, assignee, column_id, priority, labels, due_date, position, created_at, updated_at)
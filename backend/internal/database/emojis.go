@@ -0,0 +1,162 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ==================== EMOJIS DOS SERVIDORES ====================
+
+// CreateEmoji registra um emoji no servidor. O nome é único por servidor:
+// retorna false se já existir um emoji com o mesmo nome
+func (db *CassandraDB) CreateEmoji(emojiID, serverID, name, filename, createdBy string) (bool, error) {
+	emojiUUID, err := gocql.ParseUUID(emojiID)
+	if err != nil {
+		return false, err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return false, err
+	}
+	creatorUUID, err := gocql.ParseUUID(createdBy)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO nexus.server_emojis (server_id, name, emoji_id, filename, created_by, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?) IF NOT EXISTS`
+
+	existing := map[string]interface{}{}
+	applied, err := db.session.Query(query, serverUUID, name, emojiUUID, filename, creatorUUID, now).MapScanCAS(existing)
+	if err != nil || !applied {
+		return false, err
+	}
+
+	byIDQuery := `INSERT INTO nexus.emojis (emoji_id, server_id, name, filename, created_by, created_at)
+	              VALUES (?, ?, ?, ?, ?, ?)`
+	if err := db.session.Query(byIDQuery, emojiUUID, serverUUID, name, filename, creatorUUID, now).Exec(); err != nil {
+		// Não deixar o nome reservado sem o emoji correspondente
+		_ = db.session.Query(`DELETE FROM nexus.server_emojis WHERE server_id = ? AND name = ?`, serverUUID, name).Exec()
+		return false, err
+	}
+
+	return true, nil
+}
+
+// GetEmoji retorna um emoji pelo ID
+func (db *CassandraDB) GetEmoji(emojiID string) (map[string]interface{}, error) {
+	emojiUUID, err := gocql.ParseUUID(emojiID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT emoji_id, server_id, name, filename, created_by, created_at FROM nexus.emojis WHERE emoji_id = ?`
+
+	var id, serverID, createdBy gocql.UUID
+	var name, filename string
+	var createdAt time.Time
+
+	if err := db.session.Query(query, emojiUUID).Scan(&id, &serverID, &name, &filename, &createdBy, &createdAt); err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"emoji_id":   id.String(),
+		"server_id":  serverID.String(),
+		"name":       name,
+		"filename":   filename,
+		"created_by": createdBy.String(),
+		"created_at": createdAt,
+	}, nil
+}
+
+// GetServerEmojis retorna os emojis de um servidor, ordenados pelo nome
+func (db *CassandraDB) GetServerEmojis(serverID string) ([]map[string]interface{}, error) {
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT name, emoji_id, filename, created_by, created_at FROM nexus.server_emojis WHERE server_id = ?`
+
+	iter := db.session.Query(query, serverUUID).Iter()
+	defer iter.Close()
+
+	var results []map[string]interface{}
+	var emojiID, createdBy gocql.UUID
+	var name, filename string
+	var createdAt time.Time
+
+	for iter.Scan(&name, &emojiID, &filename, &createdBy, &createdAt) {
+		results = append(results, map[string]interface{}{
+			"emoji_id":   emojiID.String(),
+			"server_id":  serverID,
+			"name":       name,
+			"filename":   filename,
+			"created_by": createdBy.String(),
+			"created_at": createdAt,
+		})
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// RenameEmoji troca o nome de um emoji. Retorna false se o novo nome já estiver em uso no servidor
+func (db *CassandraDB) RenameEmoji(emojiID, serverID, oldName, newName string) (bool, error) {
+	emojiUUID, err := gocql.ParseUUID(emojiID)
+	if err != nil {
+		return false, err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return false, err
+	}
+
+	var filename string
+	var createdBy gocql.UUID
+	var createdAt time.Time
+	err = db.session.Query(`SELECT filename, created_by, created_at FROM nexus.server_emojis WHERE server_id = ? AND name = ?`,
+		serverUUID, oldName).Scan(&filename, &createdBy, &createdAt)
+	if err != nil {
+		return false, err
+	}
+
+	// Reservar o novo nome antes de liberar o antigo
+	query := `INSERT INTO nexus.server_emojis (server_id, name, emoji_id, filename, created_by, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?) IF NOT EXISTS`
+	existing := map[string]interface{}{}
+	applied, err := db.session.Query(query, serverUUID, newName, emojiUUID, filename, createdBy, createdAt).MapScanCAS(existing)
+	if err != nil || !applied {
+		return false, err
+	}
+
+	if err := db.session.Query(`DELETE FROM nexus.server_emojis WHERE server_id = ? AND name = ?`, serverUUID, oldName).Exec(); err != nil {
+		return false, err
+	}
+
+	return true, db.session.Query(`UPDATE nexus.emojis SET name = ? WHERE emoji_id = ?`, newName, emojiUUID).Exec()
+}
+
+// DeleteEmoji remove um emoji do servidor
+func (db *CassandraDB) DeleteEmoji(emojiID, serverID, name string) error {
+	emojiUUID, err := gocql.ParseUUID(emojiID)
+	if err != nil {
+		return err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.session.Query(`DELETE FROM nexus.server_emojis WHERE server_id = ? AND name = ?`, serverUUID, name).Exec(); err != nil {
+		return err
+	}
+
+	return db.session.Query(`DELETE FROM nexus.emojis WHERE emoji_id = ?`, emojiUUID).Exec()
+}
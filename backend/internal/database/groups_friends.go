@@ -528,6 +528,80 @@ func (db *CassandraDB) UpdateChannel(channelID, name, description string) error
 	return db.session.Query(query, name, description, channelUUID).Exec()
 }
 
+// UpdateChannelMessageTTL define o TTL padrão (em segundos) das mensagens de um canal; 0 desativa
+func (db *CassandraDB) UpdateChannelMessageTTL(channelID string, ttl int) error {
+	query := `UPDATE nexus.channels SET message_ttl = ? WHERE channel_id = ?`
+
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+	return db.session.Query(query, ttl, channelUUID).Exec()
+}
+
+// UpdateChannelSlowmode define o intervalo mínimo (em segundos) entre mensagens de um usuário no canal; 0 desativa
+func (db *CassandraDB) UpdateChannelSlowmode(channelID string, seconds int) error {
+	query := `UPDATE nexus.channels SET slowmode = ? WHERE channel_id = ?`
+
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+	return db.session.Query(query, seconds, channelUUID).Exec()
+}
+
+// IsChannelModerator verifica se o usuário é owner, admin ou moderador do servidor do canal
+func (db *CassandraDB) IsChannelModerator(channelID, userID string) (bool, error) {
+	channelRow, err := db.GetChannelByID(channelID)
+	if err != nil {
+		return false, err
+	}
+
+	serverID, ok := channelRow["server_id"].(string)
+	if !ok || serverID == "" || serverID == (gocql.UUID{}).String() {
+		// Canais sem servidor (DMs) não têm moderadores
+		return false, nil
+	}
+
+	role, err := db.GetServerRole(serverID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return role == "owner" || role == "admin" || role == "moderator", nil
+}
+
+// GetServerRole retorna o papel efetivo do usuário no servidor ("owner" para o dono, "" se não for membro)
+func (db *CassandraDB) GetServerRole(serverID, userID string) (string, error) {
+	serverRow, err := db.GetGroupByID(serverID)
+	if err != nil {
+		return "", err
+	}
+	if ownerID, ok := serverRow["owner_id"].(string); ok && ownerID == userID {
+		return "owner", nil
+	}
+
+	role, err := db.GetGroupMemberRole(serverID, userID)
+	if err == gocql.ErrNotFound {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return role, nil
+}
+
+// IsServerAdmin verifica se o usuário é dono ou admin do servidor
+func (db *CassandraDB) IsServerAdmin(serverID, userID string) (bool, error) {
+	role, err := db.GetServerRole(serverID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return role == "owner" || role == "admin", nil
+}
+
 // GetChannelMembers retorna membros de um canal
 func (db *CassandraDB) GetChannelMembers(channelID string) ([]map[string]interface{}, error) {
 	query := `SELECT user_id, role, joined_at FROM nexus.channel_members WHERE channel_id = ?`
@@ -630,3 +704,124 @@ func (db *CassandraDB) IsServerMember(serverID, userID string) (bool, error) {
 	}
 	return true, nil
 }
+
+// IsChannelMember verifica se o usuário participa de um canal de DM ou grupo
+func (db *CassandraDB) IsChannelMember(channelID, userID string) (bool, error) {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return false, err
+	}
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return false, err
+	}
+
+	query := `SELECT user_id FROM nexus.channel_members WHERE channel_id = ? AND user_id = ?`
+
+	var foundUserID gocql.UUID
+	err = db.session.Query(query, channelUUID, userUUID).Scan(&foundUserID)
+	if err == gocql.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AddChannelMember adiciona um participante a um canal fora de servidores
+func (db *CassandraDB) AddChannelMember(channelID, userID, role string) error {
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO nexus.channel_members (channel_id, user_id, role, joined_at) VALUES (?, ?, ?, ?)`
+	return db.session.Query(query, channelUUID, userUUID, role, time.Now()).Exec()
+}
+
+// GetUserGroupIDs retorna os IDs dos servidores e grupos dos quais o usuário é membro
+func (db *CassandraDB) GetUserGroupIDs(userID string) ([]string, error) {
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(`SELECT group_id FROM nexus.group_members WHERE user_id = ?`, userUUID).Iter()
+	defer iter.Close()
+
+	var ids []string
+	var groupID gocql.UUID
+	for iter.Scan(&groupID) {
+		ids = append(ids, groupID.String())
+	}
+
+	return ids, iter.Close()
+}
+
+// GetGroupMemberIDs retorna os IDs dos membros de um servidor ou grupo
+func (db *CassandraDB) GetGroupMemberIDs(groupID string) ([]string, error) {
+	groupUUID, err := gocql.ParseUUID(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(`SELECT user_id FROM nexus.group_members WHERE group_id = ?`, groupUUID).Iter()
+	defer iter.Close()
+
+	var ids []string
+	var userID gocql.UUID
+	for iter.Scan(&userID) {
+		ids = append(ids, userID.String())
+	}
+
+	return ids, iter.Close()
+}
+
+// GetGroupMembers retorna os membros de um servidor ou grupo (user_id, role, joined_at)
+func (db *CassandraDB) GetGroupMembers(groupID string) ([]map[string]interface{}, error) {
+	groupUUID, err := gocql.ParseUUID(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(`SELECT user_id, role, joined_at FROM nexus.group_members WHERE group_id = ?`, groupUUID).Iter()
+	defer iter.Close()
+
+	var results []map[string]interface{}
+	var userID gocql.UUID
+	var role string
+	var joinedAt time.Time
+	for iter.Scan(&userID, &role, &joinedAt) {
+		results = append(results, map[string]interface{}{
+			"user_id":   userID.String(),
+			"role":      role,
+			"joined_at": joinedAt,
+		})
+	}
+
+	return results, iter.Close()
+}
+
+// GetUserChannelIDs retorna os IDs dos canais (DMs, grupos privados) dos quais o usuário participa
+func (db *CassandraDB) GetUserChannelIDs(userID string) ([]string, error) {
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(`SELECT channel_id FROM nexus.channel_members WHERE user_id = ?`, userUUID).Iter()
+	defer iter.Close()
+
+	var ids []string
+	var channelID gocql.UUID
+	for iter.Scan(&channelID) {
+		ids = append(ids, channelID.String())
+	}
+
+	return ids, iter.Close()
+}
@@ -0,0 +1,213 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ==================== WEBHOOKS DE SAÍDA ====================
+
+// CreateOutgoingWebhook registra um endpoint que recebe eventos de um servidor
+func (db *CassandraDB) CreateOutgoingWebhook(endpointID, serverID, url, secret string, events []string, createdBy string) error {
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return err
+	}
+	creatorUUID, err := gocql.ParseUUID(createdBy)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO nexus.outgoing_webhooks (endpoint_id, server_id, url, secret, events, active, created_by, created_at, updated_at)
+	          VALUES (?, ?, ?, ?, ?, true, ?, ?, ?)`
+	if err := db.session.Query(query, endpointUUID, serverUUID, url, secret, events, creatorUUID, now, now).Exec(); err != nil {
+		return err
+	}
+
+	indexQuery := `INSERT INTO nexus.outgoing_webhooks_by_server (server_id, endpoint_id) VALUES (?, ?)`
+	return db.session.Query(indexQuery, serverUUID, endpointUUID).Exec()
+}
+
+// GetOutgoingWebhook retorna um endpoint de webhook de saída (incluindo o segredo)
+func (db *CassandraDB) GetOutgoingWebhook(endpointID string) (map[string]interface{}, error) {
+	query := `SELECT endpoint_id, server_id, url, secret, events, active, created_by, created_at, updated_at
+	          FROM nexus.outgoing_webhooks WHERE endpoint_id = ?`
+
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	var id, serverID, createdBy gocql.UUID
+	var url, secret string
+	var events []string
+	var active bool
+	var createdAt, updatedAt time.Time
+
+	err = db.session.Query(query, endpointUUID).Scan(&id, &serverID, &url, &secret, &events, &active, &createdBy, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if events == nil {
+		events = []string{}
+	}
+
+	return map[string]interface{}{
+		"endpoint_id": id.String(),
+		"server_id":   serverID.String(),
+		"url":         url,
+		"secret":      secret,
+		"events":      events,
+		"active":      active,
+		"created_by":  createdBy.String(),
+		"created_at":  createdAt,
+		"updated_at":  updatedAt,
+	}, nil
+}
+
+// GetServerOutgoingWebhooks retorna os endpoints de webhook de saída de um servidor
+func (db *CassandraDB) GetServerOutgoingWebhooks(serverID string) ([]map[string]interface{}, error) {
+	query := `SELECT endpoint_id FROM nexus.outgoing_webhooks_by_server WHERE server_id = ?`
+
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(query, serverUUID).Iter()
+	defer iter.Close()
+
+	var endpointIDs []string
+	var endpointID gocql.UUID
+	for iter.Scan(&endpointID) {
+		endpointIDs = append(endpointIDs, endpointID.String())
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for _, id := range endpointIDs {
+		row, err := db.GetOutgoingWebhook(id)
+		if err == gocql.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
+// UpdateOutgoingWebhook atualiza URL, eventos assinados e estado de um endpoint
+func (db *CassandraDB) UpdateOutgoingWebhook(endpointID, url string, events []string, active bool) error {
+	query := `UPDATE nexus.outgoing_webhooks SET url = ?, events = ?, active = ?, updated_at = ? WHERE endpoint_id = ?`
+
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, url, events, active, time.Now(), endpointUUID).Exec()
+}
+
+// UpdateOutgoingWebhookSecret substitui o segredo usado para assinar as entregas
+func (db *CassandraDB) UpdateOutgoingWebhookSecret(endpointID, secret string) error {
+	query := `UPDATE nexus.outgoing_webhooks SET secret = ?, updated_at = ? WHERE endpoint_id = ?`
+
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, secret, time.Now(), endpointUUID).Exec()
+}
+
+// DeleteOutgoingWebhook remove um endpoint e seu histórico de entregas
+func (db *CassandraDB) DeleteOutgoingWebhook(endpointID, serverID string) error {
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.session.Query(`DELETE FROM nexus.outgoing_webhooks WHERE endpoint_id = ?`, endpointUUID).Exec(); err != nil {
+		return err
+	}
+	if err := db.session.Query(`DELETE FROM nexus.outgoing_webhooks_by_server WHERE server_id = ? AND endpoint_id = ?`, serverUUID, endpointUUID).Exec(); err != nil {
+		return err
+	}
+
+	return db.session.Query(`DELETE FROM nexus.webhook_deliveries WHERE endpoint_id = ?`, endpointUUID).Exec()
+}
+
+// SaveWebhookDelivery registra uma tentativa de entrega (expira após 7 dias)
+func (db *CassandraDB) SaveWebhookDelivery(endpointID, deliveryID string, attempt int, eventType, status string, statusCode int, errMsg string, duration time.Duration, requestBody, responseBody string) error {
+	query := `INSERT INTO nexus.webhook_deliveries (endpoint_id, delivery_id, attempt, event_type, status, status_code, error, duration_ms, request_body, response_body, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return err
+	}
+	deliveryUUID, err := gocql.ParseUUID(deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, endpointUUID, deliveryUUID, attempt, eventType, status, statusCode, errMsg,
+		int(duration.Milliseconds()), requestBody, responseBody, time.Now()).Exec()
+}
+
+// GetWebhookDeliveries retorna as tentativas de entrega mais recentes de um endpoint
+func (db *CassandraDB) GetWebhookDeliveries(endpointID string, limit int) ([]map[string]interface{}, error) {
+	query := `SELECT delivery_id, attempt, event_type, status, status_code, error, duration_ms, request_body, response_body, created_at
+	          FROM nexus.webhook_deliveries WHERE endpoint_id = ? LIMIT ?`
+
+	endpointUUID, err := gocql.ParseUUID(endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(query, endpointUUID, limit).Iter()
+	defer iter.Close()
+
+	var results []map[string]interface{}
+	var deliveryID gocql.UUID
+	var attempt, statusCode, durationMs int
+	var eventType, status, errMsg, requestBody, responseBody string
+	var createdAt time.Time
+
+	for iter.Scan(&deliveryID, &attempt, &eventType, &status, &statusCode, &errMsg, &durationMs, &requestBody, &responseBody, &createdAt) {
+		results = append(results, map[string]interface{}{
+			"delivery_id":   deliveryID.String(),
+			"attempt":       attempt,
+			"event_type":    eventType,
+			"status":        status,
+			"status_code":   statusCode,
+			"error":         errMsg,
+			"duration_ms":   durationMs,
+			"request_body":  requestBody,
+			"response_body": responseBody,
+			"created_at":    createdAt,
+		})
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ==================== ENQUETES ====================
+
+// CreatePoll salva a enquete de uma mensagem do tipo "poll".
+// pollID é o ID (TimeUUID) da mensagem; ttl acompanha o TTL da mensagem (0 = permanente).
+func (db *CassandraDB) CreatePoll(pollID, channelID, authorID, question string, options []string, multiSelect bool, expiresAt *time.Time, ttl int) error {
+	query := `INSERT INTO nexus.polls (poll_id, channel_id, author_id, question, options, multi_select, expires_at, created_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?) USING TTL ?`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return err
+	}
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+	authorUUID, err := gocql.ParseUUID(authorID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, pollUUID, channelUUID, authorUUID, question, options, multiSelect, expiresAt, time.Now(), ttl).Exec()
+}
+
+// GetPoll retorna uma enquete. Enquetes encerradas trazem closed_at e os resultados congelados
+func (db *CassandraDB) GetPoll(pollID string) (map[string]interface{}, error) {
+	query := `SELECT poll_id, channel_id, author_id, question, options, multi_select, expires_at, closed_at, results, TTL(question)
+	          FROM nexus.polls WHERE poll_id = ?`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	var pID, channelID, authorID gocql.UUID
+	var question string
+	var options []string
+	var multiSelect bool
+	var expiresAt, closedAt *time.Time
+	var results map[int]int
+	var ttl *int
+
+	err = db.session.Query(query, pollUUID).Scan(&pID, &channelID, &authorID, &question, &options, &multiSelect, &expiresAt, &closedAt, &results, &ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	row := map[string]interface{}{
+		"poll_id":      pID.String(),
+		"channel_id":   channelID.String(),
+		"author_id":    authorID.String(),
+		"question":     question,
+		"options":      options,
+		"multi_select": multiSelect,
+		"ttl":          0,
+	}
+
+	if expiresAt != nil {
+		row["expires_at"] = *expiresAt
+	}
+	if closedAt != nil {
+		row["closed_at"] = *closedAt
+		row["results"] = results
+	}
+	if ttl != nil {
+		row["ttl"] = *ttl
+	}
+
+	return row, nil
+}
+
+// SetPollVote registra o voto de um usuário, substituindo o anterior (um voto por usuário).
+// Em enquetes de múltipla escolha o voto contém várias opções.
+func (db *CassandraDB) SetPollVote(pollID, userID string, options []int, ttl int) error {
+	query := `INSERT INTO nexus.poll_votes (poll_id, user_id, options, voted_at) VALUES (?, ?, ?, ?) USING TTL ?`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return err
+	}
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, pollUUID, userUUID, options, time.Now(), ttl).Exec()
+}
+
+// DeletePollVote remove o voto de um usuário
+func (db *CassandraDB) DeletePollVote(pollID, userID string) error {
+	query := `DELETE FROM nexus.poll_votes WHERE poll_id = ? AND user_id = ?`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return err
+	}
+	userUUID, err := gocql.ParseUUID(userID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, pollUUID, userUUID).Exec()
+}
+
+// GetPollVotes retorna os votos de uma enquete (user_id -> opções escolhidas)
+func (db *CassandraDB) GetPollVotes(pollID string) (map[string][]int, error) {
+	query := `SELECT user_id, options FROM nexus.poll_votes WHERE poll_id = ?`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(query, pollUUID).Iter()
+	defer iter.Close()
+
+	votes := make(map[string][]int)
+	var userID gocql.UUID
+	var options []int
+
+	for iter.Scan(&userID, &options) {
+		votes[userID.String()] = options
+		options = nil
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	return votes, nil
+}
+
+// ClosePoll encerra a enquete gravando os resultados finais (LWT).
+// Retorna false se a enquete já tinha sido encerrada: os resultados gravados antes prevalecem.
+func (db *CassandraDB) ClosePoll(pollID string, results map[int]int, ttl int) (bool, error) {
+	query := `UPDATE nexus.polls USING TTL ? SET closed_at = ?, results = ? WHERE poll_id = ? IF closed_at = null`
+
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return false, err
+	}
+
+	existing := make(map[string]interface{})
+	return db.session.Query(query, ttl, time.Now(), results, pollUUID).MapScanCAS(existing)
+}
+
+// DeletePoll remove uma enquete e todos os seus votos
+func (db *CassandraDB) DeletePoll(pollID string) error {
+	pollUUID, err := gocql.ParseUUID(pollID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.session.Query(`DELETE FROM nexus.poll_votes WHERE poll_id = ?`, pollUUID).Exec(); err != nil {
+		return err
+	}
+
+	return db.session.Query(`DELETE FROM nexus.polls WHERE poll_id = ?`, pollUUID).Exec()
+}
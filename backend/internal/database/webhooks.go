@@ -0,0 +1,148 @@
+package database
+
+import (
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// ==================== WEBHOOKS DE ENTRADA ====================
+
+// CreateWebhook cria um webhook de entrada para um canal. Apenas o hash do token é armazenado
+func (db *CassandraDB) CreateWebhook(webhookID, channelID, serverID, name, avatarURL, tokenHash, createdBy string) error {
+	webhookUUID, err := gocql.ParseUUID(webhookID)
+	if err != nil {
+		return err
+	}
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+	serverUUID, err := gocql.ParseUUID(serverID)
+	if err != nil {
+		return err
+	}
+	creatorUUID, err := gocql.ParseUUID(createdBy)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	query := `INSERT INTO nexus.webhooks (webhook_id, channel_id, server_id, name, avatar_url, token_hash, created_by, created_at, updated_at)
+	          VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	if err := db.session.Query(query, webhookUUID, channelUUID, serverUUID, name, avatarURL, tokenHash, creatorUUID, now, now).Exec(); err != nil {
+		return err
+	}
+
+	indexQuery := `INSERT INTO nexus.webhooks_by_channel (channel_id, webhook_id) VALUES (?, ?)`
+	return db.session.Query(indexQuery, channelUUID, webhookUUID).Exec()
+}
+
+// GetWebhook retorna um webhook (incluindo o hash do token)
+func (db *CassandraDB) GetWebhook(webhookID string) (map[string]interface{}, error) {
+	query := `SELECT webhook_id, channel_id, server_id, name, avatar_url, token_hash, created_by, created_at
+	          FROM nexus.webhooks WHERE webhook_id = ?`
+
+	webhookUUID, err := gocql.ParseUUID(webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var wID, channelID, serverID, createdBy gocql.UUID
+	var name, avatarURL, tokenHash string
+	var createdAt time.Time
+
+	err = db.session.Query(query, webhookUUID).Scan(&wID, &channelID, &serverID, &name, &avatarURL, &tokenHash, &createdBy, &createdAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"webhook_id": wID.String(),
+		"channel_id": channelID.String(),
+		"server_id":  serverID.String(),
+		"name":       name,
+		"avatar_url": avatarURL,
+		"token_hash": tokenHash,
+		"created_by": createdBy.String(),
+		"created_at": createdAt,
+	}, nil
+}
+
+// GetChannelWebhooks retorna os webhooks de um canal
+func (db *CassandraDB) GetChannelWebhooks(channelID string) ([]map[string]interface{}, error) {
+	query := `SELECT webhook_id FROM nexus.webhooks_by_channel WHERE channel_id = ?`
+
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	iter := db.session.Query(query, channelUUID).Iter()
+	defer iter.Close()
+
+	var webhookIDs []string
+	var webhookID gocql.UUID
+	for iter.Scan(&webhookID) {
+		webhookIDs = append(webhookIDs, webhookID.String())
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	var results []map[string]interface{}
+	for _, id := range webhookIDs {
+		row, err := db.GetWebhook(id)
+		if err == gocql.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, row)
+	}
+
+	return results, nil
+}
+
+// UpdateWebhook atualiza o nome e o avatar padrão de um webhook
+func (db *CassandraDB) UpdateWebhook(webhookID, name, avatarURL string) error {
+	query := `UPDATE nexus.webhooks SET name = ?, avatar_url = ?, updated_at = ? WHERE webhook_id = ?`
+
+	webhookUUID, err := gocql.ParseUUID(webhookID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, name, avatarURL, time.Now(), webhookUUID).Exec()
+}
+
+// UpdateWebhookToken substitui o hash do token (o token anterior deixa de funcionar)
+func (db *CassandraDB) UpdateWebhookToken(webhookID, tokenHash string) error {
+	query := `UPDATE nexus.webhooks SET token_hash = ?, updated_at = ? WHERE webhook_id = ?`
+
+	webhookUUID, err := gocql.ParseUUID(webhookID)
+	if err != nil {
+		return err
+	}
+
+	return db.session.Query(query, tokenHash, time.Now(), webhookUUID).Exec()
+}
+
+// DeleteWebhook remove um webhook
+func (db *CassandraDB) DeleteWebhook(webhookID, channelID string) error {
+	webhookUUID, err := gocql.ParseUUID(webhookID)
+	if err != nil {
+		return err
+	}
+	channelUUID, err := gocql.ParseUUID(channelID)
+	if err != nil {
+		return err
+	}
+
+	if err := db.session.Query(`DELETE FROM nexus.webhooks WHERE webhook_id = ?`, webhookUUID).Exec(); err != nil {
+		return err
+	}
+
+	return db.session.Query(`DELETE FROM nexus.webhooks_by_channel WHERE channel_id = ? AND webhook_id = ?`, channelUUID, webhookUUID).Exec()
+}
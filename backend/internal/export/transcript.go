@@ -0,0 +1,232 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Formatos de exportação suportados
+const (
+	FormatJSON = "json"
+	FormatHTML = "html"
+	FormatText = "txt"
+)
+
+// attachmentPattern encontra referências a arquivos enviados ao servidor (/api/images/...)
+var attachmentPattern = regexp.MustCompile(`(?:https?://[^\s/]+)?/api/images/[A-Za-z0-9._\-/]+`)
+
+// Channel identifica o canal exportado
+type Channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// Header contém os metadados do transcript
+type Header struct {
+	Channel    Channel   `json:"channel"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// Message é uma mensagem no transcript, com o autor já resolvido
+type Message struct {
+	ID          string     `json:"id"`
+	AuthorID    string     `json:"authorId"`
+	Author      string     `json:"author"`
+	Content     string     `json:"content"`
+	Type        string     `json:"type,omitempty"`
+	Timestamp   time.Time  `json:"timestamp"`
+	EditedAt    *time.Time `json:"editedAt,omitempty"`
+	Attachments []string   `json:"attachments,omitempty"`
+}
+
+// Writer escreve um transcript de forma incremental (sem manter as mensagens em memória)
+type Writer interface {
+	Begin(header Header) error
+	Write(msg Message) error
+	End(count int) error
+}
+
+// NewWriter cria o Writer do formato pedido
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatJSON:
+		return &jsonWriter{w: w}, nil
+	case FormatHTML:
+		return &htmlWriter{w: w}, nil
+	case FormatText:
+		return &textWriter{w: w}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format: %s", format)
+	}
+}
+
+// ValidFormat verifica se o formato é suportado
+func ValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatHTML || format == FormatText
+}
+
+// ContentType retorna o Content-Type do arquivo exportado
+func ContentType(format string) string {
+	switch format {
+	case FormatJSON:
+		return "application/json"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// AttachmentRefs extrai as referências a anexos do conteúdo de uma mensagem
+func AttachmentRefs(content string) []string {
+	return attachmentPattern.FindAllString(content, -1)
+}
+
+// ==================== JSON ====================
+
+type jsonWriter struct {
+	w     io.Writer
+	wrote bool
+}
+
+func (jw *jsonWriter) Begin(header Header) error {
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	// Reabrir o objeto do header para anexar a lista de mensagens
+	_, err = fmt.Fprintf(jw.w, "%s,\"messages\":[", strings.TrimSuffix(string(headerBytes), "}"))
+	return err
+}
+
+func (jw *jsonWriter) Write(msg Message) error {
+	if jw.wrote {
+		if _, err := io.WriteString(jw.w, ","); err != nil {
+			return err
+		}
+	}
+	jw.wrote = true
+
+	msgBytes, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = jw.w.Write(msgBytes)
+	return err
+}
+
+func (jw *jsonWriter) End(count int) error {
+	_, err := fmt.Fprintf(jw.w, "],\"count\":%d}\n", count)
+	return err
+}
+
+// ==================== HTML ====================
+
+// htmlStyle mantém o transcript autocontido (sem CSS ou scripts externos)
+const htmlStyle = `body{font-family:-apple-system,"Segoe UI",Roboto,sans-serif;background:#313338;color:#dbdee1;margin:0;padding:24px}
+header{border-bottom:1px solid #4e5058;margin-bottom:16px;padding-bottom:12px}
+h1{font-size:20px;margin:0 0 4px}
+.meta{color:#949ba4;font-size:12px}
+.message{padding:6px 0}
+.author{font-weight:600;color:#f2f3f5}
+.time{color:#949ba4;font-size:12px;margin-left:8px}
+.content{white-space:pre-wrap;word-wrap:break-word;margin-top:2px}
+.edited{color:#949ba4;font-size:11px;margin-left:4px}
+.attachments a{color:#00a8fc;font-size:13px;display:block}
+footer{border-top:1px solid #4e5058;margin-top:16px;padding-top:12px;color:#949ba4;font-size:12px}`
+
+type htmlWriter struct {
+	w io.Writer
+}
+
+func (hw *htmlWriter) Begin(header Header) error {
+	name := html.EscapeString(header.Channel.Name)
+	_, err := fmt.Fprintf(hw.w, `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>#%s</title>
+<style>%s</style>
+</head>
+<body>
+<header>
+<h1>#%s</h1>
+<div class="meta">%s &mdash; %s &middot; exported %s</div>
+</header>
+<main>
+`, name, htmlStyle, name,
+		header.From.UTC().Format(time.RFC1123), header.To.UTC().Format(time.RFC1123), header.ExportedAt.UTC().Format(time.RFC1123))
+	return err
+}
+
+func (hw *htmlWriter) Write(msg Message) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, `<div class="message" id="m-%s"><div><span class="author">%s</span><span class="time">%s</span>`,
+		html.EscapeString(msg.ID), html.EscapeString(msg.Author), msg.Timestamp.UTC().Format("2006-01-02 15:04:05 MST"))
+	if msg.EditedAt != nil {
+		b.WriteString(`<span class="edited">(edited)</span>`)
+	}
+	fmt.Fprintf(&b, `</div><div class="content">%s</div>`, html.EscapeString(msg.Content))
+
+	if len(msg.Attachments) > 0 {
+		b.WriteString(`<div class="attachments">`)
+		for _, ref := range msg.Attachments {
+			escaped := html.EscapeString(ref)
+			fmt.Fprintf(&b, `<a href="%s">%s</a>`, escaped, escaped)
+		}
+		b.WriteString(`</div>`)
+	}
+	b.WriteString("</div>\n")
+
+	_, err := io.WriteString(hw.w, b.String())
+	return err
+}
+
+func (hw *htmlWriter) End(count int) error {
+	_, err := fmt.Fprintf(hw.w, "</main>\n<footer>%d messages</footer>\n</body>\n</html>\n", count)
+	return err
+}
+
+// ==================== TEXTO ====================
+
+type textWriter struct {
+	w io.Writer
+}
+
+func (tw *textWriter) Begin(header Header) error {
+	_, err := fmt.Fprintf(tw.w, "# #%s (%s)\n# %s - %s\n# Exported %s\n\n",
+		header.Channel.Name, header.Channel.ID,
+		header.From.UTC().Format(time.RFC3339), header.To.UTC().Format(time.RFC3339),
+		header.ExportedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (tw *textWriter) Write(msg Message) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "[%s] %s: %s", msg.Timestamp.UTC().Format("2006-01-02 15:04:05"), msg.Author, msg.Content)
+	if msg.EditedAt != nil {
+		b.WriteString(" (edited)")
+	}
+	b.WriteString("\n")
+	for _, ref := range msg.Attachments {
+		fmt.Fprintf(&b, "    attachment: %s\n", ref)
+	}
+
+	_, err := io.WriteString(tw.w, b.String())
+	return err
+}
+
+func (tw *textWriter) End(count int) error {
+	_, err := fmt.Fprintf(tw.w, "\n# %d messages\n", count)
+	return err
+}
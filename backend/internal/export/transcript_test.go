@@ -0,0 +1,164 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTranscript escreve um transcript completo no formato pedido
+func writeTranscript(t *testing.T, format string, header Header, messages []Message) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Begin(header); err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range messages {
+		if err := w.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.End(len(messages)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func testHeader(name string) Header {
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return Header{
+		Channel:    Channel{ID: "channel", Name: name},
+		From:       at.Add(-time.Hour),
+		To:         at,
+		ExportedAt: at,
+	}
+}
+
+// TestJSONWriter verifica que o header e as mensagens formam um único documento JSON válido
+func TestJSONWriter(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		channel  string
+		messages []Message
+	}{
+		{"empty", "general", nil},
+		{"one message", "general", []Message{{ID: "1", Author: "ana", Content: "olá", Timestamp: at}}},
+		{"several messages", "general", []Message{
+			{ID: "1", Author: "ana", Content: "a", Timestamp: at},
+			{ID: "2", Author: "bia", Content: "b", Timestamp: at, EditedAt: &at},
+			{ID: "3", Author: "caio", Content: "c /api/images/x.png", Timestamp: at, Attachments: []string{"/api/images/x.png"}},
+		}},
+		{"quotes and braces in header", `sala "}{" \ ]`, []Message{{ID: "1", Content: `}"],"count":9}`, Timestamp: at}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := writeTranscript(t, FormatJSON, testHeader(tt.channel), tt.messages)
+
+			var doc struct {
+				Header
+				Messages []Message `json:"messages"`
+				Count    int       `json:"count"`
+			}
+			if err := json.Unmarshal([]byte(out), &doc); err != nil {
+				t.Fatalf("invalid JSON %q: %v", out, err)
+			}
+			if doc.Channel.Name != tt.channel || !doc.ExportedAt.Equal(testHeader(tt.channel).ExportedAt) {
+				t.Fatalf("header = %+v", doc.Header)
+			}
+			if doc.Count != len(tt.messages) || len(doc.Messages) != len(tt.messages) {
+				t.Fatalf("count = %d, messages = %d, want %d", doc.Count, len(doc.Messages), len(tt.messages))
+			}
+			for i, msg := range doc.Messages {
+				if msg.ID != tt.messages[i].ID || msg.Content != tt.messages[i].Content {
+					t.Fatalf("message %d = %+v, want %+v", i, msg, tt.messages[i])
+				}
+			}
+		})
+	}
+}
+
+// TestHTMLWriterEscaping verifica que conteúdo de usuários não injeta HTML no transcript
+func TestHTMLWriterEscaping(t *testing.T) {
+	at := time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		channel string
+		msg     Message
+		want    []string
+		reject  []string
+	}{
+		{
+			name:    "channel name",
+			channel: "<script>alert(1)</script>",
+			msg:     Message{ID: "1", Author: "ana", Content: "oi", Timestamp: at},
+			want:    []string{"<title>#&lt;script&gt;alert(1)&lt;/script&gt;</title>"},
+			reject:  []string{"<script>"},
+		},
+		{
+			name:    "content",
+			channel: "general",
+			msg:     Message{ID: "1", Author: "ana", Content: `<img src=x onerror="alert(1)"> & co`, Timestamp: at},
+			want:    []string{`&lt;img src=x onerror=&#34;alert(1)&#34;&gt; &amp; co`},
+			reject:  []string{"<img"},
+		},
+		{
+			name:    "author and id",
+			channel: "general",
+			msg:     Message{ID: `1"><b>`, Author: "<b>ana</b>", Content: "oi", Timestamp: at},
+			want:    []string{`id="m-1&#34;&gt;&lt;b&gt;"`, `<span class="author">&lt;b&gt;ana&lt;/b&gt;</span>`},
+			reject:  []string{"<b>"},
+		},
+		{
+			name:    "attachment href",
+			channel: "general",
+			msg:     Message{ID: "1", Author: "ana", Content: "", Timestamp: at, Attachments: []string{`/api/images/a.png" onclick="x`}},
+			want:    []string{`<a href="/api/images/a.png&#34; onclick=&#34;x">`},
+			reject:  []string{`onclick="x"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := writeTranscript(t, FormatHTML, testHeader(tt.channel), []Message{tt.msg})
+
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("transcript is missing %q:\n%s", want, out)
+				}
+			}
+			for _, reject := range tt.reject {
+				if strings.Contains(out, reject) {
+					t.Errorf("transcript contains unescaped %q:\n%s", reject, out)
+				}
+			}
+		})
+	}
+}
+
+func TestAttachmentRefs(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"sem anexos", nil},
+		{"veja /api/images/abc.png", []string{"/api/images/abc.png"}},
+		{"https://nexus.example.com/api/images/a/b.jpg e /api/images/c.gif", []string{"https://nexus.example.com/api/images/a/b.jpg", "/api/images/c.gif"}},
+		{"https://example.com/outra/coisa.png", nil},
+	}
+
+	for _, tt := range tests {
+		got := AttachmentRefs(tt.content)
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("AttachmentRefs(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+// authorizeChannel aplica a política de acesso ao canal para o usuário autenticado.
+// Retorna o canal e as claims; em caso de negação escreve 401, 403 ou 404 e retorna false.
+func authorizeChannel(w http.ResponseWriter, r *http.Request, logger *zap.Logger, policy *access.Policy, channelID string) (map[string]interface{}, *models.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, nil, false
+	}
+
+	channelRow, err := policy.Channel(channelID, claims.UserID)
+	if err != nil {
+		writeAccessError(w, logger, err, zap.String("channelId", channelID))
+		return nil, nil, false
+	}
+	return channelRow, claims, true
+}
+
+// authorizeServer verifica se o usuário autenticado é membro do servidor.
+// Em caso de negação escreve 401, 403 ou 404 e retorna false.
+func authorizeServer(w http.ResponseWriter, r *http.Request, logger *zap.Logger, policy *access.Policy, serverID string) (*models.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	if err := policy.Server(serverID, claims.UserID); err != nil {
+		writeAccessError(w, logger, err, zap.String("serverId", serverID))
+		return nil, false
+	}
+	return claims, true
+}
+
+// writeAccessError traduz os erros da política de acesso em respostas HTTP
+func writeAccessError(w http.ResponseWriter, logger *zap.Logger, err error, fields ...zap.Field) {
+	switch {
+	case errors.Is(err, access.ErrChannelNotFound), errors.Is(err, access.ErrServerNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, access.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		logger.Error("failed to check access", append(fields, zap.Error(err))...)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+	}
+}
@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/database"
 	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/ratelimit"
 	"go.uber.org/zap"
 )
 
@@ -15,13 +18,15 @@ import (
 type ChannelHandler struct {
 	logger *zap.Logger
 	db     *database.CassandraDB
+	policy *access.Policy
 }
 
 // NewChannelHandler cria um novo handler de canais
-func NewChannelHandler(logger *zap.Logger, db *database.CassandraDB) *ChannelHandler {
+func NewChannelHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy) *ChannelHandler {
 	return &ChannelHandler{
 		logger: logger,
 		db:     db,
+		policy: policy,
 	}
 }
 
@@ -29,7 +34,9 @@ func NewChannelHandler(logger *zap.Logger, db *database.CassandraDB) *ChannelHan
 type ChannelRequest struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
-	Type        string `json:"type"` // "text", "voice", "video"
+	Type        string `json:"type"`                 // "text", "voice", "video"
+	MessageTTL  *int   `json:"messageTtl,omitempty"` // TTL padrão das mensagens em segundos (0 desativa)
+	Slowmode    *int   `json:"slowmode,omitempty"`   // segundos entre mensagens por usuário (0 desativa)
 }
 
 // ChannelResponse representa um canal
@@ -38,16 +45,24 @@ type ChannelResponse struct {
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
 	Type        string   `json:"type"`
+	ServerID    string   `json:"serverId,omitempty"`
 	Members     []string `json:"members"`
+	MessageTTL  int      `json:"messageTtl,omitempty"`
+	Slowmode    int      `json:"slowmode,omitempty"`
 	CreatedAt   string   `json:"created_at"`
 }
 
-// ListChannels retorna todos os canais do usuário
+// ListChannels retorna os canais do usuário: os dos seus servidores e as DMs e grupos dos quais participa
 func (ch *ChannelHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
-	// Buscar todos os canais do banco de dados
-	rows, err := ch.db.GetAllChannels()
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := ch.policy.UserChannels(claims.UserID)
 	if err != nil {
-		ch.logger.Error("failed to get channels", zap.Error(err))
+		ch.logger.Error("failed to get channels", zap.Error(err), zap.String("userId", claims.UserID))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
@@ -58,6 +73,7 @@ func (ch *ChannelHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
 			ID:        row["channel_id"].(string),
 			Name:      row["name"].(string),
 			Type:      row["type"].(string),
+			ServerID:  access.ServerID(row),
 			Members:   []string{}, // TODO: Buscar membros da tabela de relacionamento
 			CreatedAt: row["created_at"].(time.Time).Format(time.RFC3339),
 		}
@@ -81,10 +97,8 @@ func (ch *ChannelHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	row, err := ch.db.GetChannelByID(channelID)
-	if err != nil {
-		ch.logger.Error("failed to get channel", zap.Error(err), zap.String("id", channelID))
-		http.Error(w, "channel not found", http.StatusNotFound)
+	row, _, ok := authorizeChannel(w, r, ch.logger, ch.policy, channelID)
+	if !ok {
 		return
 	}
 
@@ -92,6 +106,7 @@ func (ch *ChannelHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
 		ID:        row["channel_id"].(string),
 		Name:      row["name"].(string),
 		Type:      row["type"].(string),
+		ServerID:  access.ServerID(row),
 		Members:   []string{},
 		CreatedAt: row["created_at"].(time.Time).Format(time.RFC3339),
 	}
@@ -99,6 +114,12 @@ func (ch *ChannelHandler) GetChannel(w http.ResponseWriter, r *http.Request) {
 	if desc, ok := row["description"].(string); ok {
 		channel.Description = desc
 	}
+	if ttl, ok := row["message_ttl"].(int); ok {
+		channel.MessageTTL = ttl
+	}
+	if slowmode, ok := row["slowmode"].(int); ok {
+		channel.Slowmode = slowmode
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(channel)
@@ -140,6 +161,13 @@ func (ch *ChannelHandler) CreateChannel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// O criador participa do canal (controle de acesso por channel_members)
+	if err := ch.db.AddChannelMember(channelID, claims.UserID, "owner"); err != nil {
+		ch.logger.Error("failed to add channel owner as member", zap.Error(err), zap.String("id", channelID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	channel := ChannelResponse{
 		ID:          channelID,
 		Name:        req.Name,
@@ -168,7 +196,27 @@ func (ch *ChannelHandler) DeleteChannel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// TODO: Verificar se o usuário tem permissão para deletar
+	channelRow, claims, ok := authorizeChannel(w, r, ch.logger, ch.policy, channelID)
+	if !ok {
+		return
+	}
+
+	// Canais de servidor: owners e admins; demais canais: o dono
+	canDelete := channelRow["owner_id"].(string) == claims.UserID
+	if serverID := access.ServerID(channelRow); serverID != "" {
+		isAdmin, err := ch.db.IsServerAdmin(serverID, claims.UserID)
+		if err != nil {
+			ch.logger.Error("failed to check server admin", zap.Error(err), zap.String("id", channelID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		canDelete = isAdmin
+	}
+	if !canDelete {
+		http.Error(w, "forbidden: you can't delete this channel", http.StatusForbidden)
+		return
+	}
+
 	err := ch.db.DeleteChannel(channelID)
 	if err != nil {
 		ch.logger.Error("failed to delete channel", zap.Error(err), zap.String("id", channelID))
@@ -182,18 +230,17 @@ func (ch *ChannelHandler) DeleteChannel(w http.ResponseWriter, r *http.Request)
 
 // UpdateChannel atualiza um canal
 func (ch *ChannelHandler) UpdateChannel(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value("claims").(*models.Claims)
-	if !ok || claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	channelID := r.URL.Query().Get("id")
 	if channelID == "" {
 		http.Error(w, "channel id required", http.StatusBadRequest)
 		return
 	}
 
+	channelRow, claims, ok := authorizeChannel(w, r, ch.logger, ch.policy, channelID)
+	if !ok {
+		return
+	}
+
 	var req ChannelRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -205,8 +252,34 @@ func (ch *ChannelHandler) UpdateChannel(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// TODO: Verificar se o usuário tem permissão para atualizar
-	err := ch.db.UpdateChannel(channelID, req.Name, req.Description)
+	if req.MessageTTL != nil && (*req.MessageTTL < 0 || *req.MessageTTL > maxMessageTTL) {
+		http.Error(w, fmt.Sprintf("messageTtl must be between 0 and %d seconds", maxMessageTTL), http.StatusBadRequest)
+		return
+	}
+
+	if req.Slowmode != nil && (*req.Slowmode < 0 || *req.Slowmode > int(ratelimit.MaxSlowmode.Seconds())) {
+		http.Error(w, fmt.Sprintf("slowmode must be between 0 and %d seconds", int(ratelimit.MaxSlowmode.Seconds())), http.StatusBadRequest)
+		return
+	}
+
+	// Canais de servidor são alterados por moderadores; demais canais, pelo dono.
+	// Slowmode só existe em canais de servidor.
+	isModerator, err := ch.db.IsChannelModerator(channelID, claims.UserID)
+	if err != nil {
+		ch.logger.Error("failed to check moderator status", zap.Error(err), zap.String("id", channelID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if req.Slowmode != nil && !isModerator {
+		http.Error(w, "forbidden: only server moderators can change slowmode", http.StatusForbidden)
+		return
+	}
+	if !isModerator && (access.ServerID(channelRow) != "" || channelRow["owner_id"].(string) != claims.UserID) {
+		http.Error(w, "forbidden: you can't edit this channel", http.StatusForbidden)
+		return
+	}
+
+	err = ch.db.UpdateChannel(channelID, req.Name, req.Description)
 	if err != nil {
 		ch.logger.Error("failed to update channel", zap.Error(err), zap.String("id", channelID))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -220,6 +293,25 @@ func (ch *ChannelHandler) UpdateChannel(w http.ResponseWriter, r *http.Request)
 		Type:        req.Type,
 	}
 
+	// Atualizar o TTL padrão das mensagens (mensagens efêmeras)
+	if req.MessageTTL != nil {
+		if err := ch.db.UpdateChannelMessageTTL(channelID, *req.MessageTTL); err != nil {
+			ch.logger.Error("failed to update channel message ttl", zap.Error(err), zap.String("id", channelID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		response.MessageTTL = *req.MessageTTL
+	}
+
+	if req.Slowmode != nil {
+		if err := ch.db.UpdateChannelSlowmode(channelID, *req.Slowmode); err != nil {
+			ch.logger.Error("failed to update channel slowmode", zap.Error(err), zap.String("id", channelID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		response.Slowmode = *req.Slowmode
+	}
+
 	ch.logger.Info("channel updated", zap.String("id", channelID), zap.String("name", req.Name))
 
 	w.Header().Set("Content-Type", "application/json")
@@ -0,0 +1,897 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gofrs/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/commands"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"github.com/nexus/backend/internal/validation"
+	"go.uber.org/zap"
+)
+
+const (
+	maxBotCommandsPerServer = 50
+	maxBotNameLength        = 80
+
+	botCommandTimeout   = 3 * time.Second
+	botCommandsCacheTTL = 30 * time.Second
+	maxBotReplySize     = 64 * 1024
+
+	// maxReminderDelay é o maior prazo aceito por /remind
+	maxReminderDelay = 7 * 24 * time.Hour
+
+	// systemUsername identifica respostas efêmeras dos comandos embutidos
+	systemUsername = "Nexus"
+)
+
+// CommandReply é a resposta de um comando: pública (vira uma mensagem no canal)
+// ou efêmera (entregue apenas a quem executou o comando). É também o formato
+// esperado na resposta do endpoint de um bot.
+type CommandReply struct {
+	Content   string         `json:"content"`
+	Embeds    []models.Embed `json:"embeds,omitempty"`
+	Ephemeral bool           `json:"ephemeral,omitempty"`
+}
+
+// commandInvocation é o contexto de uma execução de comando
+type commandInvocation struct {
+	command    commands.Command
+	options    commands.Values
+	channelID  string
+	serverID   string
+	userID     string
+	username   string
+	channelRow map[string]interface{}
+	messageID  gocql.UUID // ID da mensagem pública criada pelo comando (o nonce do envio o referencia)
+
+	// created é preenchida por comandos que já criaram sua mensagem pública (ex.: /poll)
+	created *MessageResponse
+}
+
+// commandFunc executa um comando embutido
+type commandFunc func(ctx context.Context, inv *commandInvocation) (*CommandReply, error)
+
+// commandError é um erro de uso do comando, exibido a quem o executou
+type commandError struct {
+	status  int
+	message string
+}
+
+func (e *commandError) Error() string { return e.message }
+
+// commandOutcome é o resultado de um comando executado
+type commandOutcome struct {
+	message   MessageResponse
+	ephemeral bool
+}
+
+// botCommand é um comando de barra registrado por um bot em um servidor
+type botCommand struct {
+	commands.Command
+	id          string
+	botAvatar   string
+	callbackURL string
+	secret      string
+}
+
+// CommandHandler mantém o registro de comandos de barra, executa os comandos
+// embutidos e encaminha os comandos de bots para seus endpoints
+type CommandHandler struct {
+	logger     *zap.Logger
+	db         *database.CassandraDB
+	registry   *commands.Registry
+	builtins   map[string]commandFunc
+	messages   *MessageHandler
+	tasks      *TaskHandler
+	dispatcher *services.WebhookDispatcher
+	client     *http.Client
+	bots       *cache.MemoryCache // serverID -> []botCommand
+}
+
+// NewCommandHandler cria o handler de comandos e registra os comandos embutidos.
+// O MessageHandler passa a encaminhar para ele os conteúdos iniciados por "/".
+func NewCommandHandler(logger *zap.Logger, db *database.CassandraDB, messages *MessageHandler, tasks *TaskHandler, dispatcher *services.WebhookDispatcher) *CommandHandler {
+	ch := &CommandHandler{
+		logger:     logger,
+		db:         db,
+		registry:   commands.NewRegistry(),
+		builtins:   make(map[string]commandFunc),
+		messages:   messages,
+		tasks:      tasks,
+		dispatcher: dispatcher,
+		client:     dispatcher.NewClient(botCommandTimeout), // só endereços públicos: a resposta vai para o canal
+		bots:       cache.NewMemoryCache(botCommandsCacheTTL),
+	}
+
+	ch.register(commands.Command{
+		Name:        "remind",
+		Description: "Remind you about something later",
+		Options: []commands.Option{
+			{Name: "when", Description: "How long from now (e.g. 10m, 2h, 1d)", Type: commands.OptionDuration, Required: true},
+			{Name: "text", Description: "What to remind you about", Type: commands.OptionString, Required: true},
+		},
+	}, ch.remindCommand)
+
+	ch.register(commands.Command{
+		Name:        "poll",
+		Description: "Create a poll in this channel",
+		Options: []commands.Option{
+			{Name: "question", Description: "The poll question", Type: commands.OptionString, Required: true},
+			{Name: "choices", Description: "Choices separated by |", Type: commands.OptionString, Required: true},
+			{Name: "duration", Description: "Close the poll after (e.g. 1h, 1d)", Type: commands.OptionDuration},
+			{Name: "multi", Description: "Allow choosing more than one option", Type: commands.OptionBoolean},
+		},
+	}, ch.pollCommand)
+
+	ch.register(commands.Command{
+		Name:        "task",
+		Description: "Add a task to this channel's board",
+		Options: []commands.Option{
+			{Name: "title", Description: "Task title", Type: commands.OptionString, Required: true},
+		},
+	}, ch.taskCommand)
+
+	messages.commands = ch
+	return ch
+}
+
+// register adiciona um comando embutido; declarações inválidas são erro de programação
+func (ch *CommandHandler) register(cmd commands.Command, fn commandFunc) {
+	cmd.Source = commands.SourceBuiltin
+	if err := ch.registry.Register(cmd); err != nil {
+		panic(err)
+	}
+	ch.builtins[cmd.Name] = fn
+}
+
+// ListCommands retorna os comandos disponíveis no canal para o usuário (autocomplete)
+func (ch *CommandHandler) ListCommands(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	channelRow, claims, ok := authorizeChannel(w, r, ch.logger, ch.messages.policy, channelID)
+	if !ok {
+		return
+	}
+	serverID := access.ServerID(channelRow)
+
+	available := ch.registry.List()
+	if serverID != "" {
+		bots, err := ch.serverBotCommands(serverID)
+		if err != nil {
+			ch.logger.Error("failed to get bot commands", zap.Error(err), zap.String("serverId", serverID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		for _, bot := range bots {
+			available = append(available, bot.Command)
+		}
+	}
+
+	list := make([]commands.Command, 0, len(available))
+	for _, cmd := range available {
+		allowed, err := ch.hasPermission(cmd.Permission, channelID, serverID, claims.UserID)
+		if err != nil {
+			ch.logger.Warn("failed to check command permission", zap.Error(err), zap.String("command", cmd.Name))
+			continue
+		}
+		if allowed {
+			list = append(list, cmd)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// Subscribe atende pedidos de execução de comandos vindos do gateway WebSocket
+func (ch *CommandHandler) Subscribe(nc *nats.Conn) (*nats.Subscription, error) {
+	return nc.QueueSubscribe(commands.InvokeSubject, "api-commands", func(msg *nats.Msg) {
+		var req commands.InvokeRequest
+		if err := json.Unmarshal(msg.Data, &req); err != nil {
+			ch.logger.Warn("invalid command request", zap.Error(err))
+			return
+		}
+
+		response := ch.invokeFromGateway(req)
+		payload, _ := json.Marshal(response)
+		if err := msg.Respond(payload); err != nil {
+			ch.logger.Warn("failed to respond command request", zap.Error(err))
+		}
+	})
+}
+
+func (ch *CommandHandler) invokeFromGateway(req commands.InvokeRequest) commands.InvokeResponse {
+	ctx, cancel := context.WithTimeout(context.Background(), botCommandTimeout+2*time.Second)
+	defer cancel()
+
+	channelRow, err := ch.messages.policy.Channel(req.ChannelID, req.UserID)
+	if err != nil {
+		if errors.Is(err, access.ErrChannelNotFound) || errors.Is(err, access.ErrForbidden) {
+			return commands.InvokeResponse{Handled: true, Error: err.Error()}
+		}
+		ch.logger.Error("failed to check channel access", zap.Error(err), zap.String("channelId", req.ChannelID))
+		return commands.InvokeResponse{Handled: true, Error: "internal server error"}
+	}
+
+	messageID, err := gocql.ParseUUID(req.MessageID)
+	if err != nil || messageID.Version() != 1 {
+		messageID = gocql.TimeUUID()
+	}
+
+	outcome, handled, err := ch.execute(ctx, channelRow, messageID, req.UserID, req.Username, req.Content)
+	if !handled {
+		return commands.InvokeResponse{}
+	}
+	if err != nil {
+		var cmdErr *commandError
+		if errors.As(err, &cmdErr) {
+			return commands.InvokeResponse{Handled: true, Error: cmdErr.message}
+		}
+		ch.logger.Error("command failed", zap.Error(err))
+		return commands.InvokeResponse{Handled: true, Error: "internal server error"}
+	}
+
+	response := commands.InvokeResponse{Handled: true, Ephemeral: outcome.ephemeral}
+	if outcome.ephemeral {
+		response.Message, _ = json.Marshal(outcome.message)
+	}
+	return response
+}
+
+// execute executa o comando contido em content. handled é false quando content não
+// é um comando conhecido no canal; nesse caso deve ser enviado como mensagem comum.
+// messageID é usado na mensagem pública criada pelo comando.
+func (ch *CommandHandler) execute(ctx context.Context, channelRow map[string]interface{}, messageID gocql.UUID, userID, username, content string) (commandOutcome, bool, error) {
+	name, args, ok := commands.Parse(content)
+	if !ok {
+		return commandOutcome{}, false, nil
+	}
+
+	channelID := channelRow["channel_id"].(string)
+	serverID := access.ServerID(channelRow)
+
+	cmd, builtin := ch.registry.Get(name)
+	var bot *botCommand
+	if !builtin {
+		if serverID == "" {
+			return commandOutcome{}, false, nil
+		}
+		bots, err := ch.serverBotCommands(serverID)
+		if err != nil {
+			return commandOutcome{}, true, err
+		}
+		for i := range bots {
+			if bots[i].Name == name {
+				bot = &bots[i]
+				break
+			}
+		}
+		if bot == nil {
+			return commandOutcome{}, false, nil
+		}
+		cmd = bot.Command
+	}
+
+	allowed, err := ch.hasPermission(cmd.Permission, channelID, serverID, userID)
+	if err != nil {
+		return commandOutcome{}, true, err
+	}
+	if !allowed {
+		return commandOutcome{}, true, &commandError{http.StatusForbidden, fmt.Sprintf("you don't have permission to use /%s", name)}
+	}
+
+	options, err := cmd.ParseOptions(args)
+	if err != nil {
+		return commandOutcome{}, true, &commandError{http.StatusBadRequest, err.Error()}
+	}
+
+	inv := &commandInvocation{
+		command:    cmd,
+		options:    options,
+		channelID:  channelID,
+		serverID:   serverID,
+		userID:     userID,
+		username:   username,
+		channelRow: channelRow,
+		messageID:  messageID,
+	}
+
+	var reply *CommandReply
+	if bot != nil {
+		reply, err = ch.invokeBot(ctx, bot, inv)
+	} else {
+		reply, err = ch.builtins[name](ctx, inv)
+	}
+	if err != nil {
+		return commandOutcome{}, true, err
+	}
+
+	ch.logger.Info("command executed",
+		zap.String("command", name),
+		zap.String("source", cmd.Source),
+		zap.String("channelId", channelID),
+		zap.String("userId", userID),
+	)
+
+	if inv.created != nil {
+		return commandOutcome{message: *inv.created}, true, nil
+	}
+
+	if reply.Ephemeral {
+		authorName := systemUsername
+		authorAvatar := ""
+		if bot != nil {
+			authorName, authorAvatar = bot.BotName, bot.botAvatar
+		}
+		return commandOutcome{
+			message: MessageResponse{
+				ID:        gocql.TimeUUID().String(),
+				ChannelID: channelID,
+				Username:  authorName,
+				Avatar:    authorAvatar,
+				Content:   reply.Content,
+				Embeds:    reply.Embeds,
+				Timestamp: time.Now().UnixMilli(),
+				Ephemeral: true,
+			},
+			ephemeral: true,
+		}, true, nil
+	}
+
+	// Resposta pública: comandos embutidos falam pelo autor, bots com sua própria identidade
+	msg := newMessage{
+		ID:        messageID,
+		ChannelID: channelID,
+		AuthorID:  userID,
+		Username:  username,
+		Content:   reply.Content,
+		Embeds:    reply.Embeds,
+		TTL:       resolveMessageTTL(channelRow, nil),
+	}
+	if bot != nil {
+		msg.AuthorID = bot.id
+		msg.WebhookID = bot.id
+		msg.Username = bot.BotName
+		msg.Avatar = bot.botAvatar
+	}
+
+	message, err := ch.messages.createMessage(ctx, msg)
+	if err != nil {
+		return commandOutcome{}, true, err
+	}
+	return commandOutcome{message: message}, true, nil
+}
+
+// hasPermission verifica se o usuário pode usar um comando com a permissão informada
+func (ch *CommandHandler) hasPermission(permission, channelID, serverID, userID string) (bool, error) {
+	switch permission {
+	case commands.PermissionEveryone:
+		return true, nil
+	case commands.PermissionModerator:
+		return ch.db.IsChannelModerator(channelID, userID)
+	case commands.PermissionAdmin:
+		if serverID == "" {
+			return false, nil
+		}
+		return ch.db.IsServerAdmin(serverID, userID)
+	default:
+		return false, nil
+	}
+}
+
+// ==================== COMANDOS EMBUTIDOS ====================
+
+// remindCommand agenda um lembrete entregue apenas ao autor, no mesmo canal.
+// Os lembretes vivem no agendador em memória da instância que recebeu o comando.
+func (ch *CommandHandler) remindCommand(ctx context.Context, inv *commandInvocation) (*CommandReply, error) {
+	delay := inv.options.Duration("when")
+	if delay > maxReminderDelay {
+		return nil, &commandError{http.StatusBadRequest, fmt.Sprintf("reminders can be at most %s away", formatDuration(maxReminderDelay))}
+	}
+	text := inv.options.String("text")
+
+	reminderID := gocql.TimeUUID().String()
+	channelID, userID := inv.channelID, inv.userID
+	ch.messages.scheduler.Schedule("reminder:"+reminderID, time.Now().Add(delay), func() {
+		ch.messages.events.PublishUserEvent(context.Background(), userID, "message.ephemeral", MessageResponse{
+			ID:        gocql.TimeUUID().String(),
+			ChannelID: channelID,
+			Username:  systemUsername,
+			Content:   "⏰ Reminder: " + text,
+			Timestamp: time.Now().UnixMilli(),
+			Ephemeral: true,
+		})
+	})
+
+	return &CommandReply{
+		Content:   fmt.Sprintf("Okay, I'll remind you in %s: %s", formatDuration(delay), text),
+		Ephemeral: true,
+	}, nil
+}
+
+// pollCommand cria uma mensagem de enquete, como POST /api/messages com "poll"
+func (ch *CommandHandler) pollCommand(ctx context.Context, inv *commandInvocation) (*CommandReply, error) {
+	poll := &PollRequest{
+		Question:    inv.options.String("question"),
+		Options:     splitChoices(inv.options.String("choices")),
+		MultiSelect: inv.options.Bool("multi"),
+		Duration:    int(inv.options.Duration("duration").Seconds()),
+	}
+	if errMsg := validatePoll(poll); errMsg != "" {
+		return nil, &commandError{http.StatusBadRequest, errMsg}
+	}
+
+	message, err := ch.messages.createMessage(ctx, newMessage{
+		ID:        inv.messageID,
+		ChannelID: inv.channelID,
+		AuthorID:  inv.userID,
+		Username:  inv.username,
+		Content:   poll.Question,
+		Poll:      poll,
+		TTL:       resolveMessageTTL(inv.channelRow, nil),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	inv.created = &message
+	return nil, nil
+}
+
+// taskCommand adiciona uma tarefa ao quadro do canal e anuncia no chat
+func (ch *CommandHandler) taskCommand(ctx context.Context, inv *commandInvocation) (*CommandReply, error) {
+	title := inv.options.String("title")
+	if len(title) > 200 {
+		return nil, &commandError{http.StatusBadRequest, "task title must be at most 200 characters"}
+	}
+
+	task, err := ch.tasks.createTask(inv.channelID, TaskRequest{Title: &title})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CommandReply{
+		Content: fmt.Sprintf("added a task: **%s**", task.Title),
+		Embeds: []models.Embed{{
+			Type:   "rich",
+			Title:  task.Title,
+			Fields: []models.EmbedField{{Name: "Status", Value: task.Status, Inline: true}, {Name: "Priority", Value: task.Priority, Inline: true}},
+		}},
+	}, nil
+}
+
+// splitChoices separa as opções de /poll ("a | b | c" ou "a, b, c")
+func splitChoices(value string) []string {
+	separator := "|"
+	if !strings.Contains(value, "|") {
+		separator = ","
+	}
+
+	var choices []string
+	for _, choice := range strings.Split(value, separator) {
+		if choice = strings.TrimSpace(choice); choice != "" {
+			choices = append(choices, choice)
+		}
+	}
+	return choices
+}
+
+// formatDuration formata durações de comandos (ex.: 1d2h, 1h30m, 45s)
+func formatDuration(d time.Duration) string {
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	var b strings.Builder
+	for _, unit := range units {
+		if n := d / unit.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+			d -= n * unit.size
+		}
+	}
+	if b.Len() == 0 {
+		return "0s"
+	}
+	return b.String()
+}
+
+// ==================== COMANDOS DE BOTS ====================
+
+// BotInteraction é o corpo enviado ao endpoint de um bot quando seu comando é usado.
+// A requisição é assinada como os webhooks de saída (X-Nexus-Signature).
+type BotInteraction struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"` // "command"
+	Command   string                 `json:"command"`
+	Options   map[string]interface{} `json:"options"`
+	ChannelID string                 `json:"channelId"`
+	ServerID  string                 `json:"serverId"`
+	User      BotInteractionUser     `json:"user"`
+	Timestamp time.Time              `json:"timestamp"`
+}
+
+// BotInteractionUser identifica quem executou o comando
+type BotInteractionUser struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// invokeBot envia a interação ao endpoint do bot e valida a resposta
+func (ch *CommandHandler) invokeBot(ctx context.Context, bot *botCommand, inv *commandInvocation) (*CommandReply, error) {
+	options := make(map[string]interface{}, len(inv.options))
+	for name, value := range inv.options {
+		// Durações vão em segundos
+		if d, ok := value.(time.Duration); ok {
+			value = int64(d.Seconds())
+		}
+		options[name] = value
+	}
+
+	interactionID := gocql.TimeUUID().String()
+	body, err := json.Marshal(BotInteraction{
+		ID:        interactionID,
+		Type:      "command",
+		Command:   bot.Name,
+		Options:   options,
+		ChannelID: inv.channelID,
+		ServerID:  inv.serverID,
+		User:      BotInteractionUser{ID: inv.userID, Username: inv.username},
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, botCommandTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, bot.callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(services.WebhookEventHeader, "command")
+	req.Header.Set(services.WebhookDeliveryHeader, interactionID)
+	req.Header.Set(services.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(services.WebhookSignatureHeader, services.SignWebhookPayload(bot.secret, timestamp, body))
+
+	resp, err := ch.client.Do(req)
+	if err != nil {
+		ch.logger.Warn("bot command request failed", zap.Error(err), zap.String("command", bot.Name))
+		return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s did not respond", bot.BotName)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		ch.logger.Warn("bot command returned an error", zap.Int("status", resp.StatusCode), zap.String("command", bot.Name))
+		return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s failed to run /%s", bot.BotName, bot.Name)}
+	}
+
+	var reply CommandReply
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBotReplySize)).Decode(&reply); err != nil {
+		return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s sent an invalid reply", bot.BotName)}
+	}
+
+	if reply.Content == "" && len(reply.Embeds) == 0 {
+		return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s sent an empty reply", bot.BotName)}
+	}
+	if reply.Content != "" {
+		if err := validation.ValidateMessageContent(reply.Content); err != nil {
+			return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s sent an invalid reply: %v", bot.BotName, err)}
+		}
+	}
+	if err := validation.ValidateEmbeds(reply.Embeds); err != nil {
+		return nil, &commandError{http.StatusBadGateway, fmt.Sprintf("%s sent an invalid reply: %v", bot.BotName, err)}
+	}
+
+	return &reply, nil
+}
+
+// serverBotCommands retorna os comandos de bots de um servidor (com cache)
+func (ch *CommandHandler) serverBotCommands(serverID string) ([]botCommand, error) {
+	if cached, ok := ch.bots.Get(serverID); ok {
+		return cached.([]botCommand), nil
+	}
+
+	rows, err := ch.db.GetServerBotCommands(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	bots := make([]botCommand, 0, len(rows))
+	for _, row := range rows {
+		bot, err := botCommandFromRow(row)
+		if err != nil {
+			ch.logger.Warn("invalid bot command", zap.Error(err), zap.String("serverId", serverID))
+			continue
+		}
+		bots = append(bots, bot)
+	}
+
+	ch.bots.Set(serverID, bots)
+	return bots, nil
+}
+
+// BotCommandRequest representa o registro de um comando de bot
+type BotCommandRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Options     []commands.Option `json:"options,omitempty"`
+	Permission  string            `json:"permission,omitempty"` // "", "moderator", "admin"
+	BotName     string            `json:"botName"`
+	BotAvatar   string            `json:"botAvatar,omitempty"`
+	CallbackURL string            `json:"callbackUrl"`
+}
+
+// BotCommandResponse representa um comando de bot. O segredo só é retornado no registro
+type BotCommandResponse struct {
+	commands.Command
+	ID          string `json:"id"`
+	ServerID    string `json:"serverId"`
+	BotAvatar   string `json:"botAvatar,omitempty"`
+	CallbackURL string `json:"callbackUrl"`
+	CreatedBy   string `json:"createdBy"`
+	CreatedAt   int64  `json:"createdAt"`
+	Secret      string `json:"secret,omitempty"`
+}
+
+// ListBotCommands lista os comandos de bots de um servidor (owners e admins)
+func (ch *CommandHandler) ListBotCommands(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := ch.requireServerAdmin(w, r, serverID); !ok {
+		return
+	}
+
+	rows, err := ch.db.GetServerBotCommands(serverID)
+	if err != nil {
+		ch.logger.Error("failed to get bot commands", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	list := make([]BotCommandResponse, 0, len(rows))
+	for _, row := range rows {
+		bot, err := botCommandFromRow(row)
+		if err != nil {
+			continue
+		}
+		list = append(list, botCommandResponse(bot, row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// CreateBotCommand registra um comando de bot no servidor (owners e admins)
+func (ch *CommandHandler) CreateBotCommand(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	var req BotCommandRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	cmd := commands.Command{
+		Name:        strings.ToLower(strings.TrimSpace(req.Name)),
+		Description: strings.TrimSpace(req.Description),
+		Options:     req.Options,
+		Permission:  req.Permission,
+		Source:      commands.SourceBot,
+		BotName:     strings.TrimSpace(req.BotName),
+	}
+	if err := cmd.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, exists := ch.registry.Get(cmd.Name); exists {
+		http.Error(w, fmt.Sprintf("/%s is a built-in command", cmd.Name), http.StatusConflict)
+		return
+	}
+	if cmd.BotName == "" || len(cmd.BotName) > maxBotNameLength {
+		http.Error(w, fmt.Sprintf("bot name must be between 1 and %d characters", maxBotNameLength), http.StatusBadRequest)
+		return
+	}
+	if !validAvatarURL(req.BotAvatar) {
+		http.Error(w, "bot avatar url must be http(s)", http.StatusBadRequest)
+		return
+	}
+	if err := ch.dispatcher.ValidateURL(req.CallbackURL); err != nil {
+		http.Error(w, "callback "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := ch.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	existing, err := ch.db.GetServerBotCommands(serverID)
+	if err != nil {
+		ch.logger.Error("failed to get bot commands", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxBotCommandsPerServer {
+		http.Error(w, fmt.Sprintf("a server can have at most %d bot commands", maxBotCommandsPerServer), http.StatusBadRequest)
+		return
+	}
+
+	secret, _, err := generateWebhookToken()
+	if err != nil {
+		ch.logger.Error("failed to generate command secret", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	optionsJSON, _ := json.Marshal(cmd.Options)
+	commandID := uuid.Must(uuid.NewV4()).String()
+
+	applied, err := ch.db.CreateBotCommand(serverID, cmd.Name, commandID, cmd.Description, string(optionsJSON), cmd.Permission,
+		cmd.BotName, req.BotAvatar, req.CallbackURL, secret, claims.UserID)
+	if err != nil {
+		ch.logger.Error("failed to create bot command", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !applied {
+		http.Error(w, fmt.Sprintf("/%s is already registered in this server", cmd.Name), http.StatusConflict)
+		return
+	}
+	ch.bots.Delete(serverID)
+
+	ch.logger.Info("bot command registered",
+		zap.String("command", cmd.Name),
+		zap.String("serverId", serverID),
+		zap.String("userId", claims.UserID),
+	)
+
+	response := BotCommandResponse{
+		Command:     cmd,
+		ID:          commandID,
+		ServerID:    serverID,
+		BotAvatar:   req.BotAvatar,
+		CallbackURL: req.CallbackURL,
+		CreatedBy:   claims.UserID,
+		CreatedAt:   time.Now().UnixMilli(),
+		Secret:      secret,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteBotCommand remove um comando de bot (?serverId=&name=)
+func (ch *CommandHandler) DeleteBotCommand(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	name := r.URL.Query().Get("name")
+	if serverID == "" || name == "" {
+		http.Error(w, "server id and command name required", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := ch.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	if _, err := ch.db.GetBotCommand(serverID, name); err == gocql.ErrNotFound {
+		http.Error(w, "command not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		ch.logger.Error("failed to get bot command", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := ch.db.DeleteBotCommand(serverID, name); err != nil {
+		ch.logger.Error("failed to delete bot command", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	ch.bots.Delete(serverID)
+
+	ch.logger.Info("bot command deleted", zap.String("command", name), zap.String("serverId", serverID), zap.String("userId", claims.UserID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireServerAdmin verifica se o usuário é dono ou admin do servidor.
+// Escreve a resposta de erro e retorna false caso contrário.
+func (ch *CommandHandler) requireServerAdmin(w http.ResponseWriter, r *http.Request, serverID string) (*models.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	isAdmin, err := ch.db.IsServerAdmin(serverID, claims.UserID)
+	if err != nil {
+		ch.logger.Error("failed to check server admin", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !isAdmin {
+		http.Error(w, "forbidden: only server owners and admins can manage bot commands", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// botCommandFromRow converte uma linha de bot_commands
+func botCommandFromRow(row map[string]interface{}) (botCommand, error) {
+	var options []commands.Option
+	if raw := row["options"].(string); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &options); err != nil {
+			return botCommand{}, err
+		}
+	}
+
+	return botCommand{
+		Command: commands.Command{
+			Name:        row["name"].(string),
+			Description: row["description"].(string),
+			Options:     options,
+			Permission:  row["permission"].(string),
+			Source:      commands.SourceBot,
+			BotName:     row["bot_name"].(string),
+		},
+		id:          row["command_id"].(string),
+		botAvatar:   row["bot_avatar"].(string),
+		callbackURL: row["callback_url"].(string),
+		secret:      row["secret"].(string),
+	}, nil
+}
+
+func botCommandResponse(bot botCommand, row map[string]interface{}) BotCommandResponse {
+	response := BotCommandResponse{
+		Command:     bot.Command,
+		ID:          bot.id,
+		ServerID:    row["server_id"].(string),
+		BotAvatar:   bot.botAvatar,
+		CallbackURL: bot.callbackURL,
+		CreatedBy:   row["created_by"].(string),
+	}
+	if createdAt, ok := row["created_at"].(time.Time); ok {
+		response.CreatedAt = createdAt.UnixMilli()
+	}
+	return response
+}
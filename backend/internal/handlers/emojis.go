@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+const (
+	maxEmojisPerServer  = 50
+	maxEmojiFileSize    = 256 * 1024 // 256KB
+	maxEmojiSize        = 128        // pixels (largura e altura)
+	maxEmojisPerMessage = 50
+
+	emojiCacheTTL = 5 * time.Minute
+)
+
+var (
+	// emojiNamePattern define os nomes aceitos para emojis (ex.: party_parrot)
+	emojiNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,32}$`)
+
+	// emojiTokenPattern reconhece referências a emojis customizados: <:nome:id>
+	emojiTokenPattern = regexp.MustCompile(`<:([A-Za-z0-9_]{2,32}):([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})>`)
+)
+
+// EmojiHandler gerencia os emojis customizados dos servidores
+type EmojiHandler struct {
+	logger       *zap.Logger
+	db           *database.CassandraDB
+	policy       *access.Policy
+	events       *services.EventService
+	imageService *services.ImageService
+	resolved     *cache.MemoryCache // emojiID -> *EmojiResponse (nil quando não existe)
+}
+
+// NewEmojiHandler cria o handler de emojis. O MessageHandler passa a resolver
+// as referências <:nome:id> das mensagens por meio dele.
+func NewEmojiHandler(logger *zap.Logger, db *database.CassandraDB, events *services.EventService, messages *MessageHandler, uploadDir string) *EmojiHandler {
+	eh := &EmojiHandler{
+		logger:       logger,
+		db:           db,
+		policy:       messages.policy,
+		events:       events,
+		imageService: services.NewImageService(logger, uploadDir, maxEmojiFileSize),
+		resolved:     cache.NewMemoryCache(emojiCacheTTL),
+	}
+
+	messages.emojis = eh
+	return eh
+}
+
+// EmojiResponse representa um emoji customizado
+type EmojiResponse struct {
+	ID        string `json:"id"`
+	ServerID  string `json:"serverId"`
+	Name      string `json:"name"`
+	URL       string `json:"url"`
+	CreatedBy string `json:"createdBy,omitempty"`
+	CreatedAt int64  `json:"createdAt,omitempty"`
+}
+
+// EmojiRenameRequest representa a troca de nome de um emoji
+type EmojiRenameRequest struct {
+	Name string `json:"name"`
+}
+
+// ListEmojis lista os emojis de um servidor (membros do servidor)
+func (eh *EmojiHandler) ListEmojis(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := authorizeServer(w, r, eh.logger, eh.policy, serverID); !ok {
+		return
+	}
+
+	rows, err := eh.db.GetServerEmojis(serverID)
+	if err != nil {
+		eh.logger.Error("failed to get server emojis", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	emojis := make([]EmojiResponse, 0, len(rows))
+	for _, row := range rows {
+		emojis = append(emojis, emojiFromRow(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emojis)
+}
+
+// UploadEmoji adiciona um emoji ao servidor (multipart: "image" e "name")
+func (eh *EmojiHandler) UploadEmoji(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := eh.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxEmojiFileSize+64*1024)
+	if err := r.ParseMultipartForm(maxEmojiFileSize); err != nil {
+		eh.logger.Warn("failed to parse multipart form", zap.Error(err))
+		http.Error(w, "file too large or invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	name := r.FormValue("name")
+	if !emojiNamePattern.MatchString(name) {
+		http.Error(w, "emoji name must be 2-32 letters, digits or underscores", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		http.Error(w, "image file is required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if err := eh.imageService.ValidateImage(file, header); err != nil {
+		eh.logger.Warn("emoji validation failed", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	existing, err := eh.db.GetServerEmojis(serverID)
+	if err != nil {
+		eh.logger.Error("failed to get server emojis", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxEmojisPerServer {
+		http.Error(w, fmt.Sprintf("a server can have at most %d emojis", maxEmojisPerServer), http.StatusBadRequest)
+		return
+	}
+	for _, row := range existing {
+		if row["name"].(string) == name {
+			http.Error(w, fmt.Sprintf("an emoji named %q already exists", name), http.StatusConflict)
+			return
+		}
+	}
+
+	config := services.ImageConfig{
+		MaxWidth:  maxEmojiSize,
+		MaxHeight: maxEmojiSize,
+		Quality:   90,
+	}
+
+	filename, err := eh.imageService.ProcessImage(file, serverID, "emoji", config)
+	if err != nil {
+		eh.logger.Error("failed to process emoji image", zap.Error(err))
+		http.Error(w, "failed to process image", http.StatusInternalServerError)
+		return
+	}
+
+	emojiID := uuid.Must(uuid.NewV4()).String()
+	applied, err := eh.db.CreateEmoji(emojiID, serverID, name, filename, claims.UserID)
+	if err != nil || !applied {
+		eh.imageService.DeleteImage(filename)
+		if err != nil {
+			eh.logger.Error("failed to create emoji", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, fmt.Sprintf("an emoji named %q already exists", name), http.StatusConflict)
+		return
+	}
+
+	// Uploads simultâneos podem passar juntos pela verificação do limite: recontar e desfazer
+	// o emoji se ele não estiver entre os maxEmojisPerServer mais antigos do servidor
+	within, err := eh.withinEmojiLimit(serverID, emojiID)
+	if err != nil || !within {
+		if err := eh.db.DeleteEmoji(emojiID, serverID, name); err != nil {
+			eh.logger.Error("failed to roll back emoji over limit", zap.Error(err), zap.String("id", emojiID))
+		}
+		eh.imageService.DeleteImage(filename)
+		if err != nil {
+			eh.logger.Error("failed to recount server emojis", zap.Error(err), zap.String("serverId", serverID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		http.Error(w, fmt.Sprintf("a server can have at most %d emojis", maxEmojisPerServer), http.StatusBadRequest)
+		return
+	}
+
+	emoji := EmojiResponse{
+		ID:        emojiID,
+		ServerID:  serverID,
+		Name:      name,
+		URL:       emojiURL(filename),
+		CreatedBy: claims.UserID,
+		CreatedAt: time.Now().UnixMilli(),
+	}
+	eh.resolved.Delete(emojiID)
+
+	eh.events.PublishServerEvent(r.Context(), serverID, "emoji.create", emoji)
+
+	eh.logger.Info("emoji created",
+		zap.String("id", emojiID),
+		zap.String("name", name),
+		zap.String("serverId", serverID),
+		zap.String("userId", claims.UserID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(emoji)
+}
+
+// withinEmojiLimit verifica se o emoji está entre os maxEmojisPerServer mais antigos do
+// servidor (criação e ID desempatam, então uploads concorrentes chegam ao mesmo resultado)
+func (eh *EmojiHandler) withinEmojiLimit(serverID, emojiID string) (bool, error) {
+	rows, err := eh.db.GetServerEmojis(serverID)
+	if err != nil {
+		return false, err
+	}
+
+	var createdAt time.Time
+	for _, row := range rows {
+		if row["emoji_id"].(string) == emojiID {
+			createdAt = row["created_at"].(time.Time)
+		}
+	}
+
+	older := 0
+	for _, row := range rows {
+		id, at := row["emoji_id"].(string), row["created_at"].(time.Time)
+		if at.Before(createdAt) || (at.Equal(createdAt) && id < emojiID) {
+			older++
+		}
+	}
+	return older < maxEmojisPerServer, nil
+}
+
+// RenameEmoji troca o nome de um emoji (?serverId=&id=)
+func (eh *EmojiHandler) RenameEmoji(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	emojiID := r.URL.Query().Get("id")
+	if serverID == "" || emojiID == "" {
+		http.Error(w, "server id and emoji id required", http.StatusBadRequest)
+		return
+	}
+
+	var req EmojiRenameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !emojiNamePattern.MatchString(req.Name) {
+		http.Error(w, "emoji name must be 2-32 letters, digits or underscores", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := eh.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	row, ok := eh.getServerEmoji(w, serverID, emojiID)
+	if !ok {
+		return
+	}
+
+	oldName := row["name"].(string)
+	if oldName != req.Name {
+		applied, err := eh.db.RenameEmoji(emojiID, serverID, oldName, req.Name)
+		if err != nil {
+			eh.logger.Error("failed to rename emoji", zap.Error(err), zap.String("id", emojiID))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !applied {
+			http.Error(w, fmt.Sprintf("an emoji named %q already exists", req.Name), http.StatusConflict)
+			return
+		}
+		eh.resolved.Delete(emojiID)
+	}
+
+	row["name"] = req.Name
+	emoji := emojiFromRow(row)
+
+	eh.events.PublishServerEvent(r.Context(), serverID, "emoji.update", emoji)
+
+	eh.logger.Info("emoji renamed",
+		zap.String("id", emojiID),
+		zap.String("from", oldName),
+		zap.String("to", req.Name),
+		zap.String("userId", claims.UserID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(emoji)
+}
+
+// DeleteEmoji remove um emoji do servidor (?serverId=&id=)
+func (eh *EmojiHandler) DeleteEmoji(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	emojiID := r.URL.Query().Get("id")
+	if serverID == "" || emojiID == "" {
+		http.Error(w, "server id and emoji id required", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := eh.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	row, ok := eh.getServerEmoji(w, serverID, emojiID)
+	if !ok {
+		return
+	}
+
+	if err := eh.db.DeleteEmoji(emojiID, serverID, row["name"].(string)); err != nil {
+		eh.logger.Error("failed to delete emoji", zap.Error(err), zap.String("id", emojiID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	eh.resolved.Delete(emojiID)
+
+	if err := eh.imageService.DeleteImage(row["filename"].(string)); err != nil {
+		eh.logger.Warn("failed to delete emoji image", zap.Error(err), zap.String("id", emojiID))
+	}
+
+	eh.events.PublishServerEvent(r.Context(), serverID, "emoji.delete", map[string]string{
+		"id":       emojiID,
+		"serverId": serverID,
+	})
+
+	eh.logger.Info("emoji deleted", zap.String("id", emojiID), zap.String("serverId", serverID), zap.String("userId", claims.UserID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getServerEmoji busca um emoji e verifica se pertence ao servidor.
+// Escreve a resposta de erro e retorna false caso contrário.
+func (eh *EmojiHandler) getServerEmoji(w http.ResponseWriter, serverID, emojiID string) (map[string]interface{}, bool) {
+	row, err := eh.db.GetEmoji(emojiID)
+	if err == gocql.ErrNotFound || (err == nil && row["server_id"].(string) != serverID) {
+		http.Error(w, "emoji not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		eh.logger.Error("failed to get emoji", zap.Error(err), zap.String("id", emojiID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	return row, true
+}
+
+// requireServerAdmin verifica se o usuário é dono ou admin do servidor.
+// Escreve a resposta de erro e retorna false caso contrário.
+func (eh *EmojiHandler) requireServerAdmin(w http.ResponseWriter, r *http.Request, serverID string) (*models.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	isAdmin, err := eh.db.IsServerAdmin(serverID, claims.UserID)
+	if err != nil {
+		eh.logger.Error("failed to check server admin", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !isAdmin {
+		http.Error(w, "forbidden: only server owners and admins can manage emojis", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// resolveEmojis retorna os emojis customizados referenciados no conteúdo (<:nome:id>).
+// Referências a emojis inexistentes ou removidos são ignoradas; o cliente exibe o texto :nome:
+func (eh *EmojiHandler) resolveEmojis(content string) []EmojiResponse {
+	var emojis []EmojiResponse
+	for _, emojiID := range parseEmojiTokens(content) {
+		if emoji := eh.lookup(emojiID); emoji != nil {
+			emojis = append(emojis, *emoji)
+		}
+	}
+	return emojis
+}
+
+// lookup busca um emoji pelo ID (com cache, incluindo emojis inexistentes)
+func (eh *EmojiHandler) lookup(emojiID string) *EmojiResponse {
+	if cached, ok := eh.resolved.Get(emojiID); ok {
+		return cached.(*EmojiResponse)
+	}
+
+	var emoji *EmojiResponse
+	row, err := eh.db.GetEmoji(emojiID)
+	switch {
+	case err == nil:
+		e := emojiFromRow(row)
+		e.CreatedBy, e.CreatedAt = "", 0
+		emoji = &e
+	case err != gocql.ErrNotFound:
+		eh.logger.Warn("failed to get emoji", zap.Error(err), zap.String("id", emojiID))
+		return nil
+	}
+
+	eh.resolved.Set(emojiID, emoji)
+	return emoji
+}
+
+// parseEmojiTokens extrai os IDs (sem repetição) dos emojis referenciados como <:nome:id>
+func parseEmojiTokens(content string) []string {
+	matches := emojiTokenPattern.FindAllStringSubmatch(content, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, match := range matches {
+		if seen[match[2]] {
+			continue
+		}
+		seen[match[2]] = true
+		ids = append(ids, match[2])
+		if len(ids) == maxEmojisPerMessage {
+			break
+		}
+	}
+	return ids
+}
+
+func emojiFromRow(row map[string]interface{}) EmojiResponse {
+	emoji := EmojiResponse{
+		ID:        row["emoji_id"].(string),
+		ServerID:  row["server_id"].(string),
+		Name:      row["name"].(string),
+		URL:       emojiURL(row["filename"].(string)),
+		CreatedBy: row["created_by"].(string),
+	}
+	if createdAt, ok := row["created_at"].(time.Time); ok {
+		emoji.CreatedAt = createdAt.UnixMilli()
+	}
+	return emoji
+}
+
+// emojiURL retorna a URL pública da imagem do emoji
+func emojiURL(filename string) string {
+	return "/api/images/" + filename
+}
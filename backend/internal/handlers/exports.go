@@ -0,0 +1,314 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/export"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+// jobTypeExport identifica jobs de exportação de histórico
+const jobTypeExport = "channel.export"
+
+// unsafeFilenameChars é usado para montar o nome do arquivo baixado a partir do nome do canal
+var unsafeFilenameChars = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+
+// ExportHandler gerencia a exportação do histórico de canais
+type ExportHandler struct {
+	logger    *zap.Logger
+	db        *database.CassandraDB
+	policy    *access.Policy
+	jobs      *services.JobManager
+	exportDir string
+}
+
+// NewExportHandler cria um novo handler de exportação
+func NewExportHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy, jobs *services.JobManager, exportDir string) *ExportHandler {
+	if err := os.MkdirAll(exportDir, 0755); err != nil {
+		logger.Error("failed to create export directory", zap.Error(err))
+	}
+
+	return &ExportHandler{
+		logger:    logger,
+		db:        db,
+		policy:    policy,
+		jobs:      jobs,
+		exportDir: exportDir,
+	}
+}
+
+// ExportRequest representa a requisição de exportação de um canal
+type ExportRequest struct {
+	Format string `json:"format"`         // "json", "html" ou "txt"
+	From   *int64 `json:"from,omitempty"` // timestamp em milissegundos (padrão: criação do canal)
+	To     *int64 `json:"to,omitempty"`   // timestamp em milissegundos (padrão: agora)
+}
+
+// StartExport inicia um job de exportação do histórico de um canal
+func (eh *ExportHandler) StartExport(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	var req ExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = export.FormatJSON
+	}
+	if !export.ValidFormat(req.Format) {
+		http.Error(w, "format must be one of: json, html, txt", http.StatusBadRequest)
+		return
+	}
+
+	channelRow, claims, ok := authorizeChannel(w, r, eh.logger, eh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	// Intervalo: padrão é todo o histórico do canal
+	to := time.Now()
+	if req.To != nil {
+		to = time.UnixMilli(*req.To)
+	}
+	from, _ := channelRow["created_at"].(time.Time)
+	if from.IsZero() {
+		// Canais antigos sem created_at: cobrir os últimos anos
+		from = to.AddDate(-5, 0, 0)
+	}
+	if req.From != nil {
+		from = time.UnixMilli(*req.From)
+	}
+	if from.After(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	header := export.Header{
+		Channel: export.Channel{
+			ID:   channelID,
+			Name: channelRow["name"].(string),
+		},
+		From: from,
+		To:   to,
+	}
+	format := req.Format
+
+	job := eh.jobs.Start(jobTypeExport, claims.UserID, channelID, func(ctx context.Context, progress *services.JobProgress) (map[string]interface{}, error) {
+		return eh.runExport(ctx, progress, header, format)
+	})
+
+	eh.logger.Info("channel export started",
+		zap.String("jobId", job.ID),
+		zap.String("channelId", channelID),
+		zap.String("userId", claims.UserID),
+		zap.String("format", format),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetExport retorna o andamento de um job de exportação
+func (eh *ExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := eh.ownedJob(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// DownloadExport envia o arquivo de um job de exportação concluído
+func (eh *ExportHandler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	job, ok := eh.ownedJob(w, r)
+	if !ok {
+		return
+	}
+
+	if job.Status != services.JobCompleted {
+		http.Error(w, "export is not ready", http.StatusConflict)
+		return
+	}
+
+	format, _ := job.Result["format"].(string)
+	filename, _ := job.Result["filename"].(string)
+
+	file, err := os.Open(eh.exportPath(job.ID, format))
+	if err != nil {
+		eh.logger.Error("failed to open export file", zap.Error(err), zap.String("jobId", job.ID))
+		http.Error(w, "export file not found", http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", export.ContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	io.Copy(w, file)
+}
+
+// ownedJob busca o job de exportação indicado em ?id=, visível apenas para quem o criou
+func (eh *ExportHandler) ownedJob(w http.ResponseWriter, r *http.Request) (services.Job, bool) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "export id required", http.StatusBadRequest)
+		return services.Job{}, false
+	}
+
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return services.Job{}, false
+	}
+
+	job, ok := eh.jobs.Get(jobID)
+	if !ok || job.Type != jobTypeExport || job.OwnerID != claims.UserID {
+		http.Error(w, "export not found", http.StatusNotFound)
+		return services.Job{}, false
+	}
+
+	return job, true
+}
+
+// exportPath retorna o caminho do arquivo gerado por um job
+func (eh *ExportHandler) exportPath(jobID, format string) string {
+	return filepath.Join(eh.exportDir, jobID+"."+format)
+}
+
+// runExport percorre todos os buckets do canal no intervalo e escreve o transcript em disco
+func (eh *ExportHandler) runExport(ctx context.Context, progress *services.JobProgress, header export.Header, format string) (map[string]interface{}, error) {
+	jobID := progress.JobID()
+	path := eh.exportPath(jobID, format)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+	eh.jobs.OnDiscard(jobID, func() {
+		os.Remove(path)
+	})
+
+	buffered := bufio.NewWriter(file)
+	writer, err := export.NewWriter(format, buffered)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	header.ExportedAt = time.Now()
+	if err := writer.Begin(header); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	authors := make(map[string]string)
+	count := 0
+	buckets := database.MessageBuckets(header.From, header.To)
+
+	for i, bucket := range buckets {
+		if err := ctx.Err(); err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		err := eh.db.IterateBucketMessages(header.Channel.ID, bucket, header.From, header.To, false, func(row map[string]interface{}) error {
+			msg := export.Message{
+				ID:        row["msg_id"].(string),
+				AuthorID:  row["author_id"].(string),
+				Content:   row["content"].(string),
+				Type:      row["type"].(string),
+				Timestamp: row["ts"].(time.Time),
+			}
+			if name, ok := row["author_name"].(string); ok {
+				// Mensagem de webhook: nome exibido no momento do envio
+				msg.Author = name
+			} else {
+				msg.Author = eh.resolveAuthor(authors, msg.AuthorID)
+			}
+			if editedAt, ok := row["edited_at"].(time.Time); ok {
+				msg.EditedAt = &editedAt
+			}
+			if raw, ok := row["reference"].(string); ok {
+				// Mensagem encaminhada: a citação entra no texto do transcript
+				var reference MessageReference
+				if err := json.Unmarshal([]byte(raw), &reference); err == nil {
+					msg.Content = quoteForward(msg.Content, reference)
+				}
+			}
+			msg.Attachments = export.AttachmentRefs(msg.Content)
+
+			count++
+			return writer.Write(msg)
+		})
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to export bucket %d: %w", bucket, err)
+		}
+
+		progress.Update(float64(i+1)/float64(len(buckets)), count)
+	}
+
+	if err := writer.End(count); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := buffered.Flush(); err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Close(); err != nil {
+		return nil, err
+	}
+
+	filename := unsafeFilenameChars.ReplaceAllString(header.Channel.Name, "-")
+	if filename == "" {
+		filename = header.Channel.ID
+	}
+
+	return map[string]interface{}{
+		"format":      format,
+		"filename":    filename + "-export." + format,
+		"messages":    count,
+		"downloadUrl": "/api/channels/export/download?id=" + jobID,
+	}, nil
+}
+
+// resolveAuthor retorna o nome de exibição do autor, com cache durante a exportação
+func (eh *ExportHandler) resolveAuthor(authors map[string]string, authorID string) string {
+	if name, ok := authors[authorID]; ok {
+		return name
+	}
+
+	name := "Unknown User"
+	userRow, err := eh.db.GetUserByID(authorID)
+	if err != nil {
+		eh.logger.Warn("failed to resolve export author", zap.Error(err), zap.String("authorId", authorID))
+	} else if displayName, ok := userRow["display_name"].(string); ok && displayName != "" {
+		name = displayName
+	} else if username, ok := userRow["username"].(string); ok && username != "" {
+		name = username
+	}
+
+	authors[authorID] = name
+	return name
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/export"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/validation"
+	"go.uber.org/zap"
+)
+
+// messageTypeForward identifica mensagens que encaminham (citam) outra mensagem
+const messageTypeForward = "forward"
+
+// ForwardRequest representa o encaminhamento de uma mensagem para outro canal
+type ForwardRequest struct {
+	ChannelID string `json:"channelId"`         // canal da mensagem original
+	MessageID string `json:"messageId"`         // mensagem original
+	Content   string `json:"content,omitempty"` // comentário opcional exibido acima da citação
+	Nonce     string `json:"nonce,omitempty"`
+}
+
+// MessageReference é a cópia da mensagem original guardada na mensagem encaminhada.
+// Edições e remoções posteriores da original não a alteram.
+type MessageReference struct {
+	ChannelID   string          `json:"channelId"`
+	ServerID    string          `json:"serverId,omitempty"`
+	MessageID   string          `json:"messageId"`
+	AuthorID    string          `json:"authorId"`
+	Username    string          `json:"username"`
+	Avatar      string          `json:"avatar,omitempty"`
+	Content     string          `json:"content"`
+	Type        string          `json:"type,omitempty"`
+	Embeds      []models.Embed  `json:"embeds,omitempty"`
+	Attachments []string        `json:"attachments,omitempty"` // arquivos referenciados no conteúdo (/api/images/...)
+	Emojis      []EmojiResponse `json:"emojis,omitempty"`
+	WebhookID   string          `json:"webhookId,omitempty"`
+	Timestamp   int64           `json:"timestamp"`
+	EditedAt    *int64          `json:"editedAt,omitempty"`
+}
+
+// ForwardMessage encaminha uma mensagem para o canal informado em ?channelId=
+func (mh *MessageHandler) ForwardMessage(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	var req ForwardRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.ChannelID == "" || req.MessageID == "" {
+		http.Error(w, "source channel id and message id required", http.StatusBadRequest)
+		return
+	}
+	if req.Content != "" {
+		if err := validation.ValidateMessageContent(req.Content); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if len(req.Nonce) > maxNonceLength {
+		http.Error(w, "nonce is too long", http.StatusBadRequest)
+		return
+	}
+
+	// O usuário precisa ter acesso aos dois canais
+	channelRow, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+	sourceRow, _, ok := authorizeChannel(w, r, mh.logger, mh.policy, req.ChannelID)
+	if !ok {
+		return
+	}
+
+	row, err := mh.db.GetMessage(req.ChannelID, req.MessageID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		mh.logger.Error("failed to get message to forward", zap.Error(err), zap.String("messageId", req.MessageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	reference := mh.referenceFromRow(row, access.ServerID(sourceRow))
+
+	// A cópia não sobrevive à original efêmera
+	ttl := resolveMessageTTL(channelRow, nil)
+	if expiresAt, ok := row["expires_at"].(time.Time); ok {
+		remaining := int(time.Until(expiresAt).Seconds()) + 1
+		if ttl == 0 || remaining < ttl {
+			ttl = remaining
+		}
+	}
+
+	messageID := gocql.TimeUUID()
+	if req.Nonce != "" {
+		applied, originalChannelID, originalMessageID, err := mh.db.ReserveMessageNonce(claims.UserID, req.Nonce, channelID, messageID.String(), nonceWindow)
+		if err != nil {
+			mh.logger.Error("failed to reserve message nonce", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !applied {
+			mh.replayMessage(w, originalChannelID, originalMessageID, req.Nonce)
+			return
+		}
+	}
+
+	if !mh.allowSend(claims.UserID, channelID, channelRow, w) {
+		mh.releaseNonce(claims.UserID, req.Nonce)
+		return
+	}
+
+	message, err := mh.createMessage(r.Context(), newMessage{
+		ID:        messageID,
+		ChannelID: channelID,
+		AuthorID:  claims.UserID,
+		Username:  claims.Username,
+		Content:   req.Content,
+		Reference: reference,
+		TTL:       ttl,
+		Nonce:     req.Nonce,
+	})
+	if err != nil {
+		mh.logger.Error("failed to save forwarded message", zap.Error(err))
+		mh.releaseNonce(claims.UserID, req.Nonce)
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	mh.logger.Info("message forwarded",
+		zap.String("id", message.ID),
+		zap.String("from", req.ChannelID+"/"+req.MessageID),
+		zap.String("channelId", channelID),
+		zap.String("userId", claims.UserID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// referenceFromRow monta a cópia de uma mensagem para encaminhamento.
+// Encaminhar um encaminhamento sem comentário cita a mensagem original.
+func (mh *MessageHandler) referenceFromRow(row map[string]interface{}, serverID string) *MessageReference {
+	source := mh.messageFromRow(row)
+	if source.Reference != nil && source.Content == "" {
+		return source.Reference
+	}
+
+	return &MessageReference{
+		ChannelID:   source.ChannelID,
+		ServerID:    serverID,
+		MessageID:   source.ID,
+		AuthorID:    source.UserID,
+		Username:    source.Username,
+		Avatar:      source.Avatar,
+		Content:     source.Content,
+		Type:        source.Type,
+		Embeds:      source.Embeds,
+		Attachments: export.AttachmentRefs(source.Content),
+		WebhookID:   source.WebhookID,
+		Timestamp:   source.Timestamp,
+		EditedAt:    source.EditedAt,
+	}
+}
+
+// quoteForward formata uma mensagem encaminhada como texto: o comentário seguido da citação
+func quoteForward(comment string, reference MessageReference) string {
+	var b strings.Builder
+	if comment != "" {
+		b.WriteString(comment + "\n")
+	}
+	b.WriteString("> Forwarded from " + reference.Username + ":")
+	for _, line := range strings.Split(reference.Content, "\n") {
+		b.WriteString("\n> " + line)
+	}
+	return b.String()
+}
@@ -51,9 +51,9 @@ type FriendResponse struct {
 	Nickname      string `json:"nickname,omitempty"`
 	Avatar        string `json:"avatar,omitempty"`
 	Bio           string `json:"bio,omitempty"`
-	Status        string `json:"status"` // online, offline, idle, dnd
 	DMChannelID   string `json:"dmChannelId"`
 	AddedAt       int64  `json:"addedAt"`
+	PresenceInfo
 }
 
 // SendFriendRequest envia uma solicitação de amizade
@@ -386,14 +386,13 @@ func (fh *FriendHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		// Buscar presença
-		presence, _ := fh.db.GetUserPresence(friendID)
-		status := "offline"
-		if presence != nil {
-			if s, ok := presence["status"].(string); ok {
-				status = s
-			}
+		// Buscar presença (status, atividades e status personalizado)
+		presenceRow, _ := fh.db.GetUserPresence(friendID)
+		customStatus := ""
+		if user != nil {
+			customStatus, _ = user["custom_status"].(string)
 		}
+		presence := buildPresence(presenceRow, customStatus, time.Now())
 
 		// Extrair dm_channel_id
 		var dmChannelID string
@@ -418,8 +417,8 @@ func (fh *FriendHandler) GetFriends(w http.ResponseWriter, r *http.Request) {
 			Discriminator: discriminator,
 			DisplayName:   displayName,
 			DMChannelID:   dmChannelID,
-			Status:        status,
 			AddedAt:       addedAt,
+			PresenceInfo:  presence,
 		}
 
 		if nickname, ok := row["nickname"].(string); ok && nickname != "" {
@@ -1,46 +1,116 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
 
-	"github.com/gofrs/uuid"
+	"github.com/gocql/gocql"
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/database"
 	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/ratelimit"
+	"github.com/nexus/backend/internal/services"
+	"github.com/nexus/backend/internal/unfurl"
 	"go.uber.org/zap"
 )
 
+const (
+	// maxMessageTTL é o maior TTL aceito para mensagens efêmeras (30 dias, em segundos)
+	maxMessageTTL = 30 * 24 * 60 * 60
+
+	// nonceWindow é por quanto tempo (em segundos) um nonce de envio é lembrado por autor
+	nonceWindow = 10 * 60
+
+	// maxNonceLength limita o tamanho do nonce/Idempotency-Key aceito
+	maxNonceLength = 64
+)
+
 // MessageHandler gerencia operações de mensagens
 type MessageHandler struct {
-	logger *zap.Logger
-	db     *database.CassandraDB
+	logger    *zap.Logger
+	db        *database.CassandraDB
+	policy    *access.Policy
+	events    *services.EventService
+	scheduler *services.Scheduler
+	limiter   *ratelimit.MessageLimiter
+	jobs      *services.JobManager
+	unfurler  *unfurl.Unfurler
+	commands  *CommandHandler // definido por NewCommandHandler
+	emojis    *EmojiHandler   // definido por NewEmojiHandler
 }
 
 // NewMessageHandler cria um novo handler de mensagens
-func NewMessageHandler(logger *zap.Logger, db *database.CassandraDB) *MessageHandler {
+func NewMessageHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy, events *services.EventService, scheduler *services.Scheduler, limiter *ratelimit.MessageLimiter, jobs *services.JobManager, unfurler *unfurl.Unfurler) *MessageHandler {
 	return &MessageHandler{
-		logger: logger,
-		db:     db,
+		logger:    logger,
+		db:        db,
+		policy:    policy,
+		events:    events,
+		scheduler: scheduler,
+		limiter:   limiter,
+		jobs:      jobs,
+		unfurler:  unfurler,
 	}
 }
 
 // MessageRequest representa a requisição de envio de mensagem
 type MessageRequest struct {
-	Content string `json:"content"`
+	Content string       `json:"content"`
+	TTL     *int         `json:"ttl,omitempty"`   // segundos até a mensagem expirar (opcional)
+	Nonce   string       `json:"nonce,omitempty"` // identificador do cliente para deduplicar reenvios
+	Poll    *PollRequest `json:"poll,omitempty"`  // transforma a mensagem em uma enquete
 }
 
-// MessageResponse representa uma mensagem
-type MessageResponse struct {
+// RateLimitResponse é o corpo da resposta 429 de envio de mensagens
+type RateLimitResponse struct {
+	Error      string  `json:"error"`
+	Reason     string  `json:"reason"`      // "slowmode", "burst"
+	RetryAfter float64 `json:"retry_after"` // segundos
+}
+
+// MessageUpdateEvent é o payload do evento message.update. Embeds traz sempre a lista completa
+// de embeds da mensagem; Content e EditedAt só vêm quando o texto foi editado
+type MessageUpdateEvent struct {
+	ID        string          `json:"id"`
+	ChannelID string          `json:"channelId"`
+	Content   *string         `json:"content,omitempty"`
+	Embeds    []models.Embed  `json:"embeds"`
+	Emojis    []EmojiResponse `json:"emojis,omitempty"` // emojis customizados do novo conteúdo
+	EditedAt  *int64          `json:"editedAt,omitempty"`
+}
+
+// MessageDeleteEvent é o payload do evento message.delete
+type MessageDeleteEvent struct {
 	ID        string `json:"id"`
 	ChannelID string `json:"channelId"`
-	UserID    string `json:"userId"`
-	Username  string `json:"username"`
-	Avatar    string `json:"avatar,omitempty"`
-	Content   string `json:"content"`
-	Timestamp int64  `json:"timestamp"`
-	EditedAt  *int64 `json:"editedAt,omitempty"`
+	Reason    string `json:"reason"` // "expired", "deleted"
+}
+
+// MessageResponse representa uma mensagem
+type MessageResponse struct {
+	ID        string            `json:"id"`
+	ChannelID string            `json:"channelId"`
+	UserID    string            `json:"userId"` // ID do webhook em mensagens de webhook
+	Username  string            `json:"username"`
+	Avatar    string            `json:"avatar,omitempty"`
+	Content   string            `json:"content"`
+	Type      string            `json:"type,omitempty"` // vazio para mensagens comuns, "poll"
+	Embeds    []models.Embed    `json:"embeds,omitempty"`
+	Emojis    []EmojiResponse   `json:"emojis,omitempty"` // emojis customizados referenciados como <:nome:id>
+	WebhookID string            `json:"webhookId,omitempty"`
+	Timestamp int64             `json:"timestamp"`
+	EditedAt  *int64            `json:"editedAt,omitempty"`
+	ExpiresAt *int64            `json:"expiresAt,omitempty"`
+	Nonce     string            `json:"nonce,omitempty"`
+	Poll      *PollResponse     `json:"poll,omitempty"`
+	Reference *MessageReference `json:"reference,omitempty"` // mensagem citada em encaminhamentos
+	Ephemeral bool              `json:"ephemeral,omitempty"` // visível apenas para quem executou o comando
 }
 
 // GetMessages retorna mensagens de um canal com paginação
@@ -70,6 +140,14 @@ func (mh *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	// Usuário autenticado (para marcar os votos em enquetes)
+	viewerID := claims.UserID
+
 	// Buscar mensagens do banco de dados
 	rows, err := mh.db.GetMessagesByChannel(channelID, limit+1, beforeTime) // +1 para verificar hasMore
 	if err != nil {
@@ -86,42 +164,14 @@ func (mh *MessageHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
 
 	messages := make([]MessageResponse, 0)
 	for _, row := range rows {
-		// Buscar username do usuário
-		username := "Unknown User"
-		authorID := row["author_id"].(string)
+		msg := mh.messageFromRow(row)
 
-		userRow, err := mh.db.GetUserByID(authorID)
-		if err != nil {
-			mh.logger.Warn("failed to get user info for message",
-				zap.Error(err),
-				zap.String("authorId", authorID),
-				zap.String("msgId", row["msg_id"].(string)),
-			)
-		} else {
-			if uname, ok := userRow["username"].(string); ok && uname != "" {
-				username = uname
-			} else {
-				mh.logger.Warn("username not found in user row",
-					zap.String("authorId", authorID),
-					zap.Any("userRow", userRow),
-				)
-			}
-		}
-
-		msg := MessageResponse{
-			ID:        row["msg_id"].(string),
-			ChannelID: row["channel_id"].(string),
-			UserID:    authorID,
-			Username:  username,
-			Content:   row["content"].(string),
-			Timestamp: row["ts"].(time.Time).UnixMilli(),
-		}
-
-		if editedAt, ok := row["edited_at"].(time.Time); ok {
-			ts := editedAt.UnixMilli()
-			msg.EditedAt = &ts
+		if expiresAt, ok := row["expires_at"].(time.Time); ok && !mh.scheduler.Scheduled(expiryKey(msg.ID)) {
+			// Reagendar o evento de expiração (os agendamentos não sobrevivem a reinícios)
+			mh.scheduleExpiry(msg.ChannelID, msg.ID, expiresAt)
 		}
 
+		mh.attachPoll(&msg, viewerID)
 		messages = append(messages, msg)
 	}
 
@@ -154,6 +204,18 @@ func (mh *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if req.Poll != nil {
+		if errMsg := validatePoll(req.Poll); errMsg != "" {
+			http.Error(w, errMsg, http.StatusBadRequest)
+			return
+		}
+
+		// Clientes sem suporte a enquetes exibem a pergunta como texto
+		if req.Content == "" {
+			req.Content = req.Poll.Question
+		}
+	}
+
 	if req.Content == "" {
 		http.Error(w, "message content is required", http.StatusBadRequest)
 		return
@@ -172,37 +234,374 @@ func (mh *MessageHandler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		zap.String("channelId", channelID),
 	)
 
-	// Gerar ID da mensagem
-	messageID := uuid.Must(uuid.NewV4()).String()
+	if req.TTL != nil && (*req.TTL < 0 || *req.TTL > maxMessageTTL) {
+		http.Error(w, fmt.Sprintf("ttl must be between 0 and %d seconds", maxMessageTTL), http.StatusBadRequest)
+		return
+	}
 
-	// Salvar mensagem no banco de dados
-	err := mh.db.SaveMessage(channelID, messageID, claims.UserID, req.Content)
+	// Nonce do cliente: corpo da requisição ou header Idempotency-Key
+	nonce := req.Nonce
+	if nonce == "" {
+		nonce = r.Header.Get("Idempotency-Key")
+	}
+	if len(nonce) > maxNonceLength {
+		http.Error(w, fmt.Sprintf("nonce must be at most %d characters", maxNonceLength), http.StatusBadRequest)
+		return
+	}
+
+	channelRow, _, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	// Gerar ID da mensagem (TimeUUID: define o timestamp e o bucket)
+	messageID := gocql.TimeUUID()
+
+	// Reservar o nonce antes do slowmode e dos comandos: um retry após um envio bem-sucedido
+	// recebe a mensagem criada originalmente, sem 429 e sem executar o comando de novo
+	if nonce != "" {
+		applied, originalChannelID, originalMessageID, err := mh.db.ReserveMessageNonce(claims.UserID, nonce, channelID, messageID.String(), nonceWindow)
+		if err != nil {
+			mh.logger.Error("failed to reserve message nonce", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		if !applied {
+			mh.replayMessage(w, originalChannelID, originalMessageID, nonce)
+			return
+		}
+	}
+
+	// Slowmode do canal e limite de rajada por usuário (moderadores são isentos)
+	if !mh.allowSend(claims.UserID, channelID, channelRow, w) {
+		mh.releaseNonce(claims.UserID, nonce)
+		return
+	}
+
+	// Comandos de barra: respostas efêmeras retornam 200 e não são salvas.
+	// Respostas públicas usam messageID, então um retry as devolve como qualquer mensagem.
+	if mh.commands != nil && req.Poll == nil {
+		outcome, handled, err := mh.commands.execute(r.Context(), channelRow, messageID, claims.UserID, claims.Username, req.Content)
+		if handled {
+			if err != nil {
+				mh.releaseNonce(claims.UserID, nonce)
+			}
+			mh.writeCommandOutcome(w, outcome, err)
+			return
+		}
+	}
+
+	// Resolver o TTL efetivo (mensagem e/ou canal)
+	ttl := resolveMessageTTL(channelRow, req.TTL)
+
+	message, err := mh.createMessage(r.Context(), newMessage{
+		ID:        messageID,
+		ChannelID: channelID,
+		AuthorID:  claims.UserID,
+		Username:  claims.Username,
+		Content:   req.Content,
+		Poll:      req.Poll,
+		TTL:       ttl,
+		Nonce:     nonce,
+	})
 	if err != nil {
 		mh.logger.Error("failed to save message", zap.Error(err))
+		mh.releaseNonce(claims.UserID, nonce)
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// newMessage descreve uma mensagem a ser salva e transmitida (por usuários, webhooks, ...)
+type newMessage struct {
+	ID        gocql.UUID // TimeUUID gerado pelo chamador
+	ChannelID string
+	AuthorID  string // usuário, ou o webhook quando WebhookID está preenchido
+	Username  string
+	Avatar    string
+	Content   string
+	Embeds    []models.Embed
+	Poll      *PollRequest      // já validada
+	Reference *MessageReference // mensagem encaminhada
+	WebhookID string
+	TTL       int
+	Nonce     string
+}
+
+// createMessage salva a mensagem (e a enquete, se houver), agenda as expirações e publica message.create.
+// É o caminho comum de envio: mensagens de usuários e de webhooks passam por aqui.
+func (mh *MessageHandler) createMessage(ctx context.Context, msg newMessage) (MessageResponse, error) {
+	messageID := msg.ID.String()
+	createdAt := msg.ID.Time()
+
+	messageType := ""
+	if msg.Poll != nil {
+		messageType = messageTypePoll
+	}
+	if msg.Reference != nil {
+		messageType = messageTypeForward
+	}
+
+	embeds := ""
+	if len(msg.Embeds) > 0 {
+		embedsBytes, err := json.Marshal(msg.Embeds)
+		if err != nil {
+			return MessageResponse{}, err
+		}
+		embeds = string(embedsBytes)
+	}
+
+	// Salvar a enquete antes da mensagem: uma mensagem "poll" nunca fica sem enquete
+	var pollExpiresAt *time.Time
+	if msg.Poll != nil {
+		if msg.Poll.Duration > 0 {
+			t := createdAt.Add(time.Duration(msg.Poll.Duration) * time.Second)
+			pollExpiresAt = &t
+		}
+
+		err := mh.db.CreatePoll(messageID, msg.ChannelID, msg.AuthorID, msg.Poll.Question, msg.Poll.Options, msg.Poll.MultiSelect, pollExpiresAt, msg.TTL)
+		if err != nil {
+			return MessageResponse{}, fmt.Errorf("failed to save poll: %w", err)
+		}
+	}
+
+	// Salvar mensagem no banco de dados
+	var err error
+	switch {
+	case msg.Reference != nil:
+		reference, marshalErr := json.Marshal(msg.Reference)
+		if marshalErr != nil {
+			return MessageResponse{}, marshalErr
+		}
+		err = mh.db.SaveForwardedMessage(msg.ChannelID, messageID, msg.AuthorID, msg.Content, string(reference), msg.TTL)
+	case msg.WebhookID != "":
+		err = mh.db.SaveWebhookMessage(msg.ChannelID, messageID, msg.WebhookID, msg.Username, msg.Avatar, msg.Content, embeds, msg.TTL)
+	default:
+		err = mh.db.SaveMessage(msg.ChannelID, messageID, msg.AuthorID, msg.Content, messageType, embeds, msg.TTL)
+	}
+	if err != nil {
+		if msg.Poll != nil {
+			_ = mh.db.DeletePoll(messageID)
+		}
+		return MessageResponse{}, err
+	}
+
 	message := MessageResponse{
 		ID:        messageID,
-		ChannelID: channelID,
-		UserID:    claims.UserID,
-		Username:  claims.Username,
-		Content:   req.Content,
-		Timestamp: time.Now().UnixMilli(),
+		ChannelID: msg.ChannelID,
+		UserID:    msg.AuthorID,
+		Username:  msg.Username,
+		Avatar:    msg.Avatar,
+		Content:   msg.Content,
+		Type:      messageType,
+		Embeds:    msg.Embeds,
+		Emojis:    mh.resolveEmojis(msg.Content),
+		WebhookID: msg.WebhookID,
+		Reference: msg.Reference,
+		Timestamp: createdAt.UnixMilli(),
+		Nonce:     msg.Nonce,
+	}
+
+	if message.Reference != nil {
+		message.Reference.Emojis = mh.resolveEmojis(message.Reference.Content)
+	}
+
+	if msg.Poll != nil {
+		message.Poll = buildPoll(map[string]interface{}{
+			"question":     msg.Poll.Question,
+			"options":      msg.Poll.Options,
+			"multi_select": msg.Poll.MultiSelect,
+		}, nil, "")
+		if pollExpiresAt != nil {
+			ts := pollExpiresAt.UnixMilli()
+			message.Poll.ExpiresAt = &ts
+			mh.schedulePollClose(msg.ChannelID, messageID, *pollExpiresAt)
+		}
+	}
+
+	if msg.TTL > 0 {
+		expiresAt := createdAt.Add(time.Duration(msg.TTL) * time.Second)
+		ts := expiresAt.UnixMilli()
+		message.ExpiresAt = &ts
+		mh.scheduleExpiry(msg.ChannelID, messageID, expiresAt)
+	}
+
+	// Broadcast com o nonce para o remetente reconciliar a mensagem otimista
+	mh.events.PublishChannelEvent(ctx, msg.ChannelID, "message.create", message)
+
+	// Links são desdobrados em segundo plano; embeds explícitos (webhooks) têm prioridade
+	if len(msg.Embeds) == 0 && msg.Poll == nil {
+		mh.unfurlLinks(msg.ChannelID, messageID, msg.Content)
 	}
 
 	mh.logger.Info("message sent",
 		zap.String("id", message.ID),
-		zap.String("channelId", channelID),
+		zap.String("channelId", msg.ChannelID),
 		zap.String("userId", message.UserID),
 	)
 
+	return message, nil
+}
+
+// resolveEmojis resolve os emojis customizados referenciados no conteúdo
+func (mh *MessageHandler) resolveEmojis(content string) []EmojiResponse {
+	if mh.emojis == nil {
+		return nil
+	}
+	return mh.emojis.resolveEmojis(content)
+}
+
+// writeCommandOutcome responde a um comando de barra enviado via POST /api/messages
+func (mh *MessageHandler) writeCommandOutcome(w http.ResponseWriter, outcome commandOutcome, err error) {
+	if err != nil {
+		var cmdErr *commandError
+		if errors.As(err, &cmdErr) {
+			http.Error(w, cmdErr.message, cmdErr.status)
+			return
+		}
+		mh.logger.Error("command failed", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
+	if outcome.ephemeral {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(outcome.message)
+}
+
+// releaseNonce libera o nonce de um envio que não criou mensagem, para que o retry do
+// cliente seja processado
+func (mh *MessageHandler) releaseNonce(userID, nonce string) {
+	if nonce == "" {
+		return
+	}
+	if err := mh.db.ReleaseMessageNonce(userID, nonce); err != nil {
+		mh.logger.Warn("failed to release message nonce", zap.String("userId", userID), zap.Error(err))
+	}
+}
+
+// replayMessage responde a um envio repetido (mesmo nonce) com a mensagem criada originalmente
+func (mh *MessageHandler) replayMessage(w http.ResponseWriter, channelID, messageID, nonce string) {
+	row, err := mh.db.GetMessage(channelID, messageID)
+	if err == gocql.ErrNotFound {
+		// A requisição original ainda está gravando a mensagem (ou ela já expirou)
+		http.Error(w, "a message with this nonce is still being processed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		mh.logger.Error("failed to get original message for nonce", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	message := mh.messageFromRow(row)
+	message.Nonce = nonce
+	mh.attachPoll(&message, row["author_id"].(string))
+
+	mh.logger.Info("duplicate message send ignored",
+		zap.String("id", message.ID),
+		zap.String("channelId", channelID),
+		zap.String("nonce", nonce),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(message)
 }
 
+// messageFromRow converte uma linha de messages_by_channel em MessageResponse
+func (mh *MessageHandler) messageFromRow(row map[string]interface{}) MessageResponse {
+	// Buscar username do usuário
+	username := "Unknown User"
+	avatar := ""
+	authorID := row["author_id"].(string)
+
+	webhookID, isWebhook := row["webhook_id"].(string)
+	if isWebhook {
+		// Mensagens de webhook guardam o nome e o avatar exibidos
+		username = row["author_name"].(string)
+		avatar = row["author_avatar"].(string)
+	} else if userRow, err := mh.db.GetUserByID(authorID); err != nil {
+		mh.logger.Warn("failed to get user info for message",
+			zap.Error(err),
+			zap.String("authorId", authorID),
+			zap.String("msgId", row["msg_id"].(string)),
+		)
+	} else {
+		if uname, ok := userRow["username"].(string); ok && uname != "" {
+			username = uname
+		} else {
+			mh.logger.Warn("username not found in user row",
+				zap.String("authorId", authorID),
+				zap.Any("userRow", userRow),
+			)
+		}
+	}
+
+	msg := MessageResponse{
+		ID:        row["msg_id"].(string),
+		ChannelID: row["channel_id"].(string),
+		UserID:    authorID,
+		Username:  username,
+		Avatar:    avatar,
+		Content:   row["content"].(string),
+		Type:      row["type"].(string),
+		WebhookID: webhookID,
+		Timestamp: row["ts"].(time.Time).UnixMilli(),
+	}
+	msg.Emojis = mh.resolveEmojis(msg.Content)
+
+	if reference, ok := row["reference"].(string); ok {
+		if err := json.Unmarshal([]byte(reference), &msg.Reference); err != nil {
+			mh.logger.Warn("failed to decode message reference", zap.Error(err), zap.String("msgId", msg.ID))
+		} else {
+			msg.Reference.Emojis = mh.resolveEmojis(msg.Reference.Content)
+		}
+	}
+
+	if embeds, ok := row["embeds"].(string); ok && embeds != "" {
+		if err := json.Unmarshal([]byte(embeds), &msg.Embeds); err != nil {
+			mh.logger.Warn("failed to decode message embeds", zap.Error(err), zap.String("msgId", msg.ID))
+		}
+	}
+
+	if editedAt, ok := row["edited_at"].(time.Time); ok {
+		ts := editedAt.UnixMilli()
+		msg.EditedAt = &ts
+	}
+
+	if expiresAt, ok := row["expires_at"].(time.Time); ok {
+		ts := expiresAt.UnixMilli()
+		msg.ExpiresAt = &ts
+	}
+
+	return msg
+}
+
+// attachPoll carrega a enquete de mensagens do tipo "poll"
+func (mh *MessageHandler) attachPoll(msg *MessageResponse, viewerID string) {
+	if msg.Type != messageTypePoll {
+		return
+	}
+
+	poll, err := mh.loadPoll(msg.ChannelID, msg.ID, viewerID)
+	if err != nil {
+		mh.logger.Warn("failed to load poll for message", zap.Error(err), zap.String("msgId", msg.ID))
+		return
+	}
+	msg.Poll = poll
+}
+
 // UpdateMessage atualiza uma mensagem existente
 func (mh *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request) {
 	messageID := r.URL.Query().Get("id")
@@ -223,34 +622,23 @@ func (mh *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Obter usuário do contexto
-	claims, ok := r.Context().Value("claims").(*models.Claims)
-	if !ok || claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
 		return
 	}
 
 	// Buscar mensagem para verificar o autor
-	messageRows, err := mh.db.GetMessagesByChannel(channelID, 1000, nil)
+	message, err := mh.db.GetMessage(channelID, messageID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		mh.logger.Error("failed to get message", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var message map[string]interface{}
-	for _, row := range messageRows {
-		if row["msg_id"].(string) == messageID {
-			message = row
-			break
-		}
-	}
-
-	if message == nil {
-		http.Error(w, "message not found", http.StatusNotFound)
-		return
-	}
-
 	authorID := message["author_id"].(string)
 	if authorID != claims.UserID {
 		http.Error(w, "forbidden: you can only edit your own messages", http.StatusForbidden)
@@ -265,6 +653,13 @@ func (mh *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Os embeds de links refletiam o conteúdo anterior: são removidos e desdobrados de novo
+	if embeds, _ := message["embeds"].(string); embeds != "" {
+		if err := mh.db.UpdateMessageEmbeds(channelID, messageID, ""); err != nil {
+			mh.logger.Warn("failed to clear message embeds", zap.Error(err), zap.String("id", messageID))
+		}
+	}
+
 	editedAt := time.Now().UnixMilli()
 	response := MessageResponse{
 		ID:        messageID,
@@ -276,6 +671,16 @@ func (mh *MessageHandler) UpdateMessage(w http.ResponseWriter, r *http.Request)
 		EditedAt:  &editedAt,
 	}
 
+	mh.events.PublishChannelEvent(r.Context(), channelID, "message.update", MessageUpdateEvent{
+		ID:        messageID,
+		ChannelID: channelID,
+		Content:   &req.Content,
+		Embeds:    []models.Embed{},
+		Emojis:    mh.resolveEmojis(req.Content),
+		EditedAt:  &editedAt,
+	})
+	mh.unfurlLinks(channelID, messageID, req.Content)
+
 	mh.logger.Info("message updated", zap.String("id", messageID), zap.String("userId", claims.UserID))
 
 	w.Header().Set("Content-Type", "application/json")
@@ -296,57 +701,33 @@ func (mh *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Obter usuário do contexto
-	claims, ok := r.Context().Value("claims").(*models.Claims)
-	if !ok || claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
 		return
 	}
 
 	// Buscar mensagem para verificar o autor
-	messageRows, err := mh.db.GetMessagesByChannel(channelID, 1000, nil)
+	message, err := mh.db.GetMessage(channelID, messageID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "message not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		mh.logger.Error("failed to get message", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	var message map[string]interface{}
-	for _, row := range messageRows {
-		if row["msg_id"].(string) == messageID {
-			message = row
-			break
-		}
-	}
-
-	if message == nil {
-		http.Error(w, "message not found", http.StatusNotFound)
-		return
-	}
-
 	authorID := message["author_id"].(string)
 	canDelete := authorID == claims.UserID
 
-	// Se não é o autor, verificar se é owner ou admin do servidor
+	// Se não é o autor, verificar se é owner, admin ou moderador do servidor
 	if !canDelete {
-		// Buscar canal para obter o group_id (servidor)
-		channelRow, err := mh.db.GetChannelByID(channelID)
-		if err == nil {
-			if serverID, ok := channelRow["server_id"].(string); ok && serverID != "" {
-				// Buscar servidor
-				if serverRow, err := mh.db.GetGroupByID(serverID); err == nil {
-					// Verificar se é owner
-					if ownerID, ok := serverRow["owner_id"].(string); ok && ownerID == claims.UserID {
-						canDelete = true
-					} else {
-						// Verificar se é admin
-						if role, err := mh.db.GetGroupMemberRole(serverID, claims.UserID); err == nil {
-							canDelete = role == "admin" || role == "moderator"
-						}
-					}
-				}
-			}
+		isModerator, err := mh.db.IsChannelModerator(channelID, claims.UserID)
+		if err != nil {
+			mh.logger.Warn("failed to check moderator status", zap.Error(err), zap.String("channelId", channelID))
 		}
+		canDelete = isModerator
 	}
 
 	if !canDelete {
@@ -362,7 +743,149 @@ func (mh *MessageHandler) DeleteMessage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	mh.scheduler.Cancel(expiryKey(messageID))
+	if message["type"].(string) == messageTypePoll {
+		mh.scheduler.Cancel(pollCloseKey(messageID))
+		if err := mh.db.DeletePoll(messageID); err != nil {
+			mh.logger.Warn("failed to delete poll", zap.Error(err), zap.String("id", messageID))
+		}
+	}
+	mh.events.PublishChannelEvent(r.Context(), channelID, "message.delete", MessageDeleteEvent{
+		ID:        messageID,
+		ChannelID: channelID,
+		Reason:    "deleted",
+	})
+
 	mh.logger.Info("message deleted", zap.String("id", messageID), zap.String("userId", claims.UserID))
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// resolveMessageTTL combina o TTL pedido na mensagem (já validado) com o TTL padrão do canal.
+// O TTL do canal é também o limite máximo: uma mensagem pode expirar antes, nunca depois.
+func resolveMessageTTL(channelRow map[string]interface{}, requested *int) int {
+	ttl := 0
+	if requested != nil {
+		ttl = *requested
+	}
+
+	if channelTTL, ok := channelRow["message_ttl"].(int); ok && channelTTL > 0 {
+		if ttl == 0 || ttl > channelTTL {
+			ttl = channelTTL
+		}
+	}
+
+	return ttl
+}
+
+// allowSend aplica o slowmode do canal e o limite de rajada do usuário.
+// Se o envio for bloqueado, escreve a resposta 429 e retorna false.
+func (mh *MessageHandler) allowSend(userID, channelID string, channelRow map[string]interface{}, w http.ResponseWriter) bool {
+	slowmode := 0
+	if seconds, ok := channelRow["slowmode"].(int); ok {
+		slowmode = seconds
+	}
+
+	// Moderadores do servidor não são limitados
+	isModerator := func() bool {
+		isModerator, err := mh.db.IsChannelModerator(channelID, userID)
+		if err != nil {
+			mh.logger.Warn("failed to check moderator status", zap.Error(err), zap.String("channelId", channelID))
+		}
+		return isModerator
+	}
+
+	allowed, reason, retryAfter := mh.limiter.Allow(userID, channelID, time.Duration(slowmode)*time.Second, isModerator)
+	if allowed {
+		return true
+	}
+
+	mh.logger.Info("message send rate limited",
+		zap.String("userId", userID),
+		zap.String("channelId", channelID),
+		zap.String("reason", string(reason)),
+		zap.Duration("retryAfter", retryAfter),
+	)
+
+	writeRateLimited(w, reason, retryAfter)
+	return false
+}
+
+// writeRateLimited escreve a resposta 429 com Retry-After e o tempo de espera no corpo
+func writeRateLimited(w http.ResponseWriter, reason ratelimit.Reason, retryAfter time.Duration) {
+	message := "you are sending messages too fast"
+	if reason == ratelimit.ReasonSlowmode {
+		message = "slowmode is enabled in this channel"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(RateLimitResponse{
+		Error:      message,
+		Reason:     string(reason),
+		RetryAfter: retryAfter.Seconds(),
+	})
+}
+
+// expiryKey retorna a chave de agendamento da expiração de uma mensagem
+func expiryKey(messageID string) string {
+	return "message-expiry:" + messageID
+}
+
+// scheduleExpiry agenda o evento message.delete para quando a mensagem expirar no Cassandra
+func (mh *MessageHandler) scheduleExpiry(channelID, messageID string, expiresAt time.Time) {
+	mh.scheduler.Schedule(expiryKey(messageID), expiresAt, func() {
+		mh.events.PublishChannelEvent(context.Background(), channelID, "message.delete", MessageDeleteEvent{
+			ID:        messageID,
+			ChannelID: channelID,
+			Reason:    "expired",
+		})
+		mh.logger.Info("ephemeral message expired",
+			zap.String("id", messageID),
+			zap.String("channelId", channelID),
+		)
+	})
+}
+
+// unfurlLinks desdobra os links do conteúdo em segundo plano. Quando os metadados chegam,
+// os embeds são salvos na mensagem e transmitidos com message.update
+func (mh *MessageHandler) unfurlLinks(channelID, messageID, content string) {
+	urls := unfurl.ExtractURLs(content)
+	if len(urls) == 0 {
+		return
+	}
+
+	queued := mh.unfurler.Enqueue(unfurl.Job{
+		URLs: urls,
+		Done: func(embeds []models.Embed) {
+			if len(embeds) == 0 {
+				return
+			}
+
+			// A mensagem pode ter sido editada ou removida enquanto os links eram buscados
+			row, err := mh.db.GetMessage(channelID, messageID)
+			if err != nil || row["content"].(string) != content {
+				return
+			}
+
+			embedsBytes, err := json.Marshal(embeds)
+			if err != nil {
+				return
+			}
+			if err := mh.db.UpdateMessageEmbeds(channelID, messageID, string(embedsBytes)); err != nil {
+				mh.logger.Warn("failed to save link embeds", zap.Error(err), zap.String("id", messageID))
+				return
+			}
+
+			mh.events.PublishChannelEvent(context.Background(), channelID, "message.update", MessageUpdateEvent{
+				ID:        messageID,
+				ChannelID: channelID,
+				Embeds:    embeds,
+			})
+		},
+	})
+	if !queued {
+		mh.logger.Warn("unfurl queue full, skipping links", zap.String("id", messageID))
+	}
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+const (
+	maxOutgoingWebhooksPerServer = 10
+
+	defaultDeliveryLogLimit = 50
+	maxDeliveryLogLimit     = 200
+)
+
+// OutgoingWebhookHandler gerencia os endpoints de webhooks de saída de um servidor
+type OutgoingWebhookHandler struct {
+	logger     *zap.Logger
+	db         *database.CassandraDB
+	dispatcher *services.WebhookDispatcher
+}
+
+// NewOutgoingWebhookHandler cria um novo handler de webhooks de saída
+func NewOutgoingWebhookHandler(logger *zap.Logger, db *database.CassandraDB, dispatcher *services.WebhookDispatcher) *OutgoingWebhookHandler {
+	return &OutgoingWebhookHandler{
+		logger:     logger,
+		db:         db,
+		dispatcher: dispatcher,
+	}
+}
+
+// OutgoingWebhookRequest representa a criação/atualização de um endpoint
+type OutgoingWebhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"` // "message.create", "member.join", "task.move"
+	Active *bool    `json:"active,omitempty"`
+}
+
+// OutgoingWebhookResponse representa um endpoint. O segredo só é retornado
+// na criação e quando é regenerado.
+type OutgoingWebhookResponse struct {
+	ID        string   `json:"id"`
+	ServerID  string   `json:"serverId"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Active    bool     `json:"active"`
+	CreatedBy string   `json:"createdBy"`
+	CreatedAt int64    `json:"createdAt"`
+	UpdatedAt int64    `json:"updatedAt"`
+	Secret    string   `json:"secret,omitempty"`
+}
+
+// WebhookDeliveryResponse representa uma tentativa de entrega no log
+type WebhookDeliveryResponse struct {
+	ID           string `json:"id"`
+	Attempt      int    `json:"attempt"`
+	EventType    string `json:"eventType"`
+	Status       string `json:"status"` // "succeeded", "retrying", "failed"
+	StatusCode   int    `json:"statusCode,omitempty"`
+	Error        string `json:"error,omitempty"`
+	DurationMs   int    `json:"durationMs"`
+	RequestBody  string `json:"requestBody"`
+	ResponseBody string `json:"responseBody,omitempty"`
+	CreatedAt    int64  `json:"createdAt"`
+}
+
+// ListOutgoingWebhooks lista os endpoints de um servidor (owners e admins)
+func (oh *OutgoingWebhookHandler) ListOutgoingWebhooks(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := oh.requireServerAdmin(w, r, serverID); !ok {
+		return
+	}
+
+	rows, err := oh.db.GetServerOutgoingWebhooks(serverID)
+	if err != nil {
+		oh.logger.Error("failed to list outgoing webhooks", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	endpoints := make([]OutgoingWebhookResponse, 0, len(rows))
+	for _, row := range rows {
+		endpoints = append(endpoints, outgoingWebhookFromRow(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// CreateOutgoingWebhook registra um endpoint para o servidor (owners e admins)
+func (oh *OutgoingWebhookHandler) CreateOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	serverID := r.URL.Query().Get("serverId")
+	if serverID == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
+		return
+	}
+
+	var req OutgoingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.URL == nil {
+		http.Error(w, "url is required", http.StatusBadRequest)
+		return
+	}
+	if errMsg := oh.validateRequest(&req); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+	if req.Events == nil {
+		// Sem eventos explícitos, o endpoint assina todos
+		req.Events = append([]string(nil), services.WebhookEventTypes...)
+	}
+
+	claims, ok := oh.requireServerAdmin(w, r, serverID)
+	if !ok {
+		return
+	}
+
+	existing, err := oh.db.GetServerOutgoingWebhooks(serverID)
+	if err != nil {
+		oh.logger.Error("failed to list outgoing webhooks", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	if len(existing) >= maxOutgoingWebhooksPerServer {
+		http.Error(w, fmt.Sprintf("a server can have at most %d outgoing webhooks", maxOutgoingWebhooksPerServer), http.StatusBadRequest)
+		return
+	}
+
+	secret, _, err := generateWebhookToken()
+	if err != nil {
+		oh.logger.Error("failed to generate webhook secret", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	endpointID := uuid.Must(uuid.NewV4()).String()
+	if err := oh.db.CreateOutgoingWebhook(endpointID, serverID, *req.URL, secret, req.Events, claims.UserID); err != nil {
+		oh.logger.Error("failed to create outgoing webhook", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	oh.dispatcher.Invalidate(serverID)
+
+	oh.logger.Info("outgoing webhook created",
+		zap.String("endpointId", endpointID),
+		zap.String("serverId", serverID),
+		zap.String("userId", claims.UserID),
+		zap.Strings("events", req.Events),
+	)
+
+	now := time.Now().UnixMilli()
+	response := OutgoingWebhookResponse{
+		ID:        endpointID,
+		ServerID:  serverID,
+		URL:       *req.URL,
+		Events:    req.Events,
+		Active:    true,
+		CreatedBy: claims.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Secret:    secret,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateOutgoingWebhook altera URL, eventos ou estado (ativo/pausado) de um endpoint
+func (oh *OutgoingWebhookHandler) UpdateOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	endpointRow, claims, ok := oh.getManagedEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	var req OutgoingWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errMsg := oh.validateRequest(&req); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	if req.URL != nil {
+		endpointRow["url"] = *req.URL
+	}
+	if req.Events != nil {
+		endpointRow["events"] = req.Events
+	}
+	if req.Active != nil {
+		endpointRow["active"] = *req.Active
+	}
+
+	endpointID := endpointRow["endpoint_id"].(string)
+	serverID := endpointRow["server_id"].(string)
+	if err := oh.db.UpdateOutgoingWebhook(endpointID, endpointRow["url"].(string), endpointRow["events"].([]string), endpointRow["active"].(bool)); err != nil {
+		oh.logger.Error("failed to update outgoing webhook", zap.Error(err), zap.String("endpointId", endpointID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	oh.dispatcher.Invalidate(serverID)
+
+	oh.logger.Info("outgoing webhook updated", zap.String("endpointId", endpointID), zap.String("userId", claims.UserID))
+
+	endpointRow["updated_at"] = time.Now()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(outgoingWebhookFromRow(endpointRow))
+}
+
+// DeleteOutgoingWebhook remove um endpoint e seu log de entregas
+func (oh *OutgoingWebhookHandler) DeleteOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	endpointRow, claims, ok := oh.getManagedEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	endpointID := endpointRow["endpoint_id"].(string)
+	serverID := endpointRow["server_id"].(string)
+	if err := oh.db.DeleteOutgoingWebhook(endpointID, serverID); err != nil {
+		oh.logger.Error("failed to delete outgoing webhook", zap.Error(err), zap.String("endpointId", endpointID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	oh.dispatcher.Invalidate(serverID)
+
+	oh.logger.Info("outgoing webhook deleted", zap.String("endpointId", endpointID), zap.String("userId", claims.UserID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RegenerateOutgoingWebhookSecret troca o segredo usado nas assinaturas
+func (oh *OutgoingWebhookHandler) RegenerateOutgoingWebhookSecret(w http.ResponseWriter, r *http.Request) {
+	endpointRow, claims, ok := oh.getManagedEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	secret, _, err := generateWebhookToken()
+	if err != nil {
+		oh.logger.Error("failed to generate webhook secret", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	endpointID := endpointRow["endpoint_id"].(string)
+	if err := oh.db.UpdateOutgoingWebhookSecret(endpointID, secret); err != nil {
+		oh.logger.Error("failed to update outgoing webhook secret", zap.Error(err), zap.String("endpointId", endpointID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+	oh.dispatcher.Invalidate(endpointRow["server_id"].(string))
+
+	oh.logger.Info("outgoing webhook secret regenerated", zap.String("endpointId", endpointID), zap.String("userId", claims.UserID))
+
+	endpointRow["updated_at"] = time.Now()
+	response := outgoingWebhookFromRow(endpointRow)
+	response.Secret = secret
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// PingOutgoingWebhook envia um evento "ping" para o endpoint. O resultado aparece no log de entregas
+func (oh *OutgoingWebhookHandler) PingOutgoingWebhook(w http.ResponseWriter, r *http.Request) {
+	endpointRow, _, ok := oh.getManagedEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := oh.dispatcher.Ping(endpointRow)
+	if err != nil {
+		oh.logger.Error("failed to ping outgoing webhook", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"deliveryId": deliveryID})
+}
+
+// GetWebhookDeliveries retorna o log de entregas de um endpoint (?limit=, padrão 50)
+func (oh *OutgoingWebhookHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpointRow, _, ok := oh.getManagedEndpoint(w, r)
+	if !ok {
+		return
+	}
+
+	limit := defaultDeliveryLogLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 || l > maxDeliveryLogLimit {
+			http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxDeliveryLogLimit), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+
+	endpointID := endpointRow["endpoint_id"].(string)
+	rows, err := oh.db.GetWebhookDeliveries(endpointID, limit)
+	if err != nil {
+		oh.logger.Error("failed to get webhook deliveries", zap.Error(err), zap.String("endpointId", endpointID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	deliveries := make([]WebhookDeliveryResponse, 0, len(rows))
+	for _, row := range rows {
+		delivery := WebhookDeliveryResponse{
+			ID:           row["delivery_id"].(string),
+			Attempt:      row["attempt"].(int),
+			EventType:    row["event_type"].(string),
+			Status:       row["status"].(string),
+			StatusCode:   row["status_code"].(int),
+			Error:        row["error"].(string),
+			DurationMs:   row["duration_ms"].(int),
+			RequestBody:  row["request_body"].(string),
+			ResponseBody: row["response_body"].(string),
+		}
+		if createdAt, ok := row["created_at"].(time.Time); ok {
+			delivery.CreatedAt = createdAt.UnixMilli()
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// validateRequest normaliza e valida URL e eventos. Retorna a mensagem de erro ou ""
+func (oh *OutgoingWebhookHandler) validateRequest(req *OutgoingWebhookRequest) string {
+	if req.URL != nil {
+		trimmed := strings.TrimSpace(*req.URL)
+		req.URL = &trimmed
+		if err := oh.dispatcher.ValidateURL(trimmed); err != nil {
+			return err.Error()
+		}
+	}
+
+	if req.Events != nil {
+		if len(req.Events) == 0 {
+			return "at least one event is required"
+		}
+		seen := make(map[string]bool)
+		events := make([]string, 0, len(req.Events))
+		for _, eventType := range req.Events {
+			if !services.ValidWebhookEvent(eventType) {
+				return fmt.Sprintf("unknown event type %q (supported: %s)", eventType, strings.Join(services.WebhookEventTypes, ", "))
+			}
+			if !seen[eventType] {
+				seen[eventType] = true
+				events = append(events, eventType)
+			}
+		}
+		req.Events = events
+	}
+
+	return ""
+}
+
+// getManagedEndpoint busca o endpoint indicado em ?id= e verifica se o usuário administra o servidor dele
+func (oh *OutgoingWebhookHandler) getManagedEndpoint(w http.ResponseWriter, r *http.Request) (map[string]interface{}, *models.Claims, bool) {
+	endpointID := r.URL.Query().Get("id")
+	if endpointID == "" {
+		http.Error(w, "webhook id required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	endpointRow, err := oh.db.GetOutgoingWebhook(endpointID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		oh.logger.Error("failed to get outgoing webhook", zap.Error(err), zap.String("endpointId", endpointID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	claims, ok := oh.requireServerAdmin(w, r, endpointRow["server_id"].(string))
+	if !ok {
+		return nil, nil, false
+	}
+
+	return endpointRow, claims, true
+}
+
+// requireServerAdmin verifica se o usuário é dono ou admin do servidor.
+// Escreve a resposta de erro e retorna false caso contrário.
+func (oh *OutgoingWebhookHandler) requireServerAdmin(w http.ResponseWriter, r *http.Request, serverID string) (*models.Claims, bool) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	isAdmin, err := oh.db.IsServerAdmin(serverID, claims.UserID)
+	if err != nil {
+		oh.logger.Error("failed to check server admin", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !isAdmin {
+		http.Error(w, "forbidden: only server owners and admins can manage webhooks", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// outgoingWebhookFromRow converte uma linha de outgoing_webhooks (sem o segredo)
+func outgoingWebhookFromRow(row map[string]interface{}) OutgoingWebhookResponse {
+	response := OutgoingWebhookResponse{
+		ID:        row["endpoint_id"].(string),
+		ServerID:  row["server_id"].(string),
+		URL:       row["url"].(string),
+		Events:    row["events"].([]string),
+		Active:    row["active"].(bool),
+		CreatedBy: row["created_by"].(string),
+	}
+	if createdAt, ok := row["created_at"].(time.Time); ok {
+		response.CreatedAt = createdAt.UnixMilli()
+	}
+	if updatedAt, ok := row["updated_at"].(time.Time); ok {
+		response.UpdatedAt = updatedAt.UnixMilli()
+	}
+	return response
+}
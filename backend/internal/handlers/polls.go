@@ -0,0 +1,423 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+const (
+	// messageTypePoll identifica mensagens que carregam uma enquete
+	messageTypePoll = "poll"
+
+	maxPollOptions        = 10
+	maxPollQuestionLength = 300
+	maxPollOptionLength   = 100
+
+	// maxPollDuration é a maior duração aceita para uma enquete (7 dias, em segundos)
+	maxPollDuration = 7 * 24 * 60 * 60
+)
+
+// PollRequest representa a enquete enviada junto com uma mensagem
+type PollRequest struct {
+	Question    string   `json:"question"`
+	Options     []string `json:"options"`
+	MultiSelect bool     `json:"multiSelect"`
+	Duration    int      `json:"duration,omitempty"` // segundos até encerrar (0 = sem expiração)
+}
+
+// PollVoteRequest representa o voto em uma enquete
+type PollVoteRequest struct {
+	Options []int `json:"options"` // índices das opções escolhidas
+}
+
+// PollOption representa uma opção da enquete com sua contagem de votos
+type PollOption struct {
+	ID    int    `json:"id"`
+	Text  string `json:"text"`
+	Votes int    `json:"votes"`
+}
+
+// PollResponse representa uma enquete anexada a uma mensagem
+type PollResponse struct {
+	Question    string       `json:"question"`
+	Options     []PollOption `json:"options"`
+	MultiSelect bool         `json:"multiSelect"`
+	TotalVoters int          `json:"totalVoters"`
+	ExpiresAt   *int64       `json:"expiresAt,omitempty"`
+	ClosedAt    *int64       `json:"closedAt,omitempty"`
+	Closed      bool         `json:"closed"`
+	MyVotes     []int        `json:"myVotes,omitempty"` // opções escolhidas por quem fez a requisição
+}
+
+// PollEvent é o payload dos eventos poll.update e poll.close
+type PollEvent struct {
+	ID        string        `json:"id"` // ID da mensagem da enquete
+	ChannelID string        `json:"channelId"`
+	Poll      *PollResponse `json:"poll"`
+}
+
+// validatePoll normaliza e valida a enquete. Retorna a mensagem de erro ou "" se for válida
+func validatePoll(poll *PollRequest) string {
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" || len(poll.Question) > maxPollQuestionLength {
+		return fmt.Sprintf("poll question must be between 1 and %d characters", maxPollQuestionLength)
+	}
+
+	if len(poll.Options) < 2 || len(poll.Options) > maxPollOptions {
+		return fmt.Sprintf("poll must have between 2 and %d options", maxPollOptions)
+	}
+
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || len(option) > maxPollOptionLength {
+			return fmt.Sprintf("poll options must be between 1 and %d characters", maxPollOptionLength)
+		}
+		poll.Options[i] = option
+	}
+
+	if poll.Duration < 0 || poll.Duration > maxPollDuration {
+		return fmt.Sprintf("poll duration must be between 0 and %d seconds", maxPollDuration)
+	}
+
+	return ""
+}
+
+// VotePoll registra (ou substitui) o voto do usuário em uma enquete
+func (mh *MessageHandler) VotePoll(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	messageID := r.URL.Query().Get("id")
+	if channelID == "" || messageID == "" {
+		http.Error(w, "channel id and message id required", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	var req PollVoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	pollRow, ok := mh.getOpenPoll(w, channelID, messageID)
+	if !ok {
+		return
+	}
+
+	// Validar as opções escolhidas
+	options := pollRow["options"].([]string)
+	if len(req.Options) == 0 {
+		http.Error(w, "at least one option is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Options) > 1 && !pollRow["multi_select"].(bool) {
+		http.Error(w, "this poll allows a single option", http.StatusBadRequest)
+		return
+	}
+	seen := make(map[int]bool)
+	for _, option := range req.Options {
+		if option < 0 || option >= len(options) {
+			http.Error(w, "invalid poll option", http.StatusBadRequest)
+			return
+		}
+		if seen[option] {
+			http.Error(w, "duplicate poll option", http.StatusBadRequest)
+			return
+		}
+		seen[option] = true
+	}
+
+	// Um voto por usuário: o voto anterior é substituído
+	if err := mh.db.SetPollVote(messageID, claims.UserID, req.Options, pollRow["ttl"].(int)); err != nil {
+		mh.logger.Error("failed to save poll vote", zap.Error(err), zap.String("pollId", messageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	mh.logger.Info("poll vote saved", zap.String("pollId", messageID), zap.String("userId", claims.UserID))
+
+	mh.respondPollUpdate(w, r.Context(), channelID, messageID, pollRow, claims.UserID)
+}
+
+// UnvotePoll remove o voto do usuário em uma enquete
+func (mh *MessageHandler) UnvotePoll(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	messageID := r.URL.Query().Get("id")
+	if channelID == "" || messageID == "" {
+		http.Error(w, "channel id and message id required", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	pollRow, ok := mh.getOpenPoll(w, channelID, messageID)
+	if !ok {
+		return
+	}
+
+	if err := mh.db.DeletePollVote(messageID, claims.UserID); err != nil {
+		mh.logger.Error("failed to delete poll vote", zap.Error(err), zap.String("pollId", messageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	mh.logger.Info("poll vote removed", zap.String("pollId", messageID), zap.String("userId", claims.UserID))
+
+	mh.respondPollUpdate(w, r.Context(), channelID, messageID, pollRow, claims.UserID)
+}
+
+// ClosePoll encerra uma enquete antes da expiração (autor ou moderadores)
+func (mh *MessageHandler) ClosePoll(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	messageID := r.URL.Query().Get("id")
+	if channelID == "" || messageID == "" {
+		http.Error(w, "channel id and message id required", http.StatusBadRequest)
+		return
+	}
+
+	_, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	pollRow, ok := mh.getOpenPoll(w, channelID, messageID)
+	if !ok {
+		return
+	}
+
+	if pollRow["author_id"].(string) != claims.UserID {
+		isModerator, err := mh.db.IsChannelModerator(channelID, claims.UserID)
+		if err != nil {
+			mh.logger.Warn("failed to check moderator status", zap.Error(err), zap.String("channelId", channelID))
+		}
+		if !isModerator {
+			http.Error(w, "forbidden: only the poll author or a moderator can close it", http.StatusForbidden)
+			return
+		}
+	}
+
+	poll, err := mh.closePoll(channelID, messageID)
+	if err != nil {
+		mh.logger.Error("failed to close poll", zap.Error(err), zap.String("pollId", messageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(poll)
+}
+
+// getOpenPoll busca a enquete de uma mensagem e garante que ainda aceita votos.
+// Escreve a resposta de erro e retorna false se a enquete não existir ou já estiver encerrada.
+func (mh *MessageHandler) getOpenPoll(w http.ResponseWriter, channelID, messageID string) (map[string]interface{}, bool) {
+	pollRow, err := mh.db.GetPoll(messageID)
+	if err == gocql.ErrNotFound || (err == nil && pollRow["channel_id"].(string) != channelID) {
+		http.Error(w, "poll not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		mh.logger.Error("failed to get poll", zap.Error(err), zap.String("pollId", messageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	if _, closed := pollRow["closed_at"]; closed {
+		http.Error(w, "poll is closed", http.StatusConflict)
+		return nil, false
+	}
+
+	if expiresAt, ok := pollRow["expires_at"].(time.Time); ok && !time.Now().Before(expiresAt) {
+		// O encerramento agendado pode ter se perdido (reinício): encerrar agora
+		if _, err := mh.closePoll(channelID, messageID); err != nil {
+			mh.logger.Error("failed to close expired poll", zap.Error(err), zap.String("pollId", messageID))
+		}
+		http.Error(w, "poll is closed", http.StatusConflict)
+		return nil, false
+	}
+
+	return pollRow, true
+}
+
+// respondPollUpdate recalcula a contagem, publica poll.update e responde com a visão do usuário
+func (mh *MessageHandler) respondPollUpdate(w http.ResponseWriter, ctx context.Context, channelID, messageID string, pollRow map[string]interface{}, userID string) {
+	votes, err := mh.db.GetPollVotes(messageID)
+	if err != nil {
+		mh.logger.Error("failed to get poll votes", zap.Error(err), zap.String("pollId", messageID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	// Contagem ao vivo para todos do canal (sem os votos individuais)
+	mh.events.PublishChannelEvent(ctx, channelID, "poll.update", PollEvent{
+		ID:        messageID,
+		ChannelID: channelID,
+		Poll:      buildPoll(pollRow, votes, ""),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buildPoll(pollRow, votes, userID))
+}
+
+// loadPoll monta a enquete de uma mensagem do ponto de vista de viewerID
+func (mh *MessageHandler) loadPoll(channelID, messageID, viewerID string) (*PollResponse, error) {
+	pollRow, err := mh.db.GetPoll(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, closed := pollRow["closed_at"]; !closed {
+		if expiresAt, ok := pollRow["expires_at"].(time.Time); ok {
+			if !time.Now().Before(expiresAt) {
+				if _, err := mh.closePoll(channelID, messageID); err != nil {
+					return nil, err
+				}
+				return mh.loadPoll(channelID, messageID, viewerID)
+			}
+
+			if !mh.scheduler.Scheduled(pollCloseKey(messageID)) {
+				// Reagendar o encerramento (os agendamentos não sobrevivem a reinícios)
+				mh.schedulePollClose(channelID, messageID, expiresAt)
+			}
+		}
+	}
+
+	votes, err := mh.db.GetPollVotes(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildPoll(pollRow, votes, viewerID), nil
+}
+
+// closePoll congela os resultados da enquete e publica poll.close.
+// Se outra requisição já encerrou a enquete, os resultados gravados por ela são mantidos.
+func (mh *MessageHandler) closePoll(channelID, messageID string) (*PollResponse, error) {
+	mh.scheduler.Cancel(pollCloseKey(messageID))
+
+	pollRow, err := mh.db.GetPoll(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := mh.db.GetPollVotes(messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, closed := pollRow["closed_at"]; closed {
+		return buildPoll(pollRow, votes, ""), nil
+	}
+
+	tallies := tallyPollVotes(len(pollRow["options"].([]string)), votes)
+	results := make(map[int]int, len(tallies))
+	for option, count := range tallies {
+		results[option] = count
+	}
+
+	applied, err := mh.db.ClosePoll(messageID, results, pollRow["ttl"].(int))
+	if err != nil {
+		return nil, err
+	}
+
+	// Reler a enquete para obter closed_at e os resultados que prevaleceram
+	pollRow, err = mh.db.GetPoll(messageID)
+	if err != nil {
+		return nil, err
+	}
+	poll := buildPoll(pollRow, votes, "")
+
+	if applied {
+		mh.events.PublishChannelEvent(context.Background(), channelID, "poll.close", PollEvent{
+			ID:        messageID,
+			ChannelID: channelID,
+			Poll:      poll,
+		})
+		mh.logger.Info("poll closed",
+			zap.String("pollId", messageID),
+			zap.String("channelId", channelID),
+			zap.Int("voters", poll.TotalVoters),
+		)
+	}
+
+	return poll, nil
+}
+
+// pollCloseKey retorna a chave de agendamento do encerramento de uma enquete
+func pollCloseKey(messageID string) string {
+	return "poll-close:" + messageID
+}
+
+// schedulePollClose agenda o encerramento automático da enquete
+func (mh *MessageHandler) schedulePollClose(channelID, messageID string, expiresAt time.Time) {
+	mh.scheduler.Schedule(pollCloseKey(messageID), expiresAt, func() {
+		if _, err := mh.closePoll(channelID, messageID); err != nil {
+			mh.logger.Error("failed to close expired poll", zap.Error(err), zap.String("pollId", messageID))
+		}
+	})
+}
+
+// tallyPollVotes conta os votos por opção
+func tallyPollVotes(optionCount int, votes map[string][]int) []int {
+	tallies := make([]int, optionCount)
+	for _, options := range votes {
+		for _, option := range options {
+			if option >= 0 && option < optionCount {
+				tallies[option]++
+			}
+		}
+	}
+	return tallies
+}
+
+// buildPoll monta a PollResponse. Enquetes encerradas usam os resultados congelados;
+// viewerID (opcional) preenche MyVotes com as escolhas desse usuário.
+func buildPoll(pollRow map[string]interface{}, votes map[string][]int, viewerID string) *PollResponse {
+	options := pollRow["options"].([]string)
+
+	poll := &PollResponse{
+		Question:    pollRow["question"].(string),
+		Options:     make([]PollOption, len(options)),
+		MultiSelect: pollRow["multi_select"].(bool),
+		TotalVoters: len(votes),
+	}
+
+	tallies := tallyPollVotes(len(options), votes)
+	if closedAt, ok := pollRow["closed_at"].(time.Time); ok {
+		ts := closedAt.UnixMilli()
+		poll.ClosedAt = &ts
+		poll.Closed = true
+
+		results, _ := pollRow["results"].(map[int]int)
+		for i := range tallies {
+			tallies[i] = results[i]
+		}
+	}
+
+	for i, text := range options {
+		poll.Options[i] = PollOption{ID: i, Text: text, Votes: tallies[i]}
+	}
+
+	if expiresAt, ok := pollRow["expires_at"].(time.Time); ok {
+		ts := expiresAt.UnixMilli()
+		poll.ExpiresAt = &ts
+	}
+
+	if viewerID != "" {
+		poll.MyVotes = votes[viewerID]
+	}
+
+	return poll
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+// maxPresenceQuery é o número máximo de usuários por consulta de presença
+const maxPresenceQuery = 100
+
+// PresenceHandler consulta a presença persistida pelo gateway e gerencia o status personalizado
+type PresenceHandler struct {
+	logger    *zap.Logger
+	db        *database.CassandraDB
+	policy    *access.Policy
+	events    *services.EventService
+	scheduler *services.Scheduler
+}
+
+// NewPresenceHandler cria um novo handler de presença
+func NewPresenceHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy, events *services.EventService, scheduler *services.Scheduler) *PresenceHandler {
+	return &PresenceHandler{
+		logger:    logger,
+		db:        db,
+		policy:    policy,
+		events:    events,
+		scheduler: scheduler,
+	}
+}
+
+// PresenceInfo são os campos de presença incluídos nas listas de amigos e membros e na
+// consulta em lote. Status personalizado e atividades só aparecem para quem não está offline.
+type PresenceInfo struct {
+	Status       string               `json:"status"`             // online, idle, dnd, offline
+	LastSeen     int64                `json:"lastSeen,omitempty"` // apenas para offline (ms)
+	CustomStatus *models.CustomStatus `json:"customStatus,omitempty"`
+	Activities   []models.Activity    `json:"activities,omitempty"`
+}
+
+// buildPresence monta a PresenceInfo a partir da linha de user_presence (pode ser nil) e do
+// status personalizado armazenado no usuário
+func buildPresence(presenceRow map[string]interface{}, customStatus string, now time.Time) PresenceInfo {
+	presence := PresenceInfo{Status: "offline"}
+	if presenceRow == nil {
+		return presence
+	}
+
+	if status, _ := presenceRow["status"].(string); status != "" {
+		presence.Status = status
+	}
+	if presence.Status == "offline" {
+		if lastSeen, _ := presenceRow["last_seen"].(time.Time); !lastSeen.IsZero() {
+			presence.LastSeen = lastSeen.UnixMilli()
+		}
+		return presence
+	}
+
+	activities, _ := presenceRow["activities"].(string)
+	presence.Activities = models.ParseActivities(activities)
+	presence.CustomStatus = models.ParseCustomStatus(customStatus, now)
+	return presence
+}
+
+// PresenceQueryRequest representa a consulta de presença em lote
+type PresenceQueryRequest struct {
+	UserIDs []string `json:"userIds"`
+}
+
+// PresenceResponse representa a presença de um usuário
+type PresenceResponse struct {
+	UserID string `json:"userId"`
+	PresenceInfo
+}
+
+// QueryPresence retorna a presença de até maxPresenceQuery usuários. Usuários que não
+// compartilham um servidor ou amizade com quem consulta são omitidos da resposta.
+func (ph *PresenceHandler) QueryPresence(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PresenceQueryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(req.UserIDs) > maxPresenceQuery {
+		http.Error(w, "too many userIds (max 100)", http.StatusBadRequest)
+		return
+	}
+
+	related, err := ph.policy.RelatedUsers(claims.UserID)
+	if err != nil {
+		ph.logger.Error("failed to load related users", zap.String("userId", claims.UserID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	var userIDs []string
+	seen := make(map[string]bool)
+	for _, userID := range req.UserIDs {
+		if _, err := gocql.ParseUUID(userID); err != nil {
+			http.Error(w, "invalid user ID: "+userID, http.StatusBadRequest)
+			return
+		}
+		if related[userID] && !seen[userID] {
+			seen[userID] = true
+			userIDs = append(userIDs, userID)
+		}
+	}
+
+	presences := make([]PresenceResponse, 0, len(userIDs))
+	if len(userIDs) > 0 {
+		stored, customStatuses, err := loadPresences(ph.db, userIDs)
+		if err != nil {
+			ph.logger.Error("failed to get presences", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		for _, userID := range userIDs {
+			presences = append(presences, PresenceResponse{
+				UserID:       userID,
+				PresenceInfo: buildPresence(stored[userID], customStatuses[userID], now),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(presences)
+}
+
+// loadPresences carrega a presença e o status personalizado de até maxPresenceQuery usuários
+func loadPresences(db *database.CassandraDB, userIDs []string) (map[string]map[string]interface{}, map[string]string, error) {
+	rows, err := db.GetUserPresences(userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	customStatuses, err := db.GetUserCustomStatuses(userIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored := make(map[string]map[string]interface{}, len(rows))
+	for _, row := range rows {
+		stored[row["user_id"].(string)] = row
+	}
+	return stored, customStatuses, nil
+}
+
+// customStatusKey retorna a chave de agendamento da expiração do status personalizado
+func customStatusKey(userID string) string {
+	return "custom-status:" + userID
+}
+
+// SetCustomStatus define o status personalizado do usuário autenticado (PUT /api/users/status)
+func (ph *PresenceHandler) SetCustomStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var status models.CustomStatus
+	if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := status.Validate(time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ph.updateCustomStatus(r.Context(), claims.UserID, &status); err != nil {
+		ph.logger.Error("failed to set custom status", zap.String("userId", claims.UserID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if status.ExpiresAt != nil {
+		userID := claims.UserID
+		ph.scheduler.Schedule(customStatusKey(userID), time.UnixMilli(*status.ExpiresAt), func() {
+			if err := ph.updateCustomStatus(context.Background(), userID, nil); err != nil {
+				ph.logger.Error("failed to clear expired custom status", zap.String("userId", userID), zap.Error(err))
+			}
+		})
+	} else {
+		ph.scheduler.Cancel(customStatusKey(claims.UserID))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// ClearCustomStatus remove o status personalizado do usuário autenticado (DELETE /api/users/status)
+func (ph *PresenceHandler) ClearCustomStatus(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ph.scheduler.Cancel(customStatusKey(claims.UserID))
+	if err := ph.updateCustomStatus(r.Context(), claims.UserID, nil); err != nil {
+		ph.logger.Error("failed to clear custom status", zap.String("userId", claims.UserID), zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateCustomStatus grava o status personalizado (nil remove) e avisa o gateway, que entrega
+// a presença atualizada a quem compartilha um servidor ou amizade com o usuário.
+// Os agendamentos de expiração vivem em memória: após reiniciar a API, um status expirado
+// deixa de ser exibido (ver models.ParseCustomStatus), mas a remoção não é transmitida.
+func (ph *PresenceHandler) updateCustomStatus(ctx context.Context, userID string, status *models.CustomStatus) error {
+	stored := ""
+	if status != nil {
+		data, _ := json.Marshal(status)
+		stored = string(data)
+	}
+	if err := ph.db.UpdateUserCustomStatus(userID, stored); err != nil {
+		return err
+	}
+
+	ph.events.PublishPresenceEvent(ctx, userID, "custom_status", status)
+	return nil
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
+	"go.uber.org/zap"
+)
+
+const (
+	// jobTypePurge identifica jobs de remoção em massa de mensagens
+	jobTypePurge = "message.purge"
+
+	defaultPurgeLimit = 100
+	maxPurgeLimit     = 1000
+)
+
+// PurgeRequest representa a remoção em massa das mensagens mais recentes de um canal
+type PurgeRequest struct {
+	Limit    int    `json:"limit"`              // quantidade máxima de mensagens (padrão 100, máx. 1000)
+	AuthorID string `json:"authorId,omitempty"` // apenas mensagens deste autor
+	From     *int64 `json:"from,omitempty"`     // timestamp em milissegundos
+	To       *int64 `json:"to,omitempty"`       // timestamp em milissegundos (padrão: agora)
+}
+
+// MessageDeleteBulkEvent é o payload do evento message.delete_bulk
+type MessageDeleteBulkEvent struct {
+	ChannelID string   `json:"channelId"`
+	IDs       []string `json:"ids"`
+	Reason    string   `json:"reason"` // "purged"
+}
+
+// purgeTarget identifica uma mensagem a ser removida
+type purgeTarget struct {
+	id          string
+	ts          time.Time
+	messageType string
+}
+
+// PurgeMessages inicia a remoção em massa de mensagens (owners, admins e moderadores)
+func (mh *MessageHandler) PurgeMessages(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	var req PurgeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Limit == 0 {
+		req.Limit = defaultPurgeLimit
+	}
+	if req.Limit < 0 || req.Limit > maxPurgeLimit {
+		http.Error(w, fmt.Sprintf("limit must be between 1 and %d", maxPurgeLimit), http.StatusBadRequest)
+		return
+	}
+	if req.AuthorID != "" {
+		if _, err := gocql.ParseUUID(req.AuthorID); err != nil {
+			http.Error(w, "invalid author id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	channelRow, claims, ok := authorizeChannel(w, r, mh.logger, mh.policy, channelID)
+	if !ok {
+		return
+	}
+
+	isModerator, err := mh.db.IsChannelModerator(channelID, claims.UserID)
+	if err != nil {
+		mh.logger.Warn("failed to check moderator status", zap.Error(err), zap.String("channelId", channelID))
+	}
+	if !isModerator {
+		http.Error(w, "forbidden: only server owners, admins and moderators can purge messages", http.StatusForbidden)
+		return
+	}
+
+	to := time.Now()
+	if req.To != nil {
+		to = time.UnixMilli(*req.To)
+	}
+	from, _ := channelRow["created_at"].(time.Time)
+	if from.IsZero() {
+		from = to.AddDate(-5, 0, 0)
+	}
+	if req.From != nil {
+		from = time.UnixMilli(*req.From)
+	}
+	if from.After(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	job := mh.jobs.Start(jobTypePurge, claims.UserID, channelID, func(ctx context.Context, progress *services.JobProgress) (map[string]interface{}, error) {
+		return mh.runPurge(ctx, progress, channelID, req.AuthorID, from, to, req.Limit)
+	})
+
+	mh.logger.Info("message purge started",
+		zap.String("jobId", job.ID),
+		zap.String("channelId", channelID),
+		zap.String("userId", claims.UserID),
+		zap.Int("limit", req.Limit),
+		zap.String("authorId", req.AuthorID),
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
+}
+
+// GetPurge retorna o andamento de um job de remoção em massa
+func (mh *MessageHandler) GetPurge(w http.ResponseWriter, r *http.Request) {
+	jobID := r.URL.Query().Get("id")
+	if jobID == "" {
+		http.Error(w, "purge id required", http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := r.Context().Value("claims").(*models.Claims)
+	if !ok || claims == nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	job, ok := mh.jobs.Get(jobID)
+	if !ok || job.Type != jobTypePurge || job.OwnerID != claims.UserID {
+		http.Error(w, "purge not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// runPurge seleciona as mensagens mais recentes (bucket a bucket, do mais novo ao mais antigo),
+// remove uma a uma e publica um único message.delete_bulk ao final
+func (mh *MessageHandler) runPurge(ctx context.Context, progress *services.JobProgress, channelID, authorID string, from, to time.Time, limit int) (map[string]interface{}, error) {
+	var targets []purgeTarget
+
+	buckets := database.MessageBuckets(from, to)
+	for i := len(buckets) - 1; i >= 0 && len(targets) < limit; i-- {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		err := mh.db.IterateBucketMessages(channelID, buckets[i], from, to, true, func(row map[string]interface{}) error {
+			if authorID != "" && row["author_id"].(string) != authorID {
+				return nil
+			}
+
+			targets = append(targets, purgeTarget{
+				id:          row["msg_id"].(string),
+				ts:          row["ts"].(time.Time),
+				messageType: row["type"].(string),
+			})
+			if len(targets) >= limit {
+				return database.ErrStopIteration
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bucket %d: %w", buckets[i], err)
+		}
+	}
+
+	deleted := make([]string, 0, len(targets))
+	var deleteErr error
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			deleteErr = err
+			break
+		}
+
+		if err := mh.db.DeleteMessageByKey(channelID, target.ts, target.id); err != nil {
+			deleteErr = fmt.Errorf("failed to delete message %s: %w", target.id, err)
+			break
+		}
+
+		mh.scheduler.Cancel(expiryKey(target.id))
+		if target.messageType == messageTypePoll {
+			mh.scheduler.Cancel(pollCloseKey(target.id))
+			if err := mh.db.DeletePoll(target.id); err != nil {
+				mh.logger.Warn("failed to delete poll", zap.Error(err), zap.String("id", target.id))
+			}
+		}
+
+		deleted = append(deleted, target.id)
+		progress.Update(float64(len(deleted))/float64(len(targets)), len(deleted))
+	}
+
+	// Um único evento para o que foi removido, mesmo que o job tenha sido interrompido
+	if len(deleted) > 0 {
+		mh.events.PublishChannelEvent(context.Background(), channelID, "message.delete_bulk", MessageDeleteBulkEvent{
+			ChannelID: channelID,
+			IDs:       deleted,
+			Reason:    "purged",
+		})
+	}
+
+	if deleteErr != nil {
+		return nil, deleteErr
+	}
+
+	return map[string]interface{}{
+		"deleted": len(deleted),
+	}, nil
+}
@@ -4,22 +4,29 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/database"
 	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/services"
 	"go.uber.org/zap"
 )
 
 type ServerHandler struct {
 	logger *zap.Logger
 	db     *database.CassandraDB
+	policy *access.Policy
+	events *services.EventService
 }
 
-func NewServerHandler(logger *zap.Logger, db *database.CassandraDB) *ServerHandler {
+func NewServerHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy, events *services.EventService) *ServerHandler {
 	return &ServerHandler{
 		logger: logger,
 		db:     db,
+		policy: policy,
+		events: events,
 	}
 }
 
@@ -40,10 +47,15 @@ type ServerResponse struct {
 }
 
 type ServerMemberResponse struct {
-	ServerID string `json:"serverId"`
-	UserID   string `json:"userId"`
-	Role     string `json:"role"`
-	JoinedAt int64  `json:"joinedAt"`
+	ServerID      string `json:"serverId"`
+	UserID        string `json:"userId"`
+	Role          string `json:"role"`
+	JoinedAt      int64  `json:"joinedAt"`
+	Username      string `json:"username,omitempty"`
+	Discriminator string `json:"discriminator,omitempty"`
+	DisplayName   string `json:"displayName,omitempty"`
+	Avatar        string `json:"avatar,omitempty"`
+	*PresenceInfo        // apenas na lista de membros
 }
 
 // GetServers retorna todos os servidores do usuário
@@ -157,6 +169,10 @@ func (sh *ServerHandler) GetServerChannels(w http.ResponseWriter, r *http.Reques
 
 	serverID := parts[3]
 
+	if _, ok := authorizeServer(w, r, sh.logger, sh.policy, serverID); !ok {
+		return
+	}
+
 	channels, err := sh.db.GetServerChannels(serverID)
 	if err != nil {
 		sh.logger.Error("failed to get server channels", zap.Error(err))
@@ -168,14 +184,71 @@ func (sh *ServerHandler) GetServerChannels(w http.ResponseWriter, r *http.Reques
 	json.NewEncoder(w).Encode(channels)
 }
 
-// CreateServerChannel cria um novo canal em um servidor
-func (sh *ServerHandler) CreateServerChannel(w http.ResponseWriter, r *http.Request) {
-	claims, ok := r.Context().Value("claims").(*models.Claims)
-	if !ok || claims == nil {
-		http.Error(w, "unauthorized", http.StatusUnauthorized)
+// GetServerMembers retorna os membros do servidor com a presença de cada um
+// (/api/servers/{id}/members - GET)
+func (sh *ServerHandler) GetServerMembers(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) < 4 || parts[3] == "" {
+		http.Error(w, "server id required", http.StatusBadRequest)
 		return
 	}
+	serverID := parts[3]
+
+	if _, ok := authorizeServer(w, r, sh.logger, sh.policy, serverID); !ok {
+		return
+	}
+
+	rows, err := sh.db.GetGroupMembers(serverID)
+	if err != nil {
+		sh.logger.Error("failed to get server members", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	members := make([]ServerMemberResponse, 0, len(rows))
+	userIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		member := ServerMemberResponse{
+			ServerID: serverID,
+			UserID:   row["user_id"].(string),
+			Role:     row["role"].(string),
+			JoinedAt: row["joined_at"].(time.Time).UnixMilli(),
+		}
+		if user, err := sh.db.GetUserByID(member.UserID); err == nil {
+			member.Username, _ = user["username"].(string)
+			member.Discriminator, _ = user["discriminator"].(string)
+			member.DisplayName, _ = user["display_name"].(string)
+			member.Avatar, _ = user["avatar_url"].(string)
+		}
+		members = append(members, member)
+		userIDs = append(userIDs, member.UserID)
+	}
+
+	// Presença em lotes (consultas IN limitadas a maxPresenceQuery usuários)
+	now := time.Now()
+	for start := 0; start < len(userIDs); start += maxPresenceQuery {
+		end := start + maxPresenceQuery
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+		stored, customStatuses, err := loadPresences(sh.db, userIDs[start:end])
+		if err != nil {
+			sh.logger.Error("failed to get member presences", zap.Error(err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+		for i := start; i < end; i++ {
+			presence := buildPresence(stored[userIDs[i]], customStatuses[userIDs[i]], now)
+			members[i].PresenceInfo = &presence
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
 
+// CreateServerChannel cria um novo canal em um servidor
+func (sh *ServerHandler) CreateServerChannel(w http.ResponseWriter, r *http.Request) {
 	// Extrair server ID da URL: /api/servers/{id}/channels
 	path := r.URL.Path
 	parts := strings.Split(path, "/")
@@ -208,21 +281,14 @@ func (sh *ServerHandler) CreateServerChannel(w http.ResponseWriter, r *http.Requ
 	}
 
 	// Verificar se o usuário é membro do servidor
-	isMember, err := sh.db.IsServerMember(serverID, claims.UserID)
-	if err != nil {
-		sh.logger.Error("failed to check server membership", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	if !isMember {
-		http.Error(w, "forbidden: not a member of this server", http.StatusForbidden)
+	claims, ok := authorizeServer(w, r, sh.logger, sh.policy, serverID)
+	if !ok {
 		return
 	}
 
 	// Criar canal
 	channelID := uuid.Must(uuid.NewV4()).String()
-	err = sh.db.CreateServerChannel(channelID, serverID, req.Name, req.Description, req.Type, claims.UserID)
+	err := sh.db.CreateServerChannel(channelID, serverID, req.Name, req.Description, req.Type, claims.UserID)
 	if err != nil {
 		sh.logger.Error("failed to create server channel", zap.Error(err))
 		http.Error(w, "internal server error", http.StatusInternalServerError)
@@ -408,6 +474,13 @@ func (sh *ServerHandler) JoinServerByInvite(w http.ResponseWriter, r *http.Reque
 		zap.String("userId", claims.UserID),
 		zap.String("serverId", serverID))
 
+	sh.events.PublishServerEvent(r.Context(), serverID, "member.join", ServerMemberResponse{
+		ServerID: serverID,
+		UserID:   claims.UserID,
+		Role:     "member",
+		JoinedAt: time.Now().UnixMilli(),
+	})
+
 	// Retornar informações do servidor
 	response := ServerResponse{
 		ID:          serverID,
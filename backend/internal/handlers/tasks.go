@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/access"
 	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/services"
 	"go.uber.org/zap"
 )
 
@@ -15,13 +18,17 @@ import (
 type TaskHandler struct {
 	logger *zap.Logger
 	db     *database.CassandraDB
+	policy *access.Policy
+	events *services.EventService
 }
 
 // NewTaskHandler cria um novo handler de tarefas
-func NewTaskHandler(logger *zap.Logger, db *database.CassandraDB) *TaskHandler {
+func NewTaskHandler(logger *zap.Logger, db *database.CassandraDB, policy *access.Policy, events *services.EventService) *TaskHandler {
 	return &TaskHandler{
 		logger: logger,
 		db:     db,
+		policy: policy,
+		events: events,
 	}
 }
 
@@ -53,6 +60,15 @@ type TaskResponse struct {
 	UpdatedAt   int64    `json:"updatedAt"`
 }
 
+// TaskMoveEvent é o payload do evento task.move (mudança de coluna, status ou posição)
+type TaskMoveEvent struct {
+	Task         TaskResponse `json:"task"`
+	FromColumnID string       `json:"fromColumnId,omitempty"`
+	FromStatus   string       `json:"fromStatus"`
+	FromPosition int          `json:"fromPosition"`
+	Position     int          `json:"position"`
+}
+
 // GetTasks retorna todas as tarefas de um canal
 func (th *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 	channelID := r.URL.Query().Get("channelId")
@@ -61,6 +77,10 @@ func (th *TaskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	// Buscar tarefas do banco de dados
 	rows, err := th.db.GetTasksByChannel(channelID)
 	if err != nil {
@@ -122,6 +142,10 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	var req TaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -133,6 +157,20 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	task, err := th.createTask(channelID, req)
+	if err != nil {
+		th.logger.Error("failed to create task", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(task)
+}
+
+// createTask salva uma nova tarefa no fim do quadro (usado pela API e pelo comando /task)
+func (th *TaskHandler) createTask(channelID string, req TaskRequest) (TaskResponse, error) {
 	status := "todo"
 	if req.Status != nil && *req.Status != "" {
 		status = *req.Status
@@ -159,9 +197,7 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	// Calcular próxima posição (última posição + 1)
 	existingTasks, err := th.db.GetTasksByChannel(channelID)
 	if err != nil {
-		th.logger.Error("failed to get tasks for position calculation", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+		return TaskResponse{}, fmt.Errorf("failed to get tasks for position calculation: %w", err)
 	}
 
 	position := 0
@@ -199,9 +235,7 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 	// For now, I will proceed with what's available and fix DB later if needed.
 
 	if err := th.db.CreateTask(channelID, taskID, *req.Title, status, assigneeID, columnID, priority, req.Labels, dueDate, position); err != nil {
-		th.logger.Error("failed to create task", zap.Error(err))
-		http.Error(w, "internal server error", http.StatusInternalServerError)
-		return
+		return TaskResponse{}, err
 	}
 
 	now := time.Now().UnixMilli()
@@ -226,9 +260,7 @@ func (th *TaskHandler) CreateTask(w http.ResponseWriter, r *http.Request) {
 		zap.String("title", task.Title),
 	)
 
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(task)
+	return task, nil
 }
 
 // UpdateTask atualiza uma tarefa existente
@@ -245,6 +277,10 @@ func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	var req TaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid request body", http.StatusBadRequest)
@@ -356,6 +392,19 @@ func (th *TaskHandler) UpdateTask(w http.ResponseWriter, r *http.Request) {
 
 	th.logger.Info("task updated", zap.String("id", taskID))
 
+	previousColumnID, _ := currentTask["column_id"].(string)
+	previousStatus := currentTask["status"].(string)
+	previousPosition := currentTask["position"].(int)
+	if columnID != previousColumnID || status != previousStatus || position != previousPosition {
+		th.events.PublishChannelEvent(r.Context(), channelID, "task.move", TaskMoveEvent{
+			Task:         updatedTask,
+			FromColumnID: previousColumnID,
+			FromStatus:   previousStatus,
+			FromPosition: previousPosition,
+			Position:     position,
+		})
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(updatedTask)
 }
@@ -374,6 +423,10 @@ func (th *TaskHandler) DeleteTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	positionStr := r.URL.Query().Get("position")
 	if positionStr == "" {
 		http.Error(w, "position required", http.StatusBadRequest)
@@ -408,6 +461,10 @@ func (th *TaskHandler) GetColumns(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	rows, err := th.db.GetTaskColumns(channelID)
 	if err != nil {
 		th.logger.Error("failed to get columns", zap.Error(err))
@@ -427,6 +484,10 @@ func (th *TaskHandler) CreateColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	var req struct {
 		Name string `json:"name"`
 	}
@@ -483,6 +544,10 @@ func (th *TaskHandler) UpdateColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	position, err := strconv.Atoi(positionStr)
 	if err != nil {
 		http.Error(w, "invalid position", http.StatusBadRequest)
@@ -517,6 +582,10 @@ func (th *TaskHandler) DeleteColumn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if _, _, ok := authorizeChannel(w, r, th.logger, th.policy, channelID); !ok {
+		return
+	}
+
 	position, err := strconv.Atoi(positionStr)
 	if err != nil {
 		http.Error(w, "invalid position", http.StatusBadRequest)
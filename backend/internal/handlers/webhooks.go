@@ -0,0 +1,466 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/gofrs/uuid"
+	"github.com/nexus/backend/internal/access"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/models"
+	"github.com/nexus/backend/internal/ratelimit"
+	"github.com/nexus/backend/internal/validation"
+	"go.uber.org/zap"
+)
+
+const maxWebhookNameLength = 80
+
+// WebhookHandler gerencia webhooks de entrada (criação, gestão e execução)
+type WebhookHandler struct {
+	logger   *zap.Logger
+	db       *database.CassandraDB
+	messages *MessageHandler
+	limiter  *ratelimit.MessageLimiter
+}
+
+// NewWebhookHandler cria um novo handler de webhooks.
+// As mensagens são criadas pelo MessageHandler (mesmo caminho de save + broadcast).
+func NewWebhookHandler(logger *zap.Logger, db *database.CassandraDB, messages *MessageHandler, limiter *ratelimit.MessageLimiter) *WebhookHandler {
+	return &WebhookHandler{
+		logger:   logger,
+		db:       db,
+		messages: messages,
+		limiter:  limiter,
+	}
+}
+
+// WebhookRequest representa a criação/atualização de um webhook
+type WebhookRequest struct {
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+}
+
+// WebhookResponse representa um webhook. Token e URL só são retornados na criação
+// e quando o token é regenerado.
+type WebhookResponse struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channelId"`
+	ServerID  string `json:"serverId"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatarUrl,omitempty"`
+	CreatedBy string `json:"createdBy"`
+	CreatedAt int64  `json:"createdAt"`
+	Token     string `json:"token,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// WebhookExecuteRequest é o payload aceito por POST /api/webhooks/{id}/{token}
+type WebhookExecuteRequest struct {
+	Content   string         `json:"content"`
+	Username  string         `json:"username,omitempty"`  // substitui o nome do webhook nesta mensagem
+	AvatarURL string         `json:"avatarUrl,omitempty"` // substitui o avatar do webhook nesta mensagem
+	Embeds    []models.Embed `json:"embeds,omitempty"`
+}
+
+// ExecuteWebhook posta uma mensagem no canal do webhook (rota pública autenticada pelo token)
+func (wh *WebhookHandler) ExecuteWebhook(w http.ResponseWriter, r *http.Request) {
+	// /api/webhooks/{id}/{token}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/webhooks/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	webhookID, token := parts[0], parts[1]
+
+	webhookRow, err := wh.db.GetWebhook(webhookID)
+	if err != nil || !validWebhookToken(webhookRow["token_hash"].(string), token) {
+		if err != nil && err != gocql.ErrNotFound {
+			wh.logger.Warn("failed to get webhook", zap.Error(err), zap.String("webhookId", webhookID))
+		}
+		// Mesma resposta para webhook inexistente e token inválido
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+		return
+	}
+
+	var req WebhookExecuteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if req.Content == "" && len(req.Embeds) == 0 {
+		http.Error(w, "content or embeds are required", http.StatusBadRequest)
+		return
+	}
+	if req.Content != "" {
+		if err := validation.ValidateMessageContent(req.Content); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := validation.ValidateEmbeds(req.Embeds); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Username) > maxWebhookNameLength {
+		http.Error(w, fmt.Sprintf("username must be at most %d characters", maxWebhookNameLength), http.StatusBadRequest)
+		return
+	}
+	if !validAvatarURL(req.AvatarURL) {
+		http.Error(w, "avatar url must be http(s)", http.StatusBadRequest)
+		return
+	}
+
+	channelID := webhookRow["channel_id"].(string)
+
+	allowed, reason, retryAfter := wh.limiter.Allow(webhookID, channelID, 0, nil)
+	if !allowed {
+		wh.logger.Info("webhook rate limited", zap.String("webhookId", webhookID), zap.Duration("retryAfter", retryAfter))
+		writeRateLimited(w, reason, retryAfter)
+		return
+	}
+
+	channelRow, err := wh.db.GetChannelByID(channelID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "unknown webhook", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		wh.logger.Error("failed to get channel", zap.Error(err), zap.String("channelId", channelID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	username := strings.TrimSpace(req.Username)
+	if username == "" {
+		username = webhookRow["name"].(string)
+	}
+	avatarURL := req.AvatarURL
+	if avatarURL == "" {
+		avatarURL = webhookRow["avatar_url"].(string)
+	}
+
+	message, err := wh.messages.createMessage(r.Context(), newMessage{
+		ID:        gocql.TimeUUID(),
+		ChannelID: channelID,
+		AuthorID:  webhookID,
+		Username:  username,
+		Avatar:    avatarURL,
+		Content:   req.Content,
+		Embeds:    req.Embeds,
+		WebhookID: webhookID,
+		TTL:       resolveMessageTTL(channelRow, nil),
+	})
+	if err != nil {
+		wh.logger.Error("failed to save webhook message", zap.Error(err), zap.String("webhookId", webhookID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(message)
+}
+
+// ListWebhooks lista os webhooks de um canal (admins do servidor)
+func (wh *WebhookHandler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	if _, ok := wh.requireChannelAdmin(w, r, channelID); !ok {
+		return
+	}
+
+	rows, err := wh.db.GetChannelWebhooks(channelID)
+	if err != nil {
+		wh.logger.Error("failed to list webhooks", zap.Error(err), zap.String("channelId", channelID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	webhooks := make([]WebhookResponse, 0, len(rows))
+	for _, row := range rows {
+		webhooks = append(webhooks, webhookFromRow(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhooks)
+}
+
+// CreateWebhook cria um webhook de entrada no canal (admins do servidor)
+func (wh *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	channelID := r.URL.Query().Get("channelId")
+	if channelID == "" {
+		http.Error(w, "channel id required", http.StatusBadRequest)
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errMsg := validateWebhookRequest(&req); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	claims, ok := wh.requireChannelAdmin(w, r, channelID)
+	if !ok {
+		return
+	}
+
+	channelRow, err := wh.db.GetChannelByID(channelID)
+	if err != nil {
+		wh.logger.Error("failed to get channel", zap.Error(err), zap.String("channelId", channelID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	token, tokenHash, err := generateWebhookToken()
+	if err != nil {
+		wh.logger.Error("failed to generate webhook token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	webhookID := uuid.Must(uuid.NewV4()).String()
+	serverID := channelRow["server_id"].(string)
+
+	if err := wh.db.CreateWebhook(webhookID, channelID, serverID, req.Name, req.AvatarURL, tokenHash, claims.UserID); err != nil {
+		wh.logger.Error("failed to create webhook", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	wh.logger.Info("webhook created",
+		zap.String("webhookId", webhookID),
+		zap.String("channelId", channelID),
+		zap.String("userId", claims.UserID),
+	)
+
+	response := WebhookResponse{
+		ID:        webhookID,
+		ChannelID: channelID,
+		ServerID:  serverID,
+		Name:      req.Name,
+		AvatarURL: req.AvatarURL,
+		CreatedBy: claims.UserID,
+		CreatedAt: time.Now().UnixMilli(),
+		Token:     token,
+		URL:       webhookURL(webhookID, token),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// UpdateWebhook altera o nome e o avatar padrão de um webhook (admins do servidor)
+func (wh *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookRow, claims, ok := wh.getManagedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	var req WebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if errMsg := validateWebhookRequest(&req); errMsg != "" {
+		http.Error(w, errMsg, http.StatusBadRequest)
+		return
+	}
+
+	webhookID := webhookRow["webhook_id"].(string)
+	if err := wh.db.UpdateWebhook(webhookID, req.Name, req.AvatarURL); err != nil {
+		wh.logger.Error("failed to update webhook", zap.Error(err), zap.String("webhookId", webhookID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	wh.logger.Info("webhook updated", zap.String("webhookId", webhookID), zap.String("userId", claims.UserID))
+
+	webhookRow["name"] = req.Name
+	webhookRow["avatar_url"] = req.AvatarURL
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(webhookFromRow(webhookRow))
+}
+
+// RegenerateWebhookToken invalida o token atual e retorna um novo (admins do servidor)
+func (wh *WebhookHandler) RegenerateWebhookToken(w http.ResponseWriter, r *http.Request) {
+	webhookRow, claims, ok := wh.getManagedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	token, tokenHash, err := generateWebhookToken()
+	if err != nil {
+		wh.logger.Error("failed to generate webhook token", zap.Error(err))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	webhookID := webhookRow["webhook_id"].(string)
+	if err := wh.db.UpdateWebhookToken(webhookID, tokenHash); err != nil {
+		wh.logger.Error("failed to update webhook token", zap.Error(err), zap.String("webhookId", webhookID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	wh.logger.Info("webhook token regenerated", zap.String("webhookId", webhookID), zap.String("userId", claims.UserID))
+
+	response := webhookFromRow(webhookRow)
+	response.Token = token
+	response.URL = webhookURL(webhookID, token)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteWebhook remove um webhook (admins do servidor)
+func (wh *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookRow, claims, ok := wh.getManagedWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	webhookID := webhookRow["webhook_id"].(string)
+	if err := wh.db.DeleteWebhook(webhookID, webhookRow["channel_id"].(string)); err != nil {
+		wh.logger.Error("failed to delete webhook", zap.Error(err), zap.String("webhookId", webhookID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	wh.logger.Info("webhook deleted", zap.String("webhookId", webhookID), zap.String("userId", claims.UserID))
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getManagedWebhook busca o webhook indicado em ?id= e verifica se o usuário administra o servidor dele
+func (wh *WebhookHandler) getManagedWebhook(w http.ResponseWriter, r *http.Request) (map[string]interface{}, *models.Claims, bool) {
+	webhookID := r.URL.Query().Get("id")
+	if webhookID == "" {
+		http.Error(w, "webhook id required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	webhookRow, err := wh.db.GetWebhook(webhookID)
+	if err == gocql.ErrNotFound {
+		http.Error(w, "webhook not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	if err != nil {
+		wh.logger.Error("failed to get webhook", zap.Error(err), zap.String("webhookId", webhookID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, nil, false
+	}
+
+	claims, ok := wh.requireChannelAdmin(w, r, webhookRow["channel_id"].(string))
+	if !ok {
+		return nil, nil, false
+	}
+
+	return webhookRow, claims, true
+}
+
+// requireChannelAdmin verifica se o usuário é dono ou admin do servidor do canal.
+// Escreve a resposta de erro e retorna false caso contrário.
+func (wh *WebhookHandler) requireChannelAdmin(w http.ResponseWriter, r *http.Request, channelID string) (*models.Claims, bool) {
+	channelRow, claims, ok := authorizeChannel(w, r, wh.logger, wh.messages.policy, channelID)
+	if !ok {
+		return nil, false
+	}
+
+	serverID := access.ServerID(channelRow)
+	if serverID == "" {
+		http.Error(w, "webhooks are only available in server channels", http.StatusBadRequest)
+		return nil, false
+	}
+
+	isAdmin, err := wh.db.IsServerAdmin(serverID, claims.UserID)
+	if err != nil {
+		wh.logger.Error("failed to check server admin", zap.Error(err), zap.String("serverId", serverID))
+		http.Error(w, "internal server error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if !isAdmin {
+		http.Error(w, "forbidden: only server owners and admins can manage webhooks", http.StatusForbidden)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// validateWebhookRequest normaliza e valida nome e avatar. Retorna a mensagem de erro ou ""
+func validateWebhookRequest(req *WebhookRequest) string {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxWebhookNameLength {
+		return fmt.Sprintf("webhook name must be between 1 and %d characters", maxWebhookNameLength)
+	}
+	if !validAvatarURL(req.AvatarURL) {
+		return "avatar url must be http(s)"
+	}
+	return ""
+}
+
+// validAvatarURL indica se o avatar é vazio, uma URL http(s) ou uma imagem enviada à API
+func validAvatarURL(avatarURL string) bool {
+	return avatarURL == "" || strings.HasPrefix(avatarURL, "https://") || strings.HasPrefix(avatarURL, "http://") || strings.HasPrefix(avatarURL, "/api/images/")
+}
+
+// generateWebhookToken gera um token secreto e o hash SHA-256 armazenado no banco
+func generateWebhookToken() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", err
+	}
+
+	token := hex.EncodeToString(bytes)
+	return token, hashWebhookToken(token), nil
+}
+
+// hashWebhookToken retorna o hash SHA-256 (hex) de um token
+func hashWebhookToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// validWebhookToken compara o token recebido com o hash armazenado em tempo constante
+func validWebhookToken(tokenHash, token string) bool {
+	return subtle.ConstantTimeCompare([]byte(tokenHash), []byte(hashWebhookToken(token))) == 1
+}
+
+// webhookURL retorna o caminho de execução de um webhook
+func webhookURL(webhookID, token string) string {
+	return "/api/webhooks/" + webhookID + "/" + token
+}
+
+// webhookFromRow converte uma linha de webhooks em WebhookResponse (sem o token)
+func webhookFromRow(row map[string]interface{}) WebhookResponse {
+	response := WebhookResponse{
+		ID:        row["webhook_id"].(string),
+		ChannelID: row["channel_id"].(string),
+		ServerID:  row["server_id"].(string),
+		Name:      row["name"].(string),
+		AvatarURL: row["avatar_url"].(string),
+		CreatedBy: row["created_by"].(string),
+	}
+	if createdAt, ok := row["created_at"].(time.Time); ok {
+		response.CreatedAt = createdAt.UnixMilli()
+	}
+	return response
+}
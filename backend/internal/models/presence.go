@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+	"unicode/utf8"
+)
+
+// Tipos de atividade. "voice" é gerada pelo gateway; as demais são informadas pelo cliente.
+const (
+	ActivityVoice     = "voice"     // em um canal de voz
+	ActivityTask      = "task"      // editando uma tarefa
+	ActivityPlaying   = "playing"   // jogando
+	ActivityListening = "listening" // ouvindo
+	ActivityWatching  = "watching"  // assistindo
+)
+
+const (
+	// MaxCustomStatusText é o tamanho máximo (em caracteres) do texto do status personalizado
+	MaxCustomStatusText = 128
+
+	// MaxActivities é o número máximo de atividades informadas por sessão
+	MaxActivities = 5
+
+	maxActivityName = 128
+	maxStatusEmoji  = 64
+)
+
+// CustomStatus é o status personalizado do usuário (emoji e texto), com expiração opcional
+type CustomStatus struct {
+	Text      string `json:"text,omitempty"`
+	Emoji     string `json:"emoji,omitempty"`     // emoji unicode ou ID de emoji customizado
+	ExpiresAt *int64 `json:"expiresAt,omitempty"` // ms; nil = não expira
+}
+
+// Activity é uma atividade exibida na presença do usuário
+type Activity struct {
+	Type      string `json:"type"`
+	Name      string `json:"name,omitempty"` // jogo, música, título da tarefa...
+	ChannelID string `json:"channelId,omitempty"`
+	TaskID    string `json:"taskId,omitempty"`
+	Since     int64  `json:"since,omitempty"` // ms
+}
+
+// Validate verifica um status personalizado enviado pelo usuário
+func (s *CustomStatus) Validate(now time.Time) error {
+	if s.Text == "" && s.Emoji == "" {
+		return errors.New("custom status requires text or emoji")
+	}
+	if utf8.RuneCountInString(s.Text) > MaxCustomStatusText {
+		return errors.New("custom status text is too long (max 128 characters)")
+	}
+	if len(s.Emoji) > maxStatusEmoji {
+		return errors.New("custom status emoji is too long")
+	}
+	if s.ExpiresAt != nil && *s.ExpiresAt <= now.UnixMilli() {
+		return errors.New("custom status expiry must be in the future")
+	}
+	return nil
+}
+
+// Expired indica se o status personalizado já expirou
+func (s *CustomStatus) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && *s.ExpiresAt <= now.UnixMilli()
+}
+
+// ParseCustomStatus decodifica o status personalizado armazenado no usuário.
+// Retorna nil se não houver status ou se já tiver expirado.
+func ParseCustomStatus(stored string, now time.Time) *CustomStatus {
+	if stored == "" {
+		return nil
+	}
+	var status CustomStatus
+	if err := json.Unmarshal([]byte(stored), &status); err != nil || status.Expired(now) {
+		return nil
+	}
+	return &status
+}
+
+// Validate verifica uma atividade informada pelo cliente
+func (a *Activity) Validate() error {
+	switch a.Type {
+	case ActivityTask:
+		if a.TaskID == "" {
+			return errors.New("task activity requires taskId")
+		}
+	case ActivityPlaying, ActivityListening, ActivityWatching:
+		if a.Name == "" {
+			return errors.New("activity requires a name")
+		}
+	default:
+		return errors.New("invalid activity type")
+	}
+	if utf8.RuneCountInString(a.Name) > maxActivityName {
+		return errors.New("activity name is too long (max 128 characters)")
+	}
+	return nil
+}
+
+// ParseActivities decodifica as atividades armazenadas na presença do usuário
+func ParseActivities(stored string) []Activity {
+	if stored == "" {
+		return nil
+	}
+	var activities []Activity
+	json.Unmarshal([]byte(stored), &activities)
+	return activities
+}
@@ -129,3 +129,52 @@ type VoiceSession struct {
 	StartedAt time.Time
 	EndedAt   *time.Time
 }
+
+// Embed representa um conteúdo rico anexado a uma mensagem (webhooks, bots, links)
+type Embed struct {
+	Type        string         `json:"type,omitempty"` // "rich" (padrão), "link", "article", "image", "video"
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	URL         string         `json:"url,omitempty"`
+	Color       int            `json:"color,omitempty"`     // RGB, ex.: 0x5865F2
+	Timestamp   string         `json:"timestamp,omitempty"` // RFC 3339
+	Author      *EmbedAuthor   `json:"author,omitempty"`
+	Fields      []EmbedField   `json:"fields,omitempty"`
+	Image       *EmbedMedia    `json:"image,omitempty"`
+	Thumbnail   *EmbedMedia    `json:"thumbnail,omitempty"`
+	Footer      *EmbedFooter   `json:"footer,omitempty"`
+	Provider    *EmbedProvider `json:"provider,omitempty"` // site de origem de links desdobrados
+}
+
+// EmbedAuthor é o autor exibido no topo de um embed
+type EmbedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"iconUrl,omitempty"`
+}
+
+// EmbedField é um par nome/valor exibido em um embed
+type EmbedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// EmbedMedia é uma imagem de um embed
+type EmbedMedia struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// EmbedProvider identifica o site de onde veio um embed de link
+type EmbedProvider struct {
+	Name string `json:"name,omitempty"`
+	URL  string `json:"url,omitempty"`
+}
+
+// EmbedFooter é o rodapé de um embed
+type EmbedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"iconUrl,omitempty"`
+}
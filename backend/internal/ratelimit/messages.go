@@ -0,0 +1,172 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultBurst é quantas mensagens um usuário pode enviar em rajada
+	DefaultBurst = 5
+	// DefaultRefill é o intervalo para recuperar uma mensagem da rajada
+	DefaultRefill = time.Second
+
+	// WebhookBurst e WebhookRefill limitam o envio de cada webhook de entrada
+	WebhookBurst  = 5
+	WebhookRefill = 2 * time.Second
+
+	// MaxSlowmode é o maior intervalo de slowmode aceito por canal
+	MaxSlowmode = 6 * time.Hour
+)
+
+// Reason identifica qual limite bloqueou o envio
+type Reason string
+
+const (
+	ReasonNone     Reason = ""
+	ReasonSlowmode Reason = "slowmode"
+	ReasonBurst    Reason = "burst"
+)
+
+// SlowmodeStore guarda o último envio de cada usuário por canal fora do processo, para que o
+// slowmode valha em todas as réplicas da API e em todos os nós do gateway
+type SlowmodeStore interface {
+	// ReserveSlowmode registra um envio se não houver outro do usuário no canal dentro de
+	// slowmode. Caso haja, retorna false e quando o envio anterior foi feito.
+	ReserveSlowmode(channelID, userID string, slowmode time.Duration) (bool, time.Time, error)
+}
+
+// MessageLimiter aplica slowmode por canal (uma mensagem a cada N segundos por usuário)
+// e um limite de rajada de mensagens por usuário, somando todos os canais.
+// O slowmode é compartilhado pelo SlowmodeStore (quando informado); o limite de rajada é
+// mantido em memória e vale por processo.
+type MessageLimiter struct {
+	mu       sync.Mutex
+	lastSent map[string]time.Time // "channelID:userID" -> último envio conhecido (cache do store)
+	users    map[string]*userLimiter
+	refill   time.Duration
+	burst    int
+	store    SlowmodeStore // nil = slowmode apenas em memória
+}
+
+type userLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewMessageLimiter cria um limitador com a rajada e o intervalo de recarga informados.
+// store pode ser nil para limitadores sem slowmode (ex: webhooks) ou de um único processo.
+func NewMessageLimiter(burst int, refill time.Duration, store SlowmodeStore) *MessageLimiter {
+	ml := &MessageLimiter{
+		lastSent: make(map[string]time.Time),
+		users:    make(map[string]*userLimiter),
+		refill:   refill,
+		burst:    burst,
+		store:    store,
+	}
+
+	// Limpar entradas antigas periodicamente
+	go func() {
+		ticker := time.NewTicker(1 * time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ml.cleanup()
+		}
+	}()
+
+	return ml
+}
+
+// Allow verifica se userID pode enviar uma mensagem em channelID agora.
+// Se não puder, retorna o motivo e quanto tempo falta até o próximo envio permitido.
+// exempt (pode ser nil) é consultado apenas quando o envio seria bloqueado; se retornar
+// true (ex: moderadores do canal) o envio é aceito sem ser contabilizado.
+// Um envio só é contabilizado quando é aceito.
+func (ml *MessageLimiter) Allow(userID, channelID string, slowmode time.Duration, exempt func() bool) (bool, Reason, time.Duration) {
+	reason, retryAfter := ml.reserve(userID, channelID, slowmode, time.Now())
+	if reason == ReasonNone {
+		return true, ReasonNone, 0
+	}
+	if exempt != nil && exempt() {
+		return true, ReasonNone, 0
+	}
+	return false, reason, retryAfter
+}
+
+// reserve contabiliza o envio, ou retorna o limite que o bloqueou
+func (ml *MessageLimiter) reserve(userID, channelID string, slowmode time.Duration, now time.Time) (Reason, time.Duration) {
+	key := channelID + ":" + userID
+
+	ml.mu.Lock()
+	if slowmode > 0 {
+		if last, ok := ml.lastSent[key]; ok {
+			if elapsed := now.Sub(last); elapsed < slowmode {
+				ml.mu.Unlock()
+				return ReasonSlowmode, slowmode - elapsed
+			}
+		}
+	}
+
+	ul, ok := ml.users[userID]
+	if !ok {
+		ul = &userLimiter{limiter: rate.NewLimiter(rate.Every(ml.refill), ml.burst)}
+		ml.users[userID] = ul
+	}
+	ul.lastSeen = now
+
+	reservation := ul.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		ml.mu.Unlock()
+		return ReasonBurst, delay
+	}
+	ml.mu.Unlock()
+
+	if slowmode > 0 && ml.store != nil {
+		// Envios por outras réplicas ou nós. Se o store falhar, vale apenas o estado local.
+		applied, last, err := ml.store.ReserveSlowmode(channelID, userID, slowmode)
+		if err == nil && !applied {
+			reservation.CancelAt(now)
+			retryAfter := slowmode - now.Sub(last)
+			if retryAfter <= 0 || retryAfter > slowmode {
+				retryAfter = slowmode
+			}
+
+			ml.mu.Lock()
+			ml.lastSent[key] = now.Add(retryAfter - slowmode)
+			ml.mu.Unlock()
+			return ReasonSlowmode, retryAfter
+		}
+	}
+
+	if slowmode > 0 {
+		ml.mu.Lock()
+		ml.lastSent[key] = now
+		ml.mu.Unlock()
+	}
+	return ReasonNone, 0
+}
+
+// cleanup remove entradas que não podem mais bloquear envios
+func (ml *MessageLimiter) cleanup() {
+	ml.mu.Lock()
+	defer ml.mu.Unlock()
+
+	now := time.Now()
+	for key, last := range ml.lastSent {
+		if now.Sub(last) > MaxSlowmode {
+			delete(ml.lastSent, key)
+		}
+	}
+
+	// Após burst*refill sem envios a rajada está totalmente recarregada
+	idle := time.Duration(ml.burst) * ml.refill
+	for userID, ul := range ml.users {
+		if now.Sub(ul.lastSeen) > idle {
+			delete(ml.users, userID)
+		}
+	}
+}
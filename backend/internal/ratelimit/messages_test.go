@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// memorySlowmodeStore é um SlowmodeStore compartilhado entre limitadores (réplicas) do teste
+type memorySlowmodeStore struct {
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func (s *memorySlowmodeStore) ReserveSlowmode(channelID, userID string, slowmode time.Duration) (bool, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := channelID + ":" + userID
+	if last, ok := s.lastSent[key]; ok && time.Since(last) < slowmode {
+		return false, last, nil
+	}
+	s.lastSent[key] = time.Now()
+	return true, time.Time{}, nil
+}
+
+func TestMessageLimiterSlowmode(t *testing.T) {
+	ml := NewMessageLimiter(DefaultBurst, DefaultRefill, nil)
+
+	if ok, _, _ := ml.Allow("user", "channel", time.Minute, nil); !ok {
+		t.Fatal("first send in slowmode channel was blocked")
+	}
+	ok, reason, retryAfter := ml.Allow("user", "channel", time.Minute, nil)
+	if ok || reason != ReasonSlowmode {
+		t.Fatalf("second send = %v %q, want slowmode", ok, reason)
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("retryAfter = %v, want (0, 1m]", retryAfter)
+	}
+
+	// O slowmode é por canal e por usuário
+	if ok, _, _ := ml.Allow("user", "other-channel", time.Minute, nil); !ok {
+		t.Fatal("send in another channel was blocked")
+	}
+	if ok, _, _ := ml.Allow("other-user", "channel", time.Minute, nil); !ok {
+		t.Fatal("send by another user was blocked")
+	}
+}
+
+func TestMessageLimiterBurst(t *testing.T) {
+	ml := NewMessageLimiter(3, time.Hour, nil)
+
+	// A rajada soma todos os canais
+	for i, channelID := range []string{"a", "b", "c"} {
+		if ok, _, _ := ml.Allow("user", channelID, 0, nil); !ok {
+			t.Fatalf("send %d within burst was blocked", i+1)
+		}
+	}
+	ok, reason, retryAfter := ml.Allow("user", "d", 0, nil)
+	if ok || reason != ReasonBurst || retryAfter <= 0 {
+		t.Fatalf("send over burst = %v %q %v, want burst with retryAfter", ok, reason, retryAfter)
+	}
+
+	// Envios bloqueados não consomem a rajada de outros usuários
+	if ok, _, _ := ml.Allow("other-user", "a", 0, nil); !ok {
+		t.Fatal("send by another user was blocked")
+	}
+}
+
+func TestMessageLimiterExempt(t *testing.T) {
+	ml := NewMessageLimiter(1, time.Hour, nil)
+	moderator := func() bool { return true }
+
+	calls := 0
+	counted := func() bool { calls++; return false }
+	if ok, _, _ := ml.Allow("user", "channel", time.Minute, counted); !ok {
+		t.Fatal("first send was blocked")
+	}
+	if calls != 0 {
+		t.Fatal("exempt was checked for an allowed send")
+	}
+
+	// Moderadores passam pelo slowmode e pela rajada
+	for i := 0; i < 3; i++ {
+		if ok, reason, _ := ml.Allow("user", "channel", time.Minute, moderator); !ok {
+			t.Fatalf("exempt send %d blocked by %q", i+1, reason)
+		}
+	}
+	if ok, _, _ := ml.Allow("user", "channel", time.Minute, counted); ok || calls != 1 {
+		t.Fatalf("non-exempt send = %v (exempt checked %d times), want blocked", ok, calls)
+	}
+}
+
+// TestMessageLimiterSharedSlowmode verifica que o slowmode vale entre processos que
+// compartilham o store (réplicas da API e nós do gateway)
+func TestMessageLimiterSharedSlowmode(t *testing.T) {
+	store := &memorySlowmodeStore{lastSent: make(map[string]time.Time)}
+	api := NewMessageLimiter(DefaultBurst, DefaultRefill, store)
+	gateway := NewMessageLimiter(DefaultBurst, DefaultRefill, store)
+
+	if ok, _, _ := api.Allow("user", "channel", time.Minute, nil); !ok {
+		t.Fatal("first send was blocked")
+	}
+	ok, reason, retryAfter := gateway.Allow("user", "channel", time.Minute, nil)
+	if ok || reason != ReasonSlowmode || retryAfter <= 0 || retryAfter > time.Minute {
+		t.Fatalf("send on another replica = %v %q %v, want slowmode", ok, reason, retryAfter)
+	}
+
+	// O envio bloqueado pelo store não consome a rajada local
+	for i := 0; i < DefaultBurst; i++ {
+		if ok, reason, _ := gateway.Allow("user", "channel-"+string(rune('a'+i)), 0, nil); !ok {
+			t.Fatalf("send %d blocked by %q", i+1, reason)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"go.uber.org/zap"
+)
+
+// Status de um job em segundo plano
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobCompleted = "completed"
+	JobFailed    = "failed"
+)
+
+// Job representa uma tarefa demorada executada em segundo plano (export, purge, ...)
+type Job struct {
+	ID         string                 `json:"id"`
+	Type       string                 `json:"type"`
+	OwnerID    string                 `json:"ownerId"`
+	ChannelID  string                 `json:"channelId,omitempty"`
+	Status     string                 `json:"status"`
+	Progress   float64                `json:"progress"`  // 0..1
+	Processed  int                    `json:"processed"` // itens processados até agora
+	Result     map[string]interface{} `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	CreatedAt  time.Time              `json:"createdAt"`
+	FinishedAt *time.Time             `json:"finishedAt,omitempty"`
+}
+
+// JobFunc executa o trabalho do job, reportando o andamento em progress
+type JobFunc func(ctx context.Context, progress *JobProgress) (map[string]interface{}, error)
+
+// JobProgress permite que o JobFunc atualize o andamento do job
+type JobProgress struct {
+	manager *JobManager
+	id      string
+}
+
+// JobID retorna o ID do job em execução
+func (p *JobProgress) JobID() string {
+	return p.id
+}
+
+// Update registra o andamento (fração de 0 a 1 e itens processados)
+func (p *JobProgress) Update(progress float64, processed int) {
+	p.manager.mu.Lock()
+	defer p.manager.mu.Unlock()
+
+	if job, ok := p.manager.jobs[p.id]; ok {
+		job.Progress = progress
+		job.Processed = processed
+	}
+}
+
+// JobManager executa e acompanha jobs em segundo plano.
+// Os jobs vivem apenas em memória e são descartados após retention.
+type JobManager struct {
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	cleanup   map[string]func() // limpeza de artefatos (arquivos) ao descartar o job
+	retention time.Duration
+	ctx       context.Context
+	cancel    context.CancelFunc
+	logger    *zap.Logger
+}
+
+// NewJobManager cria um novo gerenciador de jobs
+func NewJobManager(logger *zap.Logger, retention time.Duration) *JobManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	jm := &JobManager{
+		jobs:      make(map[string]*Job),
+		cleanup:   make(map[string]func()),
+		retention: retention,
+		ctx:       ctx,
+		cancel:    cancel,
+		logger:    logger,
+	}
+
+	go jm.janitor()
+
+	return jm
+}
+
+// Start cria um job e executa fn em uma goroutine. Retorna uma cópia do job criado
+func (jm *JobManager) Start(jobType, ownerID, channelID string, fn JobFunc) Job {
+	job := &Job{
+		ID:        uuid.Must(uuid.NewV4()).String(),
+		Type:      jobType,
+		OwnerID:   ownerID,
+		ChannelID: channelID,
+		Status:    JobPending,
+		CreatedAt: time.Now(),
+	}
+
+	jm.mu.Lock()
+	jm.jobs[job.ID] = job
+	snapshot := *job
+	jm.mu.Unlock()
+
+	go jm.run(job.ID, fn)
+
+	return snapshot
+}
+
+// run executa o job e registra o resultado
+func (jm *JobManager) run(id string, fn JobFunc) {
+	jm.setStatus(id, JobRunning)
+
+	var result map[string]interface{}
+	var err error
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				jm.logger.Error("job panicked", zap.String("jobId", id), zap.Any("error", r))
+				err = fmt.Errorf("job panicked: %v", r)
+			}
+		}()
+		result, err = fn(jm.ctx, &JobProgress{manager: jm, id: id})
+	}()
+
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	job.FinishedAt = &now
+	if err != nil {
+		job.Status = JobFailed
+		job.Error = err.Error()
+		jm.logger.Error("job failed", zap.String("jobId", id), zap.String("type", job.Type), zap.Error(err))
+		return
+	}
+
+	job.Status = JobCompleted
+	job.Progress = 1
+	job.Result = result
+	jm.logger.Info("job completed", zap.String("jobId", id), zap.String("type", job.Type), zap.Int("processed", job.Processed))
+}
+
+// setStatus atualiza o status de um job
+func (jm *JobManager) setStatus(id, status string) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	if job, ok := jm.jobs[id]; ok {
+		job.Status = status
+	}
+}
+
+// Get retorna uma cópia do job
+func (jm *JobManager) Get(id string) (Job, bool) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	job, ok := jm.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// OnDiscard registra uma função de limpeza executada quando o job for descartado
+func (jm *JobManager) OnDiscard(id string, fn func()) {
+	jm.mu.Lock()
+	defer jm.mu.Unlock()
+
+	jm.cleanup[id] = fn
+}
+
+// Stop cancela os jobs em andamento e encerra a limpeza periódica
+func (jm *JobManager) Stop() {
+	jm.cancel()
+}
+
+// janitor descarta jobs finalizados há mais de retention
+func (jm *JobManager) janitor() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-jm.ctx.Done():
+			return
+		case now := <-ticker.C:
+			var discarded []func()
+
+			jm.mu.Lock()
+			for id, job := range jm.jobs {
+				if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > jm.retention {
+					delete(jm.jobs, id)
+					if fn, ok := jm.cleanup[id]; ok {
+						discarded = append(discarded, fn)
+						delete(jm.cleanup, id)
+					}
+				}
+			}
+			jm.mu.Unlock()
+
+			for _, fn := range discarded {
+				fn()
+			}
+		}
+	}
+}
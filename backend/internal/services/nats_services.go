@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -154,6 +155,144 @@ func (vs *VoiceService) PublishVoiceEvent(ctx context.Context, channelID string,
 	return nil
 }
 
+// Event representa um evento entregue aos clientes pelo gateway WebSocket
+type Event struct {
+	Type      string      `json:"type"`
+	ChannelID string      `json:"channelId,omitempty"`
+	ServerID  string      `json:"serverId,omitempty"`
+	UserID    string      `json:"userId,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// PresenceEventsSubject é o subject NATS das mudanças de presença publicadas pela API
+const PresenceEventsSubject = "events.presence"
+
+// ChannelEventsSubject retorna o subject NATS de eventos de um canal
+func ChannelEventsSubject(channelID string) string {
+	return fmt.Sprintf("events.channel.%s", channelID)
+}
+
+// ServerEventsSubject retorna o subject NATS de eventos de um servidor (ex.: entrada de membros)
+func ServerEventsSubject(serverID string) string {
+	return fmt.Sprintf("events.server.%s", serverID)
+}
+
+// UserEventsSubject retorna o subject NATS de eventos destinados a um único usuário
+func UserEventsSubject(userID string) string {
+	return fmt.Sprintf("events.user.%s", userID)
+}
+
+// EventService publica eventos gerados pela API para o gateway WebSocket
+type EventService struct {
+	nc     *nats.Conn
+	logger *zap.Logger
+}
+
+// NewEventService cria um novo serviço de eventos
+func NewEventService(nc *nats.Conn, logger *zap.Logger) *EventService {
+	return &EventService{
+		nc:     nc,
+		logger: logger,
+	}
+}
+
+// PublishChannelEvent publica um evento para todos os clientes inscritos em um canal
+func (es *EventService) PublishChannelEvent(ctx context.Context, channelID, eventType string, data interface{}) error {
+	event := Event{
+		Type:      eventType,
+		ChannelID: channelID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := ChannelEventsSubject(channelID)
+	if err := es.nc.Publish(subject, payload); err != nil {
+		es.logger.Error("failed to publish channel event", zap.Error(err), zap.String("type", eventType))
+		return err
+	}
+
+	es.logger.Debug("channel event published", zap.String("subject", subject), zap.String("type", eventType))
+	return nil
+}
+
+// PublishServerEvent publica um evento que diz respeito ao servidor como um todo
+func (es *EventService) PublishServerEvent(ctx context.Context, serverID, eventType string, data interface{}) error {
+	event := Event{
+		Type:      eventType,
+		ServerID:  serverID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := ServerEventsSubject(serverID)
+	if err := es.nc.Publish(subject, payload); err != nil {
+		es.logger.Error("failed to publish server event", zap.Error(err), zap.String("type", eventType))
+		return err
+	}
+
+	es.logger.Debug("server event published", zap.String("subject", subject), zap.String("type", eventType))
+	return nil
+}
+
+// PublishUserEvent publica um evento entregue apenas às conexões de um usuário (ex.: respostas efêmeras)
+func (es *EventService) PublishUserEvent(ctx context.Context, userID, eventType string, data interface{}) error {
+	event := Event{
+		Type:      eventType,
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	subject := UserEventsSubject(userID)
+	if err := es.nc.Publish(subject, payload); err != nil {
+		es.logger.Error("failed to publish user event", zap.Error(err), zap.String("type", eventType))
+		return err
+	}
+
+	es.logger.Debug("user event published", zap.String("subject", subject), zap.String("type", eventType))
+	return nil
+}
+
+// PublishPresenceEvent publica uma mudança na presença do usuário (ex.: status personalizado);
+// cada nó do gateway a entrega a quem compartilha um servidor ou amizade com ele
+func (es *EventService) PublishPresenceEvent(ctx context.Context, userID, eventType string, data interface{}) error {
+	event := Event{
+		Type:      eventType,
+		UserID:    userID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := es.nc.Publish(PresenceEventsSubject, payload); err != nil {
+		es.logger.Error("failed to publish presence event", zap.Error(err), zap.String("type", eventType))
+		return err
+	}
+
+	es.logger.Debug("presence event published", zap.String("userId", userID), zap.String("type", eventType))
+	return nil
+}
+
 // HealthCheck verifica se o NATS está conectado
 func HealthCheckNATS(nc *nats.Conn, timeout time.Duration) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Scheduler executa funções em um horário futuro, identificadas por uma chave.
+// Os agendamentos vivem apenas em memória no processo atual.
+type Scheduler struct {
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+	logger *zap.Logger
+}
+
+// NewScheduler cria um novo agendador
+func NewScheduler(logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		timers: make(map[string]*time.Timer),
+		logger: logger,
+	}
+}
+
+// Schedule agenda fn para executar em at, substituindo um agendamento existente com a mesma chave
+func (s *Scheduler) Schedule(key string, at time.Time, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.timers[key]; ok {
+		existing.Stop()
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(time.Until(at), func() {
+		s.mu.Lock()
+		// Só remover se ainda for o timer atual (pode ter sido reagendado)
+		if s.timers[key] == timer {
+			delete(s.timers, key)
+		}
+		s.mu.Unlock()
+
+		defer func() {
+			if err := recover(); err != nil {
+				s.logger.Error("scheduled task panicked", zap.String("key", key), zap.Any("error", err))
+			}
+		}()
+		fn()
+	})
+	s.timers[key] = timer
+}
+
+// Scheduled indica se há um agendamento pendente com a chave
+func (s *Scheduler) Scheduled(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.timers[key]
+	return ok
+}
+
+// Cancel cancela um agendamento pendente. Retorna false se não havia agendamento
+func (s *Scheduler) Cancel(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	timer, ok := s.timers[key]
+	if !ok {
+		return false
+	}
+	timer.Stop()
+	delete(s.timers, key)
+	return true
+}
+
+// Stop cancela todos os agendamentos pendentes
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, key)
+	}
+}
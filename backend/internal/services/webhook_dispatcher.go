@@ -0,0 +1,519 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/nats-io/nats.go"
+	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/database"
+	"github.com/nexus/backend/internal/unfurl"
+	"go.uber.org/zap"
+)
+
+// Tipos de evento que podem ser assinados por webhooks de saída
+const (
+	WebhookEventMessageCreate = "message.create"
+	WebhookEventMemberJoin    = "member.join"
+	WebhookEventTaskMove      = "task.move"
+
+	// WebhookEventPing é enviado apenas pelo teste manual de um endpoint
+	WebhookEventPing = "ping"
+)
+
+// WebhookEventTypes lista os eventos aceitos na assinatura de um endpoint
+var WebhookEventTypes = []string{WebhookEventMessageCreate, WebhookEventMemberJoin, WebhookEventTaskMove}
+
+// Status de uma tentativa de entrega
+const (
+	DeliverySucceeded = "succeeded"
+	DeliveryRetrying  = "retrying"
+	DeliveryFailed    = "failed"
+)
+
+// Cabeçalhos enviados em cada entrega
+const (
+	WebhookSignatureHeader = "X-Nexus-Signature"
+	WebhookTimestampHeader = "X-Nexus-Timestamp"
+	WebhookEventHeader     = "X-Nexus-Event"
+	WebhookDeliveryHeader  = "X-Nexus-Delivery"
+)
+
+const (
+	webhookQueueGroup       = "webhook-dispatchers"
+	webhookMaxAttempts      = 6
+	webhookBaseBackoff      = 2 * time.Second
+	webhookMaxBackoff       = 5 * time.Minute
+	webhookRequestTimeout   = 10 * time.Second
+	webhookMaxConcurrent    = 16
+	webhookMaxResponseLog   = 1024
+	webhookEndpointsTTL     = 30 * time.Second
+	webhookChannelTTL       = 5 * time.Minute
+	webhookDefaultUserAgent = "Nexus-Webhooks/1.0"
+)
+
+// ValidWebhookEvent indica se o tipo de evento pode ser assinado
+func ValidWebhookEvent(eventType string) bool {
+	for _, t := range WebhookEventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookPayload é o corpo JSON enviado aos endpoints. O ID é o mesmo em todas
+// as tentativas de uma entrega, permitindo ao receptor descartar duplicatas.
+type WebhookPayload struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	ServerID  string          `json:"serverId"`
+	ChannelID string          `json:"channelId,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// SignWebhookPayload calcula a assinatura enviada em X-Nexus-Signature:
+// "sha256=" + HMAC-SHA256(secret, "<timestamp>.<body>") em hexadecimal
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookEndpoint é a parte de um endpoint usada nas entregas
+type webhookEndpoint struct {
+	id     string
+	url    string
+	secret string
+	events map[string]bool
+}
+
+// deliveryLog grava cada tentativa de entrega (CassandraDB; substituído nos testes)
+type deliveryLog interface {
+	SaveWebhookDelivery(endpointID, deliveryID string, attempt int, eventType, status string, statusCode int, errMsg string, duration time.Duration, requestBody, responseBody string) error
+}
+
+// WebhookDispatcher assina os eventos publicados no NATS e os entrega, assinados,
+// aos endpoints registrados pelos servidores. Entregas que falham são repetidas
+// com backoff exponencial e cada tentativa fica registrada no log de entregas.
+// Várias instâncias da API dividem os eventos por um queue group do NATS.
+type WebhookDispatcher struct {
+	nc        *nats.Conn
+	db        *database.CassandraDB
+	logger    *zap.Logger
+	client    *http.Client
+	allowHTTP bool
+	log       deliveryLog
+
+	endpoints *cache.MemoryCache // serverID -> []webhookEndpoint
+	channels  *cache.MemoryCache // channelID -> serverID ("" para DMs)
+
+	sem    chan struct{}
+	subs   []*nats.Subscription
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWebhookDispatcher cria um novo dispatcher. As entregas só alcançam endereços públicos
+// (ver unfurl.NewSafeClient), já que a resposta fica visível no log de entregas.
+// allowHTTP permite endpoints http:// e endereços internos (usado em desenvolvimento para
+// testar com um receptor local; recusado em produção pela configuração)
+func NewWebhookDispatcher(nc *nats.Conn, db *database.CassandraDB, logger *zap.Logger, allowHTTP bool) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookDispatcher{
+		nc:        nc,
+		db:        db,
+		logger:    logger,
+		client:    newWebhookClient(webhookRequestTimeout, allowHTTP),
+		allowHTTP: allowHTTP,
+		log:       db,
+		endpoints: cache.NewMemoryCache(webhookEndpointsTTL),
+		channels:  cache.NewMemoryCache(webhookChannelTTL),
+		sem:       make(chan struct{}, webhookMaxConcurrent),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// newWebhookClient cria o cliente das requisições a endpoints registrados por usuários.
+// Redirecionamentos não são seguidos: o endpoint registrado é o único destino.
+func newWebhookClient(timeout time.Duration, allowHTTP bool) *http.Client {
+	if !allowHTTP {
+		return unfurl.NewSafeClient(timeout, 0)
+	}
+	return &http.Client{
+		Timeout: timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// NewClient cria um cliente com as mesmas restrições das entregas, para outras requisições
+// a URLs validadas por ValidateURL (ex.: callbacks de comandos de bots)
+func (d *WebhookDispatcher) NewClient(timeout time.Duration) *http.Client {
+	return newWebhookClient(timeout, d.allowHTTP)
+}
+
+// Start assina os eventos de canais e de servidores
+func (d *WebhookDispatcher) Start() error {
+	subjects := map[string]nats.MsgHandler{
+		"events.channel.*": d.handleChannelEvent,
+		"events.server.*":  d.handleServerEvent,
+	}
+
+	for subject, handler := range subjects {
+		sub, err := d.nc.QueueSubscribe(subject, webhookQueueGroup, handler)
+		if err != nil {
+			d.Stop()
+			return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+		}
+		d.subs = append(d.subs, sub)
+	}
+
+	d.logger.Info("webhook dispatcher started")
+	return nil
+}
+
+// Stop cancela as assinaturas e as tentativas pendentes e aguarda as entregas em andamento
+func (d *WebhookDispatcher) Stop() {
+	for _, sub := range d.subs {
+		sub.Unsubscribe()
+	}
+	d.subs = nil
+	d.cancel()
+	d.wg.Wait()
+}
+
+// ValidateURL verifica se a URL pode ser registrada como endpoint
+func (d *WebhookDispatcher) ValidateURL(rawURL string) error {
+	if len(rawURL) > 2048 {
+		return errors.New("url is too long")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errors.New("invalid url")
+	}
+	if u.User != nil {
+		return errors.New("url must not contain credentials")
+	}
+
+	// Destinos internos também são recusados na conexão; aqui o erro é claro já no cadastro
+	if !d.allowHTTP {
+		host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+		if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+			return errors.New("url must not point to a private address")
+		}
+		if ip := net.ParseIP(host); ip != nil && unfurl.IsBlockedIP(ip) {
+			return errors.New("url must not point to a private address")
+		}
+	}
+
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if d.allowHTTP {
+			return nil
+		}
+	}
+	return errors.New("url must use https")
+}
+
+// Invalidate descarta os endpoints em cache de um servidor (após alterações)
+func (d *WebhookDispatcher) Invalidate(serverID string) {
+	d.endpoints.Delete(serverID)
+}
+
+// Ping envia um evento de teste para um endpoint, ignorando a assinatura de eventos.
+// Retorna o ID da entrega, que pode ser consultado no log
+func (d *WebhookDispatcher) Ping(endpointRow map[string]interface{}) (string, error) {
+	endpoint := endpointFromRow(endpointRow)
+	data, _ := json.Marshal(map[string]string{"message": "pong"})
+
+	return d.dispatch(endpoint, WebhookPayload{
+		Type:      WebhookEventPing,
+		ServerID:  endpointRow["server_id"].(string),
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+}
+
+// handleChannelEvent resolve o servidor do canal e despacha o evento
+func (d *WebhookDispatcher) handleChannelEvent(msg *nats.Msg) {
+	var event struct {
+		Type      string          `json:"type"`
+		ChannelID string          `json:"channelId"`
+		Data      json.RawMessage `json:"data"`
+		Timestamp time.Time       `json:"timestamp"`
+	}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		d.logger.Warn("invalid channel event", zap.Error(err), zap.String("subject", msg.Subject))
+		return
+	}
+	if !ValidWebhookEvent(event.Type) {
+		return
+	}
+
+	serverID := d.channelServer(event.ChannelID)
+	if serverID == "" {
+		return
+	}
+
+	d.fanOut(WebhookPayload{
+		Type:      event.Type,
+		ServerID:  serverID,
+		ChannelID: event.ChannelID,
+		Timestamp: event.Timestamp,
+		Data:      event.Data,
+	})
+}
+
+// handleServerEvent despacha eventos publicados diretamente para um servidor
+func (d *WebhookDispatcher) handleServerEvent(msg *nats.Msg) {
+	var event struct {
+		Type      string          `json:"type"`
+		ServerID  string          `json:"serverId"`
+		Data      json.RawMessage `json:"data"`
+		Timestamp time.Time       `json:"timestamp"`
+	}
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		d.logger.Warn("invalid server event", zap.Error(err), zap.String("subject", msg.Subject))
+		return
+	}
+	if !ValidWebhookEvent(event.Type) || event.ServerID == "" {
+		return
+	}
+
+	d.fanOut(WebhookPayload{
+		Type:      event.Type,
+		ServerID:  event.ServerID,
+		Timestamp: event.Timestamp,
+		Data:      event.Data,
+	})
+}
+
+// fanOut inicia uma entrega para cada endpoint do servidor que assina o evento
+func (d *WebhookDispatcher) fanOut(payload WebhookPayload) {
+	endpoints, err := d.serverEndpoints(payload.ServerID)
+	if err != nil {
+		d.logger.Error("failed to load webhook endpoints", zap.Error(err), zap.String("serverId", payload.ServerID))
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !endpoint.events[payload.Type] {
+			continue
+		}
+		if _, err := d.dispatch(endpoint, payload); err != nil {
+			d.logger.Error("failed to dispatch webhook", zap.Error(err), zap.String("endpointId", endpoint.id))
+		}
+	}
+}
+
+// dispatch cria uma nova entrega e a executa em segundo plano
+func (d *WebhookDispatcher) dispatch(endpoint webhookEndpoint, payload WebhookPayload) (string, error) {
+	payload.ID = gocql.TimeUUID().String()
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(endpoint, payload.ID, payload.Type, body)
+	}()
+
+	return payload.ID, nil
+}
+
+// deliver envia o corpo ao endpoint, repetindo com backoff exponencial
+// enquanto a falha for temporária e houver tentativas restantes
+func (d *WebhookDispatcher) deliver(endpoint webhookEndpoint, deliveryID, eventType string, body []byte) {
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		statusCode, responseBody, retryAfter, duration, err := d.attempt(endpoint, deliveryID, eventType, body)
+
+		status := DeliverySucceeded
+		errMsg := ""
+		retryable := false
+		if err != nil {
+			errMsg = err.Error()
+			retryable = true
+		} else if statusCode < 200 || statusCode > 299 {
+			errMsg = fmt.Sprintf("unexpected status %d", statusCode)
+			retryable = statusCode == http.StatusRequestTimeout || statusCode == http.StatusTooManyRequests || statusCode >= 500
+		}
+		if errMsg != "" {
+			status = DeliveryFailed
+			if retryable && attempt < webhookMaxAttempts {
+				status = DeliveryRetrying
+			}
+		}
+
+		if err := d.log.SaveWebhookDelivery(endpoint.id, deliveryID, attempt, eventType, status, statusCode, errMsg, duration, string(body), responseBody); err != nil {
+			d.logger.Warn("failed to save webhook delivery", zap.Error(err), zap.String("deliveryId", deliveryID))
+		}
+
+		if status != DeliveryRetrying {
+			if status == DeliveryFailed {
+				d.logger.Warn("webhook delivery failed",
+					zap.String("endpointId", endpoint.id),
+					zap.String("deliveryId", deliveryID),
+					zap.Int("attempt", attempt),
+					zap.String("error", errMsg),
+				)
+			}
+			return
+		}
+
+		delay := webhookBackoff(attempt)
+		if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-d.ctx.Done():
+			timer.Stop()
+			d.logger.Info("webhook delivery abandoned on shutdown", zap.String("deliveryId", deliveryID))
+			return
+		}
+	}
+}
+
+// attempt executa uma única requisição de entrega
+func (d *WebhookDispatcher) attempt(endpoint webhookEndpoint, deliveryID, eventType string, body []byte) (int, string, time.Duration, time.Duration, error) {
+	select {
+	case d.sem <- struct{}{}:
+		defer func() { <-d.sem }()
+	case <-d.ctx.Done():
+		return 0, "", 0, 0, d.ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, endpoint.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", 0, 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", webhookDefaultUserAgent)
+	req.Header.Set(WebhookEventHeader, eventType)
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	duration := time.Since(start)
+	if err != nil {
+		return 0, "", 0, duration, err
+	}
+	defer resp.Body.Close()
+
+	responseBody, _ := io.ReadAll(io.LimitReader(resp.Body, webhookMaxResponseLog))
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	var retryAfter time.Duration
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+		retryAfter = time.Duration(seconds) * time.Second
+		if retryAfter > webhookMaxBackoff {
+			retryAfter = webhookMaxBackoff
+		}
+	}
+
+	return resp.StatusCode, string(responseBody), retryAfter, duration, nil
+}
+
+// webhookBackoff retorna a espera após a tentativa informada: 2s, 4s, 8s... até 5 minutos
+func webhookBackoff(attempt int) time.Duration {
+	delay := webhookBaseBackoff << (attempt - 1)
+	if delay <= 0 || delay > webhookMaxBackoff {
+		return webhookMaxBackoff
+	}
+	return delay
+}
+
+// serverEndpoints retorna os endpoints ativos de um servidor (com cache)
+func (d *WebhookDispatcher) serverEndpoints(serverID string) ([]webhookEndpoint, error) {
+	if cached, ok := d.endpoints.Get(serverID); ok {
+		return cached.([]webhookEndpoint), nil
+	}
+
+	rows, err := d.db.GetServerOutgoingWebhooks(serverID)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]webhookEndpoint, 0, len(rows))
+	for _, row := range rows {
+		if active, _ := row["active"].(bool); !active {
+			continue
+		}
+		endpoints = append(endpoints, endpointFromRow(row))
+	}
+
+	d.endpoints.Set(serverID, endpoints)
+	return endpoints, nil
+}
+
+// channelServer retorna o servidor de um canal, ou "" para DMs e canais inexistentes
+func (d *WebhookDispatcher) channelServer(channelID string) string {
+	if cached, ok := d.channels.Get(channelID); ok {
+		return cached.(string)
+	}
+
+	channel, err := d.db.GetChannelByID(channelID)
+	if err != nil {
+		if err != gocql.ErrNotFound {
+			d.logger.Warn("failed to get channel for webhook event", zap.Error(err), zap.String("channelId", channelID))
+		}
+		return ""
+	}
+
+	serverID, _ := channel["server_id"].(string)
+	if serverID == (gocql.UUID{}).String() {
+		serverID = ""
+	}
+
+	d.channels.Set(channelID, serverID)
+	return serverID
+}
+
+// endpointFromRow converte uma linha de outgoing_webhooks
+func endpointFromRow(row map[string]interface{}) webhookEndpoint {
+	events := make(map[string]bool)
+	for _, eventType := range row["events"].([]string) {
+		events[strings.TrimSpace(eventType)] = true
+	}
+
+	return webhookEndpoint{
+		id:     row["endpoint_id"].(string),
+		url:    row["url"].(string),
+		secret: row["secret"].(string),
+		events: events,
+	}
+}
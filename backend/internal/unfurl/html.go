@@ -0,0 +1,282 @@
+package unfurl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nexus/backend/internal/models"
+	"golang.org/x/net/html"
+)
+
+const (
+	maxTitleLength       = 256
+	maxDescriptionLength = 350
+	maxProviderLength    = 256
+)
+
+// pageMeta reúne os metadados encontrados no <head> de uma página
+type pageMeta struct {
+	title       string
+	description string
+	siteName    string
+	ogType      string
+	image       string
+	imageWidth  int
+	imageHeight int
+	themeColor  string
+	authorName  string
+	authorURL   string
+	providerURL string
+	oEmbedURL   string
+	htmlTitle   string
+}
+
+// oEmbedResponse são os campos usados de uma resposta oEmbed (JSON)
+type oEmbedResponse struct {
+	Type            string `json:"type"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name"`
+	AuthorURL       string `json:"author_url"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailWidth  int    `json:"thumbnail_width"`
+	ThumbnailHeight int    `json:"thumbnail_height"`
+}
+
+// parseHTMLMeta lê as tags <meta>, <link> e <title> até o fim do <head>
+func parseHTMLMeta(r io.Reader, base *url.URL) *pageMeta {
+	meta := &pageMeta{}
+	tokenizer := html.NewTokenizer(r)
+	inTitle := false
+
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.TextToken:
+			if inTitle && meta.htmlTitle == "" {
+				meta.htmlTitle = strings.TrimSpace(string(tokenizer.Text()))
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				return meta
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				return meta
+			case "title":
+				inTitle = true
+			case "meta":
+				if hasAttr {
+					meta.readMeta(readAttrs(tokenizer), base)
+				}
+			case "link":
+				if hasAttr {
+					meta.readLink(readAttrs(tokenizer), base)
+				}
+			}
+		}
+	}
+}
+
+func readAttrs(tokenizer *html.Tokenizer) map[string]string {
+	attrs := make(map[string]string)
+	for {
+		key, value, more := tokenizer.TagAttr()
+		attrs[strings.ToLower(string(key))] = string(value)
+		if !more {
+			return attrs
+		}
+	}
+}
+
+func (m *pageMeta) readMeta(attrs map[string]string, base *url.URL) {
+	key := strings.ToLower(attrs["property"])
+	if key == "" {
+		key = strings.ToLower(attrs["name"])
+	}
+	content := strings.TrimSpace(attrs["content"])
+	if content == "" {
+		return
+	}
+
+	switch key {
+	case "og:title":
+		m.title = content
+	case "twitter:title":
+		setIfEmpty(&m.title, content)
+	case "og:description":
+		m.description = content
+	case "twitter:description", "description":
+		setIfEmpty(&m.description, content)
+	case "og:site_name":
+		m.siteName = content
+	case "og:type":
+		m.ogType = content
+	case "og:image", "og:image:url", "og:image:secure_url":
+		setIfEmpty(&m.image, resolveURL(base, content))
+	case "twitter:image", "twitter:image:src":
+		setIfEmpty(&m.image, resolveURL(base, content))
+	case "og:image:width":
+		m.imageWidth, _ = strconv.Atoi(content)
+	case "og:image:height":
+		m.imageHeight, _ = strconv.Atoi(content)
+	case "theme-color":
+		m.themeColor = content
+	case "author", "article:author":
+		setIfEmpty(&m.authorName, content)
+	}
+}
+
+func (m *pageMeta) readLink(attrs map[string]string, base *url.URL) {
+	if strings.ToLower(attrs["rel"]) != "alternate" {
+		return
+	}
+	if strings.ToLower(attrs["type"]) == "application/json+oembed" && attrs["href"] != "" {
+		m.oEmbedURL = resolveURL(base, attrs["href"])
+	}
+}
+
+// fetchOEmbed busca o documento oEmbed descoberto na página
+func (u *Unfurler) fetchOEmbed(ctx context.Context, oEmbedURL string) (*oEmbedResponse, error) {
+	resp, err := u.get(ctx, oEmbedURL, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var oembed oEmbedResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxOEmbedSize)).Decode(&oembed); err != nil {
+		return nil, err
+	}
+	return &oembed, nil
+}
+
+// mergeOEmbed completa os metadados da página com os do oEmbed
+func (m *pageMeta) mergeOEmbed(oembed *oEmbedResponse) {
+	setIfEmpty(&m.title, oembed.Title)
+	setIfEmpty(&m.siteName, oembed.ProviderName)
+	setIfEmpty(&m.providerURL, oembed.ProviderURL)
+	setIfEmpty(&m.authorName, oembed.AuthorName)
+	setIfEmpty(&m.authorURL, oembed.AuthorURL)
+	if m.image == "" && (strings.HasPrefix(oembed.ThumbnailURL, "https://") || strings.HasPrefix(oembed.ThumbnailURL, "http://")) {
+		m.image = oembed.ThumbnailURL
+		m.imageWidth = oembed.ThumbnailWidth
+		m.imageHeight = oembed.ThumbnailHeight
+	}
+	if oembed.Type == "video" || oembed.Type == "photo" {
+		m.ogType = oembed.Type
+	}
+}
+
+// embed monta o embed do link. Retorna nil quando não há nada útil para exibir
+func (m *pageMeta) embed(rawURL string) *models.Embed {
+	title := m.title
+	if title == "" {
+		title = m.htmlTitle
+	}
+	if title == "" && m.description == "" && m.image == "" {
+		return nil
+	}
+
+	embed := &models.Embed{
+		Type:        embedType(m.ogType),
+		Title:       truncate(title, maxTitleLength),
+		Description: truncate(m.description, maxDescriptionLength),
+		URL:         rawURL,
+		Color:       parseColor(m.themeColor),
+	}
+
+	if m.siteName != "" {
+		embed.Provider = &models.EmbedProvider{
+			Name: truncate(m.siteName, maxProviderLength),
+			URL:  m.providerURL,
+		}
+	}
+	if m.authorName != "" {
+		embed.Author = &models.EmbedAuthor{
+			Name: truncate(m.authorName, maxProviderLength),
+			URL:  m.authorURL,
+		}
+	}
+	if m.image != "" {
+		embed.Thumbnail = &models.EmbedMedia{
+			URL:    m.image,
+			Width:  m.imageWidth,
+			Height: m.imageHeight,
+		}
+	}
+
+	return embed
+}
+
+func embedType(ogType string) string {
+	switch {
+	case strings.HasPrefix(ogType, "video"):
+		return "video"
+	case ogType == "photo":
+		return "image"
+	case ogType == "article":
+		return "article"
+	default:
+		return "link"
+	}
+}
+
+// resolveURL resolve URLs relativas em relação à página; só aceita http(s)
+func resolveURL(base *url.URL, ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(parsed)
+	if resolved.Scheme != "http" && resolved.Scheme != "https" {
+		return ""
+	}
+	return resolved.String()
+}
+
+// parseColor converte "#rrggbb" em RGB; retorna 0 para outros formatos
+func parseColor(value string) int {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "#")
+	if len(value) != 6 {
+		return 0
+	}
+	color, err := strconv.ParseInt(value, 16, 32)
+	if err != nil {
+		return 0
+	}
+	return int(color)
+}
+
+func truncate(s string, max int) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return strings.TrimSpace(string(runes[:max-1])) + "…"
+}
+
+func setIfEmpty(dst *string, value string) {
+	if *dst == "" {
+		*dst = strings.TrimSpace(value)
+	}
+}
@@ -0,0 +1,101 @@
+package unfurl
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexus/backend/internal/cache"
+	"go.uber.org/zap"
+)
+
+// TestParseHTMLMeta verifica a leitura das tags Open Graph, Twitter e oEmbed do <head>
+func TestParseHTMLMeta(t *testing.T) {
+	base, _ := url.Parse("https://example.com/posts/1")
+	page := `<html><head>
+		<title>Fallback title</title>
+		<meta property="og:title" content="Post title">
+		<meta name="twitter:title" content="Twitter title">
+		<meta name="description" content="Plain description">
+		<meta property="og:site_name" content="Example">
+		<meta property="og:type" content="article">
+		<meta property="og:image" content="/img/cover.png">
+		<meta property="og:image:width" content="1200">
+		<meta name="theme-color" content="#ff8800">
+		<link rel="alternate" type="application/json+oembed" href="/oembed?url=1">
+		<link rel="stylesheet" href="/style.css">
+	</head><body><meta property="og:title" content="Ignored"></body></html>`
+
+	meta := parseHTMLMeta(strings.NewReader(page), base)
+	if meta.title != "Post title" || meta.htmlTitle != "Fallback title" || meta.description != "Plain description" {
+		t.Fatalf("unexpected text fields %+v", meta)
+	}
+	if meta.image != "https://example.com/img/cover.png" || meta.imageWidth != 1200 {
+		t.Fatalf("unexpected image %q (%d)", meta.image, meta.imageWidth)
+	}
+	if meta.oEmbedURL != "https://example.com/oembed?url=1" {
+		t.Fatalf("unexpected oembed url %q", meta.oEmbedURL)
+	}
+
+	embed := meta.embed("https://example.com/posts/1")
+	if embed.Type != "article" || embed.Color != 0xff8800 || embed.Provider == nil || embed.Provider.Name != "Example" || embed.Thumbnail == nil {
+		t.Fatalf("unexpected embed %+v", embed)
+	}
+
+	if empty := parseHTMLMeta(strings.NewReader("<html><head></head><body>text</body></html>"), base); empty.embed("https://example.com") != nil {
+		t.Fatal("page without metadata should not produce an embed")
+	}
+}
+
+// TestResolveURL verifica URLs relativas e a recusa de esquemas que não são http(s)
+func TestResolveURL(t *testing.T) {
+	base, _ := url.Parse("https://example.com/a/b")
+	tests := map[string]string{
+		"/img.png":               "https://example.com/img.png",
+		"c.png":                  "https://example.com/a/c.png",
+		"//cdn.example.com/x":    "https://cdn.example.com/x",
+		"http://other.com/y":     "http://other.com/y",
+		"javascript:alert(1)":    "",
+		"data:image/png;base64,": "",
+	}
+	for ref, want := range tests {
+		if got := resolveURL(base, ref); got != want {
+			t.Errorf("resolveURL(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+// TestUnfurlOEmbed busca uma página com oEmbed em um servidor local
+func TestUnfurlOEmbed(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/video", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><link rel="alternate" type="application/json+oembed" href="/oembed"></head></html>`))
+	})
+	mux.HandleFunc("/oembed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"type":"video","title":"A video","author_name":"Someone","provider_name":"Tube",
+			"thumbnail_url":"https://img.example.com/t.jpg","thumbnail_width":480,"thumbnail_height":360}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	u := &Unfurler{
+		logger: zap.NewNop(),
+		client: newSafeClient(time.Second, maxRedirects, func(net.IP) bool { return false }),
+		cache:  cache.NewMemoryCache(time.Minute),
+	}
+	embed, err := u.Unfurl(context.Background(), server.URL+"/video")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if embed == nil || embed.Type != "video" || embed.Title != "A video" || embed.Author == nil || embed.Author.Name != "Someone" ||
+		embed.Provider == nil || embed.Provider.Name != "Tube" || embed.Thumbnail == nil || embed.Thumbnail.Width != 480 {
+		t.Fatalf("unexpected embed %+v", embed)
+	}
+}
@@ -0,0 +1,359 @@
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nexus/backend/internal/cache"
+	"github.com/nexus/backend/internal/models"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxURLsPerMessage é quantos links de uma mensagem são desdobrados
+	MaxURLsPerMessage = 5
+
+	fetchTimeout  = 5 * time.Second
+	maxHTMLBytes  = 512 * 1024
+	maxOEmbedSize = 64 * 1024
+	maxRedirects  = 3
+	cacheTTL      = time.Hour
+	queueSize     = 256
+	userAgent     = "Mozilla/5.0 (compatible; NexusBot/1.0; +link-preview)"
+)
+
+// ErrBlockedAddress indica que o destino resolve para um endereço interno
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+
+// codePattern encontra blocos de código (```...```) e código inline (`...`), cujos links não são desdobrados
+var codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]+`")
+
+// blockedNetworks são faixas que nunca são acessadas (rede interna, loopback, link-local, ...)
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",
+	"10.0.0.0/8",
+	"100.64.0.0/10",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.0.0.0/24",
+	"192.0.2.0/24",
+	"192.168.0.0/16",
+	"198.18.0.0/15",
+	"198.51.100.0/24",
+	"203.0.113.0/24",
+	"224.0.0.0/4",
+	"240.0.0.0/4",
+	"::/128",
+	"::1/128",
+	"64:ff9b::/96",
+	"fc00::/7",
+	"fe80::/10",
+	"ff00::/8",
+)
+
+// Job é um pedido de desdobramento dos links de uma mensagem
+type Job struct {
+	URLs []string
+	Done func(embeds []models.Embed) // chamado pelo worker com os embeds encontrados (pode ser vazio)
+}
+
+// Unfurler busca metadados Open Graph/oEmbed de links em workers de segundo plano.
+// As requisições só alcançam endereços públicos, têm timeout e tamanho limitados
+// e os resultados (inclusive ausência de metadados) ficam em cache.
+type Unfurler struct {
+	logger *zap.Logger
+	client *http.Client
+	cache  *cache.MemoryCache
+	queue  chan Job
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// cacheEntry guarda o resultado de uma URL (embed nil quando não há metadados)
+type cacheEntry struct {
+	embed *models.Embed
+}
+
+// New cria um Unfurler e inicia os workers
+func New(logger *zap.Logger, workers int) *Unfurler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	u := &Unfurler{
+		logger: logger,
+		client: NewSafeClient(fetchTimeout, maxRedirects),
+		cache:  cache.NewMemoryCache(cacheTTL),
+		queue:  make(chan Job, queueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	for i := 0; i < workers; i++ {
+		u.wg.Add(1)
+		go u.worker()
+	}
+
+	return u
+}
+
+// Stop interrompe os workers; pedidos ainda na fila são descartados
+func (u *Unfurler) Stop() {
+	u.cancel()
+	u.wg.Wait()
+}
+
+// Enqueue agenda um desdobramento. Retorna false se a fila estiver cheia
+func (u *Unfurler) Enqueue(job Job) bool {
+	select {
+	case u.queue <- job:
+		return true
+	default:
+		return false
+	}
+}
+
+func (u *Unfurler) worker() {
+	defer u.wg.Done()
+
+	for {
+		select {
+		case <-u.ctx.Done():
+			return
+		case job := <-u.queue:
+			u.run(job)
+		}
+	}
+}
+
+func (u *Unfurler) run(job Job) {
+	defer func() {
+		if err := recover(); err != nil {
+			u.logger.Error("unfurl job panicked", zap.Any("error", err))
+		}
+	}()
+
+	embeds := make([]models.Embed, 0, len(job.URLs))
+	for _, rawURL := range job.URLs {
+		embed, err := u.Unfurl(u.ctx, rawURL)
+		if err != nil {
+			u.logger.Debug("failed to unfurl link", zap.Error(err), zap.String("url", rawURL))
+			continue
+		}
+		if embed != nil {
+			embeds = append(embeds, *embed)
+		}
+	}
+
+	if u.ctx.Err() != nil {
+		return
+	}
+	job.Done(embeds)
+}
+
+// Unfurl retorna o embed de uma URL (nil se a página não tiver metadados)
+func (u *Unfurler) Unfurl(ctx context.Context, rawURL string) (*models.Embed, error) {
+	if cached, ok := u.cache.Get(rawURL); ok {
+		return cached.(cacheEntry).embed, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	defer cancel()
+
+	embed, err := u.fetch(ctx, rawURL)
+	if err != nil {
+		// Falhas definitivas (endereço bloqueado, conteúdo inválido) também vão para o cache
+		if errors.Is(err, ErrBlockedAddress) || errors.Is(err, errNotHTML) {
+			u.cache.Set(rawURL, cacheEntry{})
+		}
+		return nil, err
+	}
+
+	u.cache.Set(rawURL, cacheEntry{embed: embed})
+	return embed, nil
+}
+
+var errNotHTML = errors.New("unsupported content type")
+
+// fetch baixa a página e monta o embed
+func (u *Unfurler) fetch(ctx context.Context, rawURL string) (*models.Embed, error) {
+	resp, err := u.get(ctx, rawURL, "text/html,application/xhtml+xml;q=0.9,image/*;q=0.8")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		// Link direto para uma imagem
+		return &models.Embed{
+			Type:  "image",
+			URL:   rawURL,
+			Image: &models.EmbedMedia{URL: resp.Request.URL.String()},
+		}, nil
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+	default:
+		return nil, errNotHTML
+	}
+
+	meta := parseHTMLMeta(io.LimitReader(resp.Body, maxHTMLBytes), resp.Request.URL)
+
+	if meta.oEmbedURL != "" {
+		if oembed, err := u.fetchOEmbed(ctx, meta.oEmbedURL); err == nil {
+			meta.mergeOEmbed(oembed)
+		} else {
+			u.logger.Debug("failed to fetch oembed", zap.Error(err), zap.String("url", meta.oEmbedURL))
+		}
+	}
+
+	return meta.embed(rawURL), nil
+}
+
+// get faz uma requisição GET pelo cliente protegido
+func (u *Unfurler) get(ctx context.Context, rawURL, accept string) (*http.Response, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid url %q", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", accept)
+
+	return u.client.Do(req)
+}
+
+// ExtractURLs retorna os links http(s) distintos de um texto, na ordem em que aparecem.
+// Links em código e entre <> (forma de pedir que o link não seja desdobrado) são ignorados.
+func ExtractURLs(content string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+
+	content = codePattern.ReplaceAllStringFunc(content, func(code string) string {
+		return strings.Repeat(" ", len(code))
+	})
+
+	for _, loc := range urlPattern.FindAllStringIndex(content, -1) {
+		if loc[0] > 0 && content[loc[0]-1] == '<' && loc[1] < len(content) && content[loc[1]] == '>' {
+			continue
+		}
+
+		// Pontuação no fim costuma ser da frase, não do link
+		match := strings.TrimRight(content[loc[0]:loc[1]], ".,;:!?)]}*_~")
+
+		parsed, err := url.Parse(match)
+		if err != nil || parsed.Host == "" {
+			continue
+		}
+		if seen[match] {
+			continue
+		}
+		seen[match] = true
+
+		urls = append(urls, match)
+		if len(urls) >= MaxURLsPerMessage {
+			break
+		}
+	}
+
+	return urls
+}
+
+// NewSafeClient cria um cliente HTTP que só conecta a endereços públicos.
+// A verificação é feita no momento da conexão (após a resolução DNS), valendo
+// também para redirecionamentos e impedindo DNS rebinding. Com redirects = 0
+// os redirecionamentos não são seguidos (a resposta 3xx é devolvida).
+// Também usado pelos webhooks de saída e pelos callbacks de comandos de bots.
+func NewSafeClient(timeout time.Duration, redirects int) *http.Client {
+	return newSafeClient(timeout, redirects, IsBlockedIP)
+}
+
+// newSafeClient cria o cliente com a verificação de endereços informada (substituída nos testes)
+func newSafeClient(timeout time.Duration, redirects int, blocked func(net.IP) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, c syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || blocked(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		Proxy:                 nil, // nunca usar proxy: ele faria a conexão por nós
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   timeout,
+		ResponseHeaderTimeout: timeout,
+		MaxIdleConns:          32,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if redirects == 0 {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= redirects {
+				return errors.New("too many redirects")
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme %q", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// IsBlockedIP indica se o endereço pertence a uma faixa interna ou reservada
+func IsBlockedIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
@@ -0,0 +1,115 @@
+package unfurl
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestIsBlockedIP verifica as faixas internas e reservadas, inclusive IPv6 com IPv4 mapeado
+func TestIsBlockedIP(t *testing.T) {
+	tests := []struct {
+		ip      string
+		blocked bool
+	}{
+		{"127.0.0.1", true},
+		{"127.255.255.254", true},
+		{"0.0.0.0", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.168.1.1", true},
+		{"100.64.0.1", true},
+		{"169.254.169.254", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:10.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"fe80::1", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"ff02::1", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"93.184.216.34", false},
+		{"::ffff:8.8.8.8", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		if got := IsBlockedIP(net.ParseIP(tt.ip)); got != tt.blocked {
+			t.Errorf("IsBlockedIP(%s) = %v, want %v", tt.ip, got, tt.blocked)
+		}
+	}
+}
+
+// TestSafeClient verifica que endereços internos são recusados, inclusive após um redirecionamento
+func TestSafeClient(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("internal"))
+	}))
+	defer target.Close()
+
+	// Conexão direta a um endereço interno
+	if _, err := NewSafeClient(time.Second, maxRedirects).Get(target.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected ErrBlockedAddress, got %v", err)
+	}
+
+	// O primeiro destino é tratado como público; o redirecionamento aponta para 127.0.0.2
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(target.URL, "http://"))
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://127.0.0.2:"+port+"/", http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	client := newSafeClient(time.Second, maxRedirects, func(ip net.IP) bool {
+		return !ip.Equal(net.IPv4(127, 0, 0, 1))
+	})
+	if _, err := client.Get(redirect.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("redirect to a private address should be refused, got %v", err)
+	}
+
+	// Sem seguir redirecionamentos, a resposta 3xx é devolvida
+	noRedirects := newSafeClient(time.Second, 0, func(net.IP) bool { return false })
+	resp, err := noRedirects.Get(redirect.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected 302, got %d", resp.StatusCode)
+	}
+}
+
+// TestExtractURLs verifica a extração de links, a pontuação final, código e a supressão com <>
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"plain", "see https://example.com/a and http://example.org", []string{"https://example.com/a", "http://example.org"}},
+		{"trailing punctuation", "(https://example.com/page). Nice!", []string{"https://example.com/page"}},
+		{"duplicates", "https://example.com https://example.com", []string{"https://example.com"}},
+		{"suppressed", "<https://example.com/hidden> https://example.com/shown", []string{"https://example.com/shown"}},
+		{"unbalanced bracket", "<https://example.com/a", []string{"https://example.com/a"}},
+		{"inline code", "run `curl https://internal.example.com` now", nil},
+		{"code block", "```\nGET https://example.com/api\n```\nhttps://example.com/docs", []string{"https://example.com/docs"}},
+		{"not a url", "ftp://example.com and https://", nil},
+		{"limit", "https://a.com https://b.com https://c.com https://d.com https://e.com https://f.com",
+			[]string{"https://a.com", "https://b.com", "https://c.com", "https://d.com", "https://e.com"}},
+	}
+	for _, tt := range tests {
+		if got := ExtractURLs(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ExtractURLs = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
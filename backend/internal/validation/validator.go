@@ -2,9 +2,12 @@ package validation
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 	"unicode"
+
+	"github.com/nexus/backend/internal/models"
 )
 
 var (
@@ -135,3 +138,80 @@ func ValidateMessageContent(content string) error {
 	}
 	return nil
 }
+
+// Embed limits
+const (
+	MaxEmbeds               = 10
+	MaxEmbedTitleLength     = 256
+	MaxEmbedDescription     = 4096
+	MaxEmbedFields          = 25
+	MaxEmbedFieldNameLength = 256
+	MaxEmbedFieldValue      = 1024
+	MaxEmbedFooterLength    = 2048
+	MaxEmbedTotalLength     = 6000
+)
+
+// ValidateEmbeds validates rich embeds attached to a message
+func ValidateEmbeds(embeds []models.Embed) error {
+	if len(embeds) > MaxEmbeds {
+		return fmt.Errorf("too many embeds (max %d)", MaxEmbeds)
+	}
+
+	total := 0
+	for _, embed := range embeds {
+		if len(embed.Title) > MaxEmbedTitleLength {
+			return fmt.Errorf("embed title too long (max %d characters)", MaxEmbedTitleLength)
+		}
+		if len(embed.Description) > MaxEmbedDescription {
+			return fmt.Errorf("embed description too long (max %d characters)", MaxEmbedDescription)
+		}
+		if len(embed.Fields) > MaxEmbedFields {
+			return fmt.Errorf("too many embed fields (max %d)", MaxEmbedFields)
+		}
+		if embed.Color < 0 || embed.Color > 0xFFFFFF {
+			return errors.New("embed color must be an RGB value")
+		}
+
+		total += len(embed.Title) + len(embed.Description)
+		for _, field := range embed.Fields {
+			if field.Name == "" || len(field.Name) > MaxEmbedFieldNameLength {
+				return fmt.Errorf("embed field name must be between 1 and %d characters", MaxEmbedFieldNameLength)
+			}
+			if field.Value == "" || len(field.Value) > MaxEmbedFieldValue {
+				return fmt.Errorf("embed field value must be between 1 and %d characters", MaxEmbedFieldValue)
+			}
+			total += len(field.Name) + len(field.Value)
+		}
+		if embed.Author != nil {
+			if len(embed.Author.Name) > MaxEmbedTitleLength {
+				return fmt.Errorf("embed author name too long (max %d characters)", MaxEmbedTitleLength)
+			}
+			total += len(embed.Author.Name)
+		}
+		if embed.Footer != nil {
+			if len(embed.Footer.Text) > MaxEmbedFooterLength {
+				return fmt.Errorf("embed footer too long (max %d characters)", MaxEmbedFooterLength)
+			}
+			total += len(embed.Footer.Text)
+		}
+
+		for _, url := range []string{embed.URL, mediaURL(embed.Image), mediaURL(embed.Thumbnail)} {
+			if url != "" && !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+				return errors.New("embed urls must be http(s)")
+			}
+		}
+	}
+
+	if total > MaxEmbedTotalLength {
+		return fmt.Errorf("embeds too long (max %d characters in total)", MaxEmbedTotalLength)
+	}
+
+	return nil
+}
+
+func mediaURL(media *models.EmbedMedia) string {
+	if media == nil {
+		return ""
+	}
+	return media.URL
+}